@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"slices"
+
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+
+	"github.com/joho/godotenv"
+)
+
+// Sets a user's role from the command line, e.g. to create the first admin:
+//
+//	go run cmd/admin/main.go -login alice@example.com -role admin
+func main() {
+	login := flag.String("login", "", "email or username of the user")
+	role := flag.String("role", models.RoleAdmin, "role to assign (admin, analyst or viewer)")
+	flag.Parse()
+
+	if *login == "" {
+		log.Fatal("-login is required")
+	}
+	if !slices.Contains([]string{models.RoleAdmin, models.RoleAnalyst, models.RoleViewer}, *role) {
+		log.Fatalf("Unknown role %q", *role)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	// Create database connection
+	ctx := context.Background()
+	conn, err := repository.NewDBConnection(ctx)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer conn.Close()
+
+	userRepo := repository.NewUserRepository(conn)
+	user, err := userRepo.GetByLogin(*login)
+	if err != nil {
+		log.Fatal("Failed to find user:", err)
+	}
+
+	if err := userRepo.UpdateRole(user.ID, *role); err != nil {
+		log.Fatal("Failed to update role:", err)
+	}
+
+	fmt.Printf("User %s is now %s\n", user.Username, *role)
+}
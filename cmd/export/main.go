@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-flow/internal/export"
+	"go-flow/internal/repository"
+
+	"github.com/joho/godotenv"
+)
+
+// Exports a dataset from the command line, streaming it from the database
+// into a file or stdout, e.g.:
+//
+//	go run cmd/export/main.go -dataset history -symbol AAPL -out aapl.parquet
+//	go run cmd/export/main.go -dataset valuations -portfolio 3 -login alice@example.com -format ndjson
+func main() {
+	dataset := flag.String("dataset", "", "dataset to export (history, indicators, transactions or valuations)")
+	symbol := flag.String("symbol", "", "stock symbol for history and indicators")
+	portfolioID := flag.Int64("portfolio", 0, "portfolio ID for transactions and valuations")
+	login := flag.String("login", "", "owner of the portfolio; required for valuations, and when given ownership is checked")
+	format := flag.String("format", "", "csv, ndjson or parquet (default: from the -out extension, else csv)")
+	out := flag.String("out", "", "file to write (default: stdout)")
+	startFlag := flag.String("start", "", "first date, YYYY-MM-DD, for history and indicators")
+	endFlag := flag.String("end", "", "last date, YYYY-MM-DD, for history and indicators")
+	flag.Parse()
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*out), ".")
+	}
+	if *format == "" {
+		*format = export.FormatCSV
+	}
+	f, err := export.ParseFormat(*format)
+	if err != nil {
+		log.Fatalf("-format %s", err)
+	}
+	start, end := parseDate("start", *startFlag), parseDate("end", *endFlag)
+
+	switch *dataset {
+	case export.DatasetHistory, export.DatasetIndicators:
+		if *symbol == "" {
+			log.Fatalf("-symbol is required for %s", *dataset)
+		}
+	case export.DatasetTransactions, export.DatasetValuations:
+		if *portfolioID <= 0 {
+			log.Fatalf("-portfolio is required for %s", *dataset)
+		}
+		if *dataset == export.DatasetValuations && *login == "" {
+			log.Fatal("-login is required for valuations")
+		}
+	default:
+		log.Fatal("-dataset must be history, indicators, transactions or valuations")
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	// Create database connection
+	ctx := context.Background()
+	conn, err := repository.NewDBConnection(ctx)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer conn.Close()
+
+	// Valuations check ownership as they stream
+	var userID int64
+	if *login != "" && *portfolioID > 0 {
+		user, err := repository.NewUserRepository(conn).GetByLogin(*login)
+		if err != nil {
+			log.Fatal("Failed to find user:", err)
+		}
+		userID = user.ID
+		if *dataset != export.DatasetValuations {
+			if _, err := repository.NewPortfolioRepository(conn).Get(*portfolioID, userID); err != nil {
+				log.Fatal("Failed to find portfolio:", err)
+			}
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			log.Fatal("Failed to create output file:", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	buf := bufio.NewWriterSize(w, 64<<10)
+
+	exporter := export.NewExporter(repository.NewExportRepository(conn))
+	switch *dataset {
+	case export.DatasetHistory:
+		err = exporter.History(buf, f, strings.ToUpper(*symbol), start, end)
+	case export.DatasetIndicators:
+		err = exporter.Indicators(buf, f, strings.ToUpper(*symbol), start, end)
+	case export.DatasetTransactions:
+		err = exporter.Transactions(buf, f, *portfolioID)
+	case export.DatasetValuations:
+		err = exporter.Valuations(buf, f, *portfolioID, userID)
+	}
+	if err == nil {
+		err = buf.Flush()
+	}
+	if err != nil {
+		log.Fatal("Failed to export:", err)
+	}
+}
+
+// parseDate reads an optional YYYY-MM-DD flag
+func parseDate(name, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		log.Fatalf("-%s must be a date in YYYY-MM-DD format", name)
+	}
+	return &date
+}
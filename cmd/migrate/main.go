@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
 	"go-flow/internal/repository"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	migrationsDir = "db/migrations"
+	// initialVersion is the migration the previous runner applied without
+	// recording it
+	initialVersion = "000001_create_initial_tables"
+)
+
 func main() {
+	baseline := flag.String("baseline", "", "record migrations up to and including this version as applied without running them")
+	flag.Parse()
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
@@ -24,28 +38,99 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
-	defer conn.Close(ctx)
-
-	// Read and execute the migration file
-	migrationFile := "db/migrations/000001_create_initial_tables.up.sql"
+	defer conn.Close()
 
-	// Read the SQL file
-	file, err := os.Open(migrationFile)
+	// Make sure the migration bookkeeping table exists
+	_, err = conn.Exec(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version VARCHAR(255) PRIMARY KEY,
+            applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+        )
+    `)
 	if err != nil {
-		log.Fatal("Failed to open migration file:", err)
+		log.Fatal("Failed to create schema_migrations table:", err)
 	}
-	defer file.Close()
 
-	content, err := io.ReadAll(file)
+	// Collect the up migrations in version order
+	files, err := filepath.Glob(filepath.Join(migrationsDir, "*.up.sql"))
 	if err != nil {
-		log.Fatal("Failed to read migration file:", err)
+		log.Fatal("Failed to list migration files:", err)
 	}
+	sort.Strings(files)
 
-	// Execute the SQL
-	_, err = conn.Exec(ctx, string(content))
-	if err != nil {
-		log.Fatal("Failed to execute migration:", err)
+	// Databases set up by the previous runner have the initial tables but no
+	// bookkeeping, so the initial migration is recorded rather than re-run
+	if *baseline == "" {
+		var untracked bool
+		err := conn.QueryRow(ctx, `
+            SELECT NOT EXISTS (SELECT 1 FROM schema_migrations) AND to_regclass('stocks') IS NOT NULL
+        `).Scan(&untracked)
+		if err != nil {
+			log.Fatal("Failed to inspect existing schema:", err)
+		}
+		if untracked {
+			*baseline = initialVersion
+		}
+	}
+	if *baseline != "" {
+		if !slices.Contains(files, filepath.Join(migrationsDir, *baseline+".up.sql")) {
+			log.Fatalf("Unknown baseline version %s", *baseline)
+		}
+
+		recorded := 0
+		for _, migrationFile := range files {
+			version := strings.TrimSuffix(filepath.Base(migrationFile), ".up.sql")
+			if version > *baseline {
+				break
+			}
+			tag, err := conn.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1) ON CONFLICT DO NOTHING", version)
+			if err != nil {
+				log.Fatalf("Failed to record baseline migration %s: %v", version, err)
+			}
+			recorded += int(tag.RowsAffected())
+		}
+		fmt.Printf("Recorded %d migrations up to %s as applied\n", recorded, *baseline)
+	}
+
+	applied := 0
+	for _, migrationFile := range files {
+		version := strings.TrimSuffix(filepath.Base(migrationFile), ".up.sql")
+
+		var exists bool
+		err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&exists)
+		if err != nil {
+			log.Fatal("Failed to check migration status:", err)
+		}
+		if exists {
+			continue
+		}
+
+		// Read the SQL file
+		content, err := os.ReadFile(migrationFile)
+		if err != nil {
+			log.Fatal("Failed to read migration file:", err)
+		}
+
+		// Execute the SQL and record the version in one transaction
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			log.Fatal("Failed to start transaction:", err)
+		}
+		if _, err := tx.Exec(ctx, string(content)); err != nil {
+			tx.Rollback(ctx)
+			log.Fatalf("Failed to execute migration %s: %v", version, err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+			tx.Rollback(ctx)
+			log.Fatalf("Failed to record migration %s: %v", version, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			log.Fatalf("Failed to commit migration %s: %v", version, err)
+		}
+
+		fmt.Printf("Applied migration %s\n", version)
+		applied++
 	}
 
-	fmt.Println("Migration executed successfully!")
+	fmt.Printf("Migrations executed successfully! (%d applied)\n", applied)
 }
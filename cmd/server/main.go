@@ -4,10 +4,20 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
+	"go-flow/internal/alerting"
 	"go-flow/internal/api/handler"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/router"
+	"go-flow/internal/export"
+	"go-flow/internal/graph"
+	"go-flow/internal/notify"
+	"go-flow/internal/paper"
 	"go-flow/internal/repository"
 	"go-flow/internal/service"
+	"go-flow/internal/snapshots"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -25,30 +35,115 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
-	defer conn.Close(ctx)
+	defer conn.Close()
 
 	// Initialize services
 	avService := service.NewAlphaVantageService(os.Getenv("ALPHA_VANTAGE_API_KEY"))
 
+	batchRunner := service.NewBatchRunner(envInt("BATCH_WORKERS", 4))
+	priceBus := service.NewPriceBus()
+
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		log.Fatal("JWT_SECRET environment variable is not set")
+	}
+	tokenService := service.NewTokenService(
+		jwtSecret,
+		envDuration("JWT_ACCESS_TTL", 15*time.Minute),
+		envDuration("JWT_REFRESH_TTL", 30*24*time.Hour),
+	)
+
 	// Initialize repository with real database connection
 	stockRepo := repository.NewStockRepository(conn)
+	userRepo := repository.NewUserRepository(conn)
+	tokenRepo := repository.NewRefreshTokenRepository(conn)
+	apiKeyRepo := repository.NewAPIKeyRepository(conn)
+	watchlistRepo := repository.NewWatchlistRepository(conn)
+	alertRepo := repository.NewAlertRepository(conn)
+	notificationRepo := repository.NewNotificationRepository(conn)
+	portfolioRepo := repository.NewPortfolioRepository(conn)
+	paperRepo := repository.NewPaperRepository(conn)
+	importRepo := repository.NewImportRepository(conn)
+	exportRepo := repository.NewExportRepository(conn)
+
+	// Fired alerts reach users through the in-app inbox, signed webhooks
+	// and, when an SMTP server is configured, email
+	channels := []notify.Channel{notify.NewWebhookChannel(nil)}
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		channels = append(channels, notify.NewEmailChannel(notify.SMTPConfig{
+			Host:     smtpHost,
+			Port:     envOr("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     envOr("SMTP_FROM", "alerts@go-flow.local"),
+		}))
+	}
+	dispatcher := notify.NewDispatcher(notificationRepo, userRepo, channels...)
+
+	// Evaluate alerts as prices are stored, with a periodic sweep for
+	// prices stored by other instances or tools
+	alertEngine := alerting.NewEngine(alertRepo, stockRepo, priceBus, envDuration("ALERT_SWEEP_INTERVAL", time.Minute))
+	alertEngine.OnFire(dispatcher.NotifyAlert)
+	go alertEngine.Run(ctx)
+
+	// Match paper orders against the same prices; the sweep also expires
+	// day orders at the close
+	paperEngine := paper.NewEngine(paperRepo, priceBus, envDuration("PAPER_SWEEP_INTERVAL", time.Minute))
+	go paperEngine.Run(ctx)
+
+	// Rebuild stored valuations after ledger and price changes so reads
+	// never have to
+	snapshotRefresher := snapshots.NewRefresher(portfolioRepo, envDuration("SNAPSHOT_REFRESH_INTERVAL", time.Minute))
+	go snapshotRefresher.Run(ctx)
 
 	// Initialize handlers
-	stocksHandler := handler.NewStocksHandler(stockRepo, avService)
+	stocksHandler := handler.NewStocksHandler(stockRepo, avService, priceBus)
+	screenerHandler := handler.NewScreenerHandler(stockRepo)
+	batchHandler := handler.NewBatchHandler(stockRepo, avService, batchRunner, priceBus)
+	authHandler := handler.NewAuthHandler(userRepo, tokenRepo, tokenService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyRepo)
+	adminHandler := handler.NewAdminHandler(userRepo, stockRepo, avService, batchRunner, priceBus)
+	watchlistHandler := handler.NewWatchlistHandler(watchlistRepo)
+	alertHandler := handler.NewAlertHandler(alertRepo, stockRepo)
+	notificationHandler := handler.NewNotificationHandler(notificationRepo)
+	portfolioHandler := handler.NewPortfolioHandler(portfolioRepo, stockRepo)
+	analyticsHandler := handler.NewAnalyticsHandler(stockRepo)
+	paperHandler := handler.NewPaperHandler(portfolioRepo, paperRepo, paperEngine)
+	importHandler := handler.NewImportHandler(importRepo)
+	exportHandler := handler.NewExportHandler(portfolioRepo, export.NewExporter(exportRepo))
+	chartHandler := handler.NewChartHandler(stockRepo)
+	graphqlHandler := handler.NewGraphQLHandler(&graph.Resolver{
+		StockRepo:     stockRepo,
+		PortfolioRepo: portfolioRepo,
+		WatchlistRepo: watchlistRepo,
+		AlertRepo:     alertRepo,
+		PriceBus:      priceBus,
+	}, envInt("GRAPHQL_COMPLEXITY_LIMIT", 20000))
 
-	// Set up Gin router
-	r := gin.Default()
+	// Set up Gin router; recovery is installed with the routes so panics
+	// are reported as problem details
+	r := gin.New()
+	r.Use(gin.Logger())
 
 	// Set up routes
-	api := r.Group("/api")
-	{
-		stocks := api.Group("/stocks")
-		{
-			stocks.GET("", stocksHandler.GetStocks)
-			stocks.GET("/:id", stocksHandler.GetStockByID)
-			stocks.POST("/fetch/:symbol", stocksHandler.FetchStockData)
-		}
-	}
+	router.SetupRoutes(r, router.Handlers{
+		Stocks:        stocksHandler,
+		Screener:      screenerHandler,
+		Batch:         batchHandler,
+		Auth:          authHandler,
+		APIKeys:       apiKeyHandler,
+		Admin:         adminHandler,
+		Watchlists:    watchlistHandler,
+		Alerts:        alertHandler,
+		Notifications: notificationHandler,
+		Portfolios:    portfolioHandler,
+		Paper:         paperHandler,
+		Imports:       importHandler,
+		Exports:       exportHandler,
+		Charts:        chartHandler,
+		GraphQL:       graphqlHandler,
+		Analytics:     analyticsHandler,
+	}, middleware.RequireAuth(tokenService, tokenRepo, apiKeyRepo, userRepo))
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -61,3 +156,29 @@ func main() {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+// envOr reads an environment variable, falling back to def when it is unset
+func envOr(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// envDuration reads a duration such as "15m" from the environment
+func envDuration(key string, def time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// envInt reads an integer from the environment
+func envInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_stocks_industry;
+DROP INDEX IF EXISTS idx_stocks_sector;
+
+ALTER TABLE stocks
+    DROP COLUMN IF EXISTS dividend_yield,
+    DROP COLUMN IF EXISTS pe_ratio,
+    DROP COLUMN IF EXISTS market_cap,
+    DROP COLUMN IF EXISTS industry,
+    DROP COLUMN IF EXISTS sector;
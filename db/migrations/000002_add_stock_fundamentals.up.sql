@@ -0,0 +1,10 @@
+-- Fundamental data used by the stock screener
+ALTER TABLE stocks
+    ADD COLUMN sector VARCHAR(100),
+    ADD COLUMN industry VARCHAR(255),
+    ADD COLUMN market_cap BIGINT,
+    ADD COLUMN pe_ratio NUMERIC(12, 4),
+    ADD COLUMN dividend_yield NUMERIC(8, 4);
+
+CREATE INDEX idx_stocks_sector ON stocks (sector);
+CREATE INDEX idx_stocks_industry ON stocks (industry);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_stocks_market_cap_symbol;
+DROP INDEX IF EXISTS idx_stocks_price_symbol;
+DROP INDEX IF EXISTS idx_stocks_name_symbol;
+DROP INDEX IF EXISTS idx_stocks_created_at_symbol;
@@ -0,0 +1,6 @@
+-- Indexes backing keyset pagination on the stock list; expressions match the
+-- sort expressions used by the repository
+CREATE INDEX idx_stocks_created_at_symbol ON stocks (created_at, symbol);
+CREATE INDEX idx_stocks_name_symbol ON stocks (name, symbol);
+CREATE INDEX idx_stocks_price_symbol ON stocks ((COALESCE(last_price, 0)), symbol);
+CREATE INDEX idx_stocks_market_cap_symbol ON stocks ((COALESCE(market_cap, 0)), symbol);
@@ -0,0 +1,4 @@
+-- Roles drive route-level permissions; new accounts start as viewers
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'viewer'
+    CHECK (role IN ('admin', 'analyst', 'viewer'));
@@ -0,0 +1,14 @@
+-- Collapse every watchlist back into one list per user
+DELETE FROM stock_watchlist a
+USING stock_watchlist b
+WHERE a.user_id = b.user_id AND a.symbol = b.symbol AND a.id > b.id;
+
+ALTER TABLE stock_watchlist DROP CONSTRAINT IF EXISTS stock_watchlist_watchlist_id_symbol_key;
+ALTER TABLE stock_watchlist ADD CONSTRAINT stock_watchlist_user_id_symbol_key UNIQUE (user_id, symbol);
+
+ALTER TABLE stock_watchlist
+    DROP COLUMN IF EXISTS notes,
+    DROP COLUMN IF EXISTS position,
+    DROP COLUMN IF EXISTS watchlist_id;
+
+DROP TABLE IF EXISTS watchlists;
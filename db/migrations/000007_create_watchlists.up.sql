@@ -0,0 +1,29 @@
+-- Named watchlists; each user can keep several, ordered by position
+CREATE TABLE watchlists (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    position INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+-- Existing entries move into a default watchlist per user
+INSERT INTO watchlists (user_id, name)
+SELECT DISTINCT user_id, 'Default' FROM stock_watchlist;
+
+ALTER TABLE stock_watchlist
+    ADD COLUMN watchlist_id BIGINT REFERENCES watchlists(id) ON DELETE CASCADE,
+    ADD COLUMN position INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+
+UPDATE stock_watchlist sw
+SET watchlist_id = w.id
+FROM watchlists w
+WHERE w.user_id = sw.user_id AND w.name = 'Default';
+
+ALTER TABLE stock_watchlist ALTER COLUMN watchlist_id SET NOT NULL;
+ALTER TABLE stock_watchlist DROP CONSTRAINT stock_watchlist_user_id_symbol_key;
+ALTER TABLE stock_watchlist ADD CONSTRAINT stock_watchlist_watchlist_id_symbol_key UNIQUE (watchlist_id, symbol);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_stock_alerts_user;
+DROP INDEX IF EXISTS idx_stock_alerts_active_symbol;
+ALTER TABLE stock_alerts DROP COLUMN IF EXISTS triggered_price;
@@ -0,0 +1,6 @@
+-- The price that fired an alert is kept alongside triggered_at
+ALTER TABLE stock_alerts ADD COLUMN triggered_price NUMERIC(10, 4) NULL;
+
+-- The evaluator only ever looks at active alerts for a symbol
+CREATE INDEX idx_stock_alerts_active_symbol ON stock_alerts (symbol) WHERE is_active;
+CREATE INDEX idx_stock_alerts_user ON stock_alerts (user_id);
@@ -0,0 +1,10 @@
+DELETE FROM stock_alerts WHERE alert_type = 'condition';
+
+ALTER TABLE stock_alerts DROP CONSTRAINT IF EXISTS stock_alerts_condition_check;
+ALTER TABLE stock_alerts DROP CONSTRAINT IF EXISTS stock_alerts_alert_type_check;
+ALTER TABLE stock_alerts DROP COLUMN IF EXISTS condition;
+ALTER TABLE stock_alerts ALTER COLUMN target_price SET NOT NULL;
+
+ALTER TABLE stock_alerts
+    ADD CONSTRAINT stock_alerts_alert_type_check
+    CHECK (alert_type IN ('above', 'below'));
@@ -0,0 +1,14 @@
+-- Alerts beyond above/below store a structured condition instead of a target price
+ALTER TABLE stock_alerts DROP CONSTRAINT IF EXISTS stock_alerts_alert_type_check;
+ALTER TABLE stock_alerts ALTER COLUMN target_price DROP NOT NULL;
+ALTER TABLE stock_alerts ADD COLUMN condition JSONB NULL;
+
+ALTER TABLE stock_alerts
+    ADD CONSTRAINT stock_alerts_alert_type_check
+    CHECK (alert_type IN ('above', 'below', 'condition'));
+ALTER TABLE stock_alerts
+    ADD CONSTRAINT stock_alerts_condition_check
+    CHECK (CASE alert_type
+        WHEN 'condition' THEN condition IS NOT NULL
+        ELSE target_price IS NOT NULL
+    END);
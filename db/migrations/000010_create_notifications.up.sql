@@ -0,0 +1,29 @@
+-- Every notification is recorded here; the in-app inbox shows the ones
+-- delivered to the in_app channel
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    alert_id BIGINT NULL REFERENCES stock_alerts(id) ON DELETE SET NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    channels TEXT[] NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notifications_user_created ON notifications (user_id, created_at DESC);
+CREATE INDEX idx_notifications_user_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+CREATE TABLE notification_preferences (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    in_app_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    email_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    webhook_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    webhook_url TEXT NOT NULL DEFAULT '',
+    webhook_secret VARCHAR(64) NOT NULL DEFAULT '',
+    quiet_hours_start VARCHAR(5) NULL,
+    quiet_hours_end VARCHAR(5) NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    max_per_hour INTEGER NOT NULL DEFAULT 20 CHECK (max_per_hour >= 0),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
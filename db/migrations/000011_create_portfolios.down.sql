@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS portfolio_positions;
+DROP TABLE IF EXISTS portfolios;
@@ -0,0 +1,37 @@
+CREATE TABLE portfolios (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    cash_balance NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+-- One row per held symbol; transactions keep quantity and average cost current
+CREATE TABLE portfolio_positions (
+    id BIGSERIAL PRIMARY KEY,
+    portfolio_id BIGINT NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    symbol VARCHAR(10) NOT NULL REFERENCES stocks(symbol),
+    quantity BIGINT NOT NULL CHECK (quantity >= 0),
+    average_cost NUMERIC(18, 4) NOT NULL DEFAULT 0 CHECK (average_cost >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (portfolio_id, symbol)
+);
+
+CREATE TABLE transactions (
+    id BIGSERIAL PRIMARY KEY,
+    portfolio_id BIGINT NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    symbol VARCHAR(10) NOT NULL REFERENCES stocks(symbol),
+    type VARCHAR(10) NOT NULL CHECK (type IN ('buy', 'sell')),
+    quantity BIGINT NOT NULL CHECK (quantity > 0),
+    price NUMERIC(18, 4) NOT NULL CHECK (price >= 0),
+    fees NUMERIC(18, 4) NOT NULL DEFAULT 0 CHECK (fees >= 0),
+    total_amount NUMERIC(18, 4) NOT NULL,
+    executed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_portfolios_user ON portfolios (user_id);
+CREATE INDEX idx_transactions_portfolio_executed ON transactions (portfolio_id, executed_at DESC);
@@ -0,0 +1,22 @@
+DROP INDEX IF EXISTS idx_transactions_portfolio_symbol;
+
+DELETE FROM transactions WHERE type NOT IN ('buy', 'sell');
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_symbol_check;
+ALTER TABLE transactions DROP CONSTRAINT transactions_quantity_check;
+ALTER TABLE transactions DROP CONSTRAINT transactions_type_check;
+ALTER TABLE transactions
+    DROP COLUMN notes,
+    DROP COLUMN lot_selections,
+    DROP COLUMN split_ratio,
+    ALTER COLUMN symbol SET NOT NULL,
+    ALTER COLUMN price DROP DEFAULT,
+    ALTER COLUMN quantity DROP DEFAULT,
+    ALTER COLUMN quantity TYPE BIGINT USING ROUND(quantity),
+    ALTER COLUMN type TYPE VARCHAR(10),
+    ADD CONSTRAINT transactions_type_check CHECK (type IN ('buy', 'sell')),
+    ADD CONSTRAINT transactions_quantity_check CHECK (quantity > 0);
+
+ALTER TABLE portfolio_positions ALTER COLUMN quantity TYPE BIGINT USING ROUND(quantity);
+
+ALTER TABLE portfolios DROP COLUMN cost_basis_method;
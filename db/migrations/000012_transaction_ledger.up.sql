@@ -0,0 +1,73 @@
+-- Transactions become the ledger that positions and cash are derived from.
+-- Portfolios created before this migration keep average cost matching,
+-- which is how their positions were maintained; new ones default to FIFO.
+ALTER TABLE portfolios
+    ADD COLUMN cost_basis_method VARCHAR(10) NOT NULL DEFAULT 'average'
+        CHECK (cost_basis_method IN ('fifo', 'lifo', 'average', 'specific'));
+ALTER TABLE portfolios ALTER COLUMN cost_basis_method SET DEFAULT 'fifo';
+
+ALTER TABLE portfolio_positions ALTER COLUMN quantity TYPE NUMERIC(20, 8);
+
+ALTER TABLE transactions DROP CONSTRAINT transactions_type_check;
+ALTER TABLE transactions DROP CONSTRAINT transactions_quantity_check;
+ALTER TABLE transactions
+    ALTER COLUMN type TYPE VARCHAR(20),
+    ALTER COLUMN quantity TYPE NUMERIC(20, 8),
+    ALTER COLUMN quantity SET DEFAULT 0,
+    ALTER COLUMN price SET DEFAULT 0,
+    ALTER COLUMN symbol DROP NOT NULL,
+    ADD COLUMN split_ratio NUMERIC(20, 8) NULL CHECK (split_ratio > 0),
+    ADD COLUMN lot_selections JSONB NULL,
+    ADD COLUMN notes TEXT NOT NULL DEFAULT '',
+    ADD CONSTRAINT transactions_type_check CHECK (type IN (
+        'buy', 'sell', 'dividend', 'split', 'fee', 'deposit', 'withdrawal', 'transfer_in', 'transfer_out'
+    )),
+    ADD CONSTRAINT transactions_quantity_check CHECK (quantity >= 0),
+    ADD CONSTRAINT transactions_symbol_check CHECK (symbol IS NOT NULL OR type IN ('fee', 'deposit', 'withdrawal'));
+
+-- Positions and cash could be edited by hand until now. Record the
+-- difference from what the existing trades imply as opening adjustments so
+-- the ledger reproduces the current holdings. Additions are dated before the
+-- portfolio's first trade, so shares added by hand and then sold replay in
+-- order; removals are dated after its last.
+CREATE TEMPORARY TABLE ledger_start ON COMMIT DROP AS
+SELECT p.id AS portfolio_id, LEAST(MIN(t.executed_at), p.created_at) - INTERVAL '1 second' AS opened_at
+FROM portfolios p
+LEFT JOIN transactions t ON t.portfolio_id = p.id
+GROUP BY p.id;
+
+INSERT INTO transactions (portfolio_id, type, total_amount, notes, executed_at)
+SELECT id, CASE WHEN diff > 0 THEN 'deposit' ELSE 'withdrawal' END, ABS(diff), 'Opening balance adjustment',
+       CASE WHEN diff > 0 THEN s.opened_at ELSE NOW() END
+FROM (
+    SELECT p.id, p.cash_balance - COALESCE(SUM(
+        CASE t.type WHEN 'sell' THEN t.total_amount WHEN 'buy' THEN -t.total_amount ELSE 0 END
+    ), 0) AS diff
+    FROM portfolios p
+    LEFT JOIN transactions t ON t.portfolio_id = p.id
+    GROUP BY p.id
+) cash
+JOIN ledger_start s ON s.portfolio_id = cash.id
+WHERE diff <> 0;
+
+INSERT INTO transactions (portfolio_id, symbol, type, quantity, price, total_amount, notes, executed_at)
+SELECT holdings.portfolio_id, symbol, CASE WHEN diff > 0 THEN 'transfer_in' ELSE 'transfer_out' END,
+       ABS(diff), average_cost, 0, 'Opening balance adjustment',
+       CASE WHEN diff > 0 THEN s.opened_at ELSE NOW() END
+FROM (
+    SELECT COALESCE(pp.portfolio_id, net.portfolio_id) AS portfolio_id,
+           COALESCE(pp.symbol, net.symbol) AS symbol,
+           COALESCE(pp.quantity, 0) - COALESCE(net.quantity, 0) AS diff,
+           COALESCE(pp.average_cost, 0) AS average_cost
+    FROM portfolio_positions pp
+    FULL JOIN (
+        SELECT portfolio_id, symbol, SUM(CASE type WHEN 'buy' THEN quantity ELSE -quantity END) AS quantity
+        FROM transactions
+        WHERE type IN ('buy', 'sell')
+        GROUP BY portfolio_id, symbol
+    ) net ON net.portfolio_id = pp.portfolio_id AND net.symbol = pp.symbol
+) holdings
+JOIN ledger_start s ON s.portfolio_id = holdings.portfolio_id
+WHERE diff <> 0;
+
+CREATE INDEX idx_transactions_portfolio_symbol ON transactions (portfolio_id, symbol);
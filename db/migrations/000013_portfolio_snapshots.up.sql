@@ -0,0 +1,11 @@
+-- Daily valuations derived from the transaction ledger and stock_history;
+-- rebuilt whenever a portfolio's performance is requested
+CREATE TABLE portfolio_snapshots (
+    portfolio_id BIGINT NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    cash NUMERIC(18, 4) NOT NULL,
+    market_value NUMERIC(18, 4) NOT NULL,
+    total_value NUMERIC(18, 4) NOT NULL,
+    net_flow NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    PRIMARY KEY (portfolio_id, date)
+);
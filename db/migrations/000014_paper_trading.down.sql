@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS paper_orders;
+ALTER TABLE portfolios DROP COLUMN IF EXISTS is_paper;
@@ -0,0 +1,29 @@
+-- Paper accounts are portfolios whose transactions come from simulated
+-- order fills rather than being entered by hand
+ALTER TABLE portfolios ADD COLUMN is_paper BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE paper_orders (
+    id BIGSERIAL PRIMARY KEY,
+    portfolio_id BIGINT NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    symbol VARCHAR(10) NOT NULL REFERENCES stocks(symbol),
+    side VARCHAR(4) NOT NULL CHECK (side IN ('buy', 'sell')),
+    type VARCHAR(10) NOT NULL CHECK (type IN ('market', 'limit', 'stop', 'stop_limit')),
+    quantity NUMERIC(20, 8) NOT NULL CHECK (quantity > 0),
+    limit_price NUMERIC(18, 4) NULL CHECK (limit_price > 0),
+    stop_price NUMERIC(18, 4) NULL CHECK (stop_price > 0),
+    time_in_force VARCHAR(3) NOT NULL CHECK (time_in_force IN ('day', 'gtc', 'ioc')),
+    status VARCHAR(10) NOT NULL DEFAULT 'open'
+        CHECK (status IN ('open', 'filled', 'cancelled', 'expired', 'rejected')),
+    stop_triggered BOOLEAN NOT NULL DEFAULT FALSE,
+    filled_price NUMERIC(18, 4) NULL,
+    filled_at TIMESTAMP WITH TIME ZONE NULL,
+    transaction_id BIGINT NULL REFERENCES transactions(id) ON DELETE SET NULL,
+    reject_reason TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Matching only ever looks at open orders for one symbol
+CREATE INDEX idx_paper_orders_open_symbol ON paper_orders (symbol) WHERE status = 'open';
+CREATE INDEX idx_paper_orders_portfolio ON paper_orders (portfolio_id, created_at DESC);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_transactions_import;
+DROP INDEX IF EXISTS idx_transactions_external_id;
+ALTER TABLE transactions
+    DROP COLUMN IF EXISTS import_id,
+    DROP COLUMN IF EXISTS external_id;
+DROP TABLE IF EXISTS transaction_imports;
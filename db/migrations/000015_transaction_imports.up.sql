@@ -0,0 +1,25 @@
+-- Broker statement imports. Imported transactions keep the broker's ID so a
+-- statement imported twice only adds what is new, and point at their import
+-- so it can be rolled back as a whole.
+CREATE TABLE transaction_imports (
+    id BIGSERIAL PRIMARY KEY,
+    portfolio_id BIGINT NOT NULL REFERENCES portfolios(id) ON DELETE CASCADE,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('ofx', 'csv')),
+    layout VARCHAR(50) NOT NULL DEFAULT '',
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(12) NOT NULL DEFAULT 'completed' CHECK (status IN ('completed', 'rolled_back')),
+    imported INTEGER NOT NULL DEFAULT 0,
+    duplicates INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    rolled_back_at TIMESTAMP WITH TIME ZONE NULL
+);
+
+CREATE INDEX idx_transaction_imports_portfolio ON transaction_imports (portfolio_id, created_at DESC);
+
+ALTER TABLE transactions
+    ADD COLUMN external_id VARCHAR(255) NULL,
+    ADD COLUMN import_id BIGINT NULL REFERENCES transaction_imports(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_transactions_external_id ON transactions (portfolio_id, external_id) WHERE external_id IS NOT NULL;
+CREATE INDEX idx_transactions_import ON transactions (import_id) WHERE import_id IS NOT NULL;
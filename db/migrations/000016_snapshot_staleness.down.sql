@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_portfolios_snapshots_stale;
+ALTER TABLE portfolios DROP COLUMN IF EXISTS snapshots_stale_from;
@@ -0,0 +1,17 @@
+-- Snapshots are no longer rebuilt on every read. Ledger and price writes
+-- record the earliest day whose valuation they may have changed, and the
+-- snapshot refresher rebuilds each marked portfolio from that day on.
+ALTER TABLE portfolios ADD COLUMN snapshots_stale_from DATE NULL;
+
+-- Snapshots stored so far may be out of date; rebuild them all once
+UPDATE portfolios p
+SET snapshots_stale_from = first.day
+FROM (
+    SELECT portfolio_id, MIN((executed_at AT TIME ZONE 'UTC')::date) AS day
+    FROM transactions
+    GROUP BY portfolio_id
+) first
+WHERE first.portfolio_id = p.id;
+
+CREATE INDEX idx_portfolios_snapshots_stale ON portfolios (snapshots_stale_from)
+    WHERE snapshots_stale_from IS NOT NULL;
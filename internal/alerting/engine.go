@@ -0,0 +1,158 @@
+// Package alerting evaluates users' price alerts as new prices are stored
+package alerting
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+)
+
+// updateBuffer is how many price updates may queue before new ones are
+// dropped; the periodic sweep picks up anything that was missed
+const updateBuffer = 256
+
+// Engine fires alerts for every price published on the bus and periodically
+// sweeps all active alerts against the stored prices. Firing is an atomic
+// state change in the database, so any number of engines, in one process or
+// many, can run against the same alerts without double firing.
+type Engine struct {
+	alerts   repository.AlertRepository
+	stocks   repository.StockRepository
+	prices   *service.PriceBus
+	interval time.Duration
+	onFire   func(models.StockAlert)
+}
+
+func NewEngine(alerts repository.AlertRepository, stocks repository.StockRepository, prices *service.PriceBus, interval time.Duration) *Engine {
+	return &Engine{
+		alerts:   alerts,
+		stocks:   stocks,
+		prices:   prices,
+		interval: interval,
+		onFire:   logFired,
+	}
+}
+
+// OnFire replaces the function called once for each alert that fires
+func (e *Engine) OnFire(fn func(models.StockAlert)) {
+	e.onFire = fn
+}
+
+// Run evaluates alerts until ctx is cancelled; a non-positive interval
+// disables the sweep
+func (e *Engine) Run(ctx context.Context) {
+	updates, unsubscribe := e.prices.Subscribe(updateBuffer)
+	defer unsubscribe()
+
+	var sweep <-chan time.Time
+	if e.interval > 0 {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		sweep = ticker.C
+
+		e.Sweep()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-updates:
+			e.Evaluate(update)
+		case <-sweep:
+			e.Sweep()
+		}
+	}
+}
+
+// Evaluate fires the alerts satisfied by a single price update
+func (e *Engine) Evaluate(update service.PriceUpdate) {
+	fired, err := e.alerts.TriggerForPrice(update.Symbol, update.Price)
+	if err != nil {
+		log.Printf("alerting: failed to evaluate %s: %v", update.Symbol, err)
+	}
+	e.fire(fired)
+
+	e.evaluateConditions(update.Symbol)
+}
+
+// Sweep fires every active alert satisfied by the stored prices
+func (e *Engine) Sweep() {
+	fired, err := e.alerts.TriggerAll()
+	if err != nil {
+		log.Printf("alerting: sweep failed: %v", err)
+	}
+	e.fire(fired)
+
+	symbols, err := e.alerts.ConditionSymbols()
+	if err != nil {
+		log.Printf("alerting: sweep failed: %v", err)
+		return
+	}
+	for _, symbol := range symbols {
+		e.evaluateConditions(symbol)
+	}
+}
+
+// evaluateConditions checks the symbol's condition alerts against its stored
+// daily history
+func (e *Engine) evaluateConditions(symbol string) {
+	alerts, err := e.alerts.ListActiveConditions(symbol)
+	if err != nil {
+		log.Printf("alerting: failed to load condition alerts for %s: %v", symbol, err)
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	lookback := 1
+	for _, alert := range alerts {
+		lookback = max(lookback, service.ConditionLookback(*alert.Condition))
+	}
+
+	history, err := e.stocks.GetHistory(symbol, lookback)
+	if err != nil {
+		log.Printf("alerting: failed to load history for %s: %v", symbol, err)
+		return
+	}
+	if len(history) == 0 {
+		return
+	}
+
+	var matched []int64
+	for _, alert := range alerts {
+		if service.EvaluateCondition(*alert.Condition, history) {
+			matched = append(matched, alert.ID)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	fired, err := e.alerts.Trigger(matched, history[len(history)-1].Close)
+	if err != nil {
+		log.Printf("alerting: failed to trigger alerts for %s: %v", symbol, err)
+		return
+	}
+	e.fire(fired)
+}
+
+func (e *Engine) fire(alerts []models.StockAlert) {
+	for _, alert := range alerts {
+		e.onFire(alert)
+	}
+}
+
+func logFired(alert models.StockAlert) {
+	price := 0.0
+	if alert.TriggeredPrice != nil {
+		price = *alert.TriggeredPrice
+	}
+	log.Printf("alerting: alert %d for user %d fired: %s %s at %.4f",
+		alert.ID, alert.UserID, alert.Symbol, alert.AlertType, price)
+}
@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"errors"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultUserListLimit = 50
+	maxUserListLimit     = 200
+)
+
+type AdminHandler struct {
+	userRepo  repository.UserRepository
+	stockRepo repository.StockRepository
+	avService *service.AlphaVantageService
+	runner    *service.BatchRunner
+	prices    *service.PriceBus
+}
+
+func NewAdminHandler(userRepo repository.UserRepository, stockRepo repository.StockRepository, avService *service.AlphaVantageService, runner *service.BatchRunner, prices *service.PriceBus) *AdminHandler {
+	return &AdminHandler{
+		userRepo:  userRepo,
+		stockRepo: stockRepo,
+		avService: avService,
+		runner:    runner,
+		prices:    prices,
+	}
+}
+
+// ListUsers returns a page of users
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	limit, offset, ok := pageParams(c, defaultUserListLimit, maxUserListLimit)
+	if !ok {
+		return
+	}
+
+	users, total, err := h.userRepo.List(limit, offset)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve users")
+		return
+	}
+
+	response.OKWithMeta(c, http.StatusOK, users, &models.ResponseMeta{
+		Total:  &total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// GetUser returns a single user
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userRepo.GetByID(id)
+	if err != nil {
+		respondUserError(c, err, "Failed to retrieve user")
+		return
+	}
+
+	response.OK(c, http.StatusOK, user)
+}
+
+// UpdateUser changes a user's role
+func (h *AdminHandler) UpdateUser(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	// Keep admins from locking themselves out
+	if id == middleware.UserID(c) && req.Role != models.RoleAdmin {
+		response.Error(c, http.StatusConflict, response.CodeConflict, "You cannot remove your own admin role")
+		return
+	}
+
+	if err := h.userRepo.UpdateRole(id, req.Role); err != nil {
+		respondUserError(c, err, "Failed to update user")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(id)
+	if err != nil {
+		respondUserError(c, err, "Failed to retrieve user")
+		return
+	}
+
+	response.OK(c, http.StatusOK, user)
+}
+
+// DeleteUser removes a user along with their sessions and API keys
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	if id == middleware.UserID(c) {
+		response.Error(c, http.StatusConflict, response.CodeConflict, "You cannot delete your own account")
+		return
+	}
+
+	if err := h.userRepo.Delete(id); err != nil {
+		respondUserError(c, err, "Failed to delete user")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetQuota reports how much of the market data provider quota is used
+func (h *AdminHandler) GetQuota(c *gin.Context) {
+	response.OK(c, http.StatusOK, h.avService.QuotaStatus())
+}
+
+// Refresh re-fetches the given symbols, or every stored symbol, as a
+// background job regardless of batch size
+func (h *AdminHandler) Refresh(c *gin.Context) {
+	var req models.AdminRefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.ValidationError(c, err)
+		return
+	}
+
+	symbols := normalizeSymbols(req.Symbols)
+	if len(symbols) == 0 {
+		var err error
+		symbols, err = h.stockRepo.ListSymbols()
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to list symbols")
+			return
+		}
+	}
+	if len(symbols) == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, "There are no symbols to refresh")
+		return
+	}
+
+	job := h.runner.Submit("refresh", symbols, fetchAndStore(h.stockRepo, h.avService, h.prices))
+	c.Header("Location", "/api/stocks/batch/jobs/"+job.ID)
+	response.OK(c, http.StatusAccepted, job)
+}
+
+// pageParams parses the limit and offset query parameters, capping limit at max
+func pageParams(c *gin.Context, defaultLimit, maxLimit int) (int, int, bool) {
+	limit, offset := defaultLimit, 0
+	if raw := c.Query("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			response.InvalidField(c, "limit", "must be a positive integer")
+			return 0, 0, false
+		}
+		limit = min(value, maxLimit)
+	}
+	if raw := c.Query("offset"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			response.InvalidField(c, "offset", "must not be negative")
+			return 0, 0, false
+		}
+		offset = value
+	}
+	return limit, offset, true
+}
+
+func respondUserError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "User not found")
+		return
+	}
+	response.Error(c, http.StatusInternalServerError, response.CodeInternalError, message)
+}
@@ -0,0 +1,306 @@
+package handler
+
+import (
+	"errors"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxBacktestBars caps how much history an alert backtest replays
+	maxBacktestBars = 5000
+	// backtestSlack covers the bars a calendar estimate loads beyond the
+	// lookback before start and the horizons after end
+	backtestSlack = 40
+)
+
+type AlertHandler struct {
+	alertRepo repository.AlertRepository
+	stockRepo repository.StockRepository
+}
+
+func NewAlertHandler(repo repository.AlertRepository, stockRepo repository.StockRepository) *AlertHandler {
+	return &AlertHandler{
+		alertRepo: repo,
+		stockRepo: stockRepo,
+	}
+}
+
+// ListAlerts returns the current user's alerts; ?active=true|false filters by state
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	var active *bool
+	if raw := c.Query("active"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			response.InvalidField(c, "active", "must be true or false")
+			return
+		}
+		active = &value
+	}
+
+	alerts, err := h.alertRepo.List(middleware.UserID(c), active)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve alerts")
+		return
+	}
+
+	response.OK(c, http.StatusOK, models.StockAlertResponse{
+		Alerts: alerts,
+		Count:  len(alerts),
+	})
+}
+
+// CreateAlert creates an active alert on a stored stock
+func (h *AlertHandler) CreateAlert(c *gin.Context) {
+	var req models.StockAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	alert := models.StockAlert{
+		UserID:      middleware.UserID(c),
+		Symbol:      strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		AlertType:   req.AlertType,
+		TargetPrice: req.TargetPrice,
+		Condition:   req.Condition,
+	}
+	if !checkAlert(c, &alert) {
+		return
+	}
+	if err := h.alertRepo.Create(&alert); err != nil {
+		if errors.Is(err, repository.ErrInvalidReference) {
+			response.InvalidField(c, "symbol", "no stored stock for "+alert.Symbol+"; fetch it first")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to create alert")
+		return
+	}
+
+	response.OK(c, http.StatusCreated, alert)
+}
+
+// GetAlert returns one of the current user's alerts
+func (h *AlertHandler) GetAlert(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	alert, err := h.alertRepo.Get(id, middleware.UserID(c))
+	if err != nil {
+		respondAlertError(c, err, "Failed to retrieve alert")
+		return
+	}
+
+	response.OK(c, http.StatusOK, alert)
+}
+
+// UpdateAlert changes an alert's condition or re-arms a triggered alert
+func (h *AlertHandler) UpdateAlert(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.StockAlertUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	alert, err := h.alertRepo.Get(id, middleware.UserID(c))
+	if err != nil {
+		respondAlertError(c, err, "Failed to retrieve alert")
+		return
+	}
+
+	if req.AlertType != nil {
+		alert.AlertType = *req.AlertType
+	}
+	if req.TargetPrice != nil {
+		alert.TargetPrice = req.TargetPrice
+	}
+	if req.Condition != nil {
+		alert.Condition = req.Condition
+	}
+	if req.IsActive != nil {
+		alert.IsActive = *req.IsActive
+	}
+	if !checkAlert(c, alert) {
+		return
+	}
+	if err := h.alertRepo.Update(alert); err != nil {
+		respondAlertError(c, err, "Failed to update alert")
+		return
+	}
+
+	response.OK(c, http.StatusOK, alert)
+}
+
+// DeleteAlert removes an alert
+func (h *AlertHandler) DeleteAlert(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.alertRepo.Delete(id, middleware.UserID(c)); err != nil {
+		respondAlertError(c, err, "Failed to delete alert")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Backtest replays an alert rule over stored daily history and reports every
+// date it would have fired with the returns that followed
+func (h *AlertHandler) Backtest(c *gin.Context) {
+	var req models.AlertBacktestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	alert := models.StockAlert{
+		Symbol:      strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		AlertType:   req.AlertType,
+		TargetPrice: req.TargetPrice,
+		Condition:   req.Condition,
+	}
+	if !checkAlert(c, &alert) {
+		return
+	}
+
+	// Dates were validated by the binding
+	var start, end time.Time
+	if req.Start != "" {
+		start, _ = time.Parse("2006-01-02", req.Start)
+	}
+	if req.End != "" {
+		end, _ = time.Parse("2006-01-02", req.End)
+		if end.Before(start) {
+			response.InvalidField(c, "end", "must not be before start")
+			return
+		}
+	}
+
+	horizons := req.Horizons
+	if len(horizons) == 0 {
+		horizons = service.DefaultBacktestHorizons
+	}
+
+	// Load the lookback before start, so the first bars are evaluated on the
+	// window the live engine uses, and the horizons after end
+	cond := service.RuleCondition(alert)
+	lookback := service.ConditionLookback(cond)
+	query := models.StockHistoryQuery{Limit: maxBacktestBars + lookback + slices.Max(horizons) + backtestSlack}
+	if !start.IsZero() {
+		since := start.AddDate(0, 0, -calendarDays(lookback))
+		query.Start = &since
+	}
+	if !end.IsZero() {
+		until := end.AddDate(0, 0, calendarDays(slices.Max(horizons)))
+		query.End = &until
+	}
+
+	history, err := h.stockRepo.QueryHistory(alert.Symbol, query)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve stock history")
+		return
+	}
+	if len(history) == 0 {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "No stored history for "+alert.Symbol)
+		return
+	}
+
+	// First bar on or after start and last bar on or before end
+	from := sort.Search(len(history), func(i int) bool {
+		return !history[i].Date.Before(start)
+	})
+	to := len(history) - 1
+	if !end.IsZero() {
+		to = sort.Search(len(history), func(i int) bool {
+			return history[i].Date.After(end.Add(24*time.Hour - time.Nanosecond))
+		}) - 1
+	}
+	if start.IsZero() {
+		from = max(from, to+1-maxBacktestBars)
+	}
+	if from > to {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "No stored history for "+alert.Symbol+" in the requested range")
+		return
+	}
+	// The limit leaves room for every bar of an allowed range, so a range
+	// cut short by it is over the cap too
+	if to-from+1 > maxBacktestBars {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, "The range covers more than "+strconv.Itoa(maxBacktestBars)+" trading days; pass a later start or an earlier end")
+		return
+	}
+
+	firings, summary := service.BacktestCondition(cond, history, from, to, horizons)
+
+	response.OK(c, http.StatusOK, models.AlertBacktestResponse{
+		Symbol:        alert.Symbol,
+		Start:         history[from].Date,
+		End:           history[to].Date,
+		BarsEvaluated: to - from + 1,
+		FiringCount:   len(firings),
+		Firings:       firings,
+		Summary:       summary,
+	})
+}
+
+// checkAlert makes sure an alert has what its type needs: a target price for
+// above/below alerts, a valid condition otherwise
+func checkAlert(c *gin.Context, alert *models.StockAlert) bool {
+	if alert.AlertType != models.AlertTypeCondition {
+		if alert.TargetPrice == nil {
+			response.InvalidField(c, "target_price", "is required for above and below alerts")
+			return false
+		}
+		alert.Condition = nil
+		return true
+	}
+
+	if alert.Condition == nil {
+		response.InvalidField(c, "condition", "is required for condition alerts")
+		return false
+	}
+	if err := service.NormalizeCondition(alert.Condition); err != nil {
+		var condErr *service.ConditionError
+		if errors.As(err, &condErr) {
+			response.InvalidField(c, condErr.Field, condErr.Message)
+		} else {
+			response.InvalidField(c, "condition", err.Error())
+		}
+		return false
+	}
+	alert.TargetPrice = nil
+	return true
+}
+
+func respondAlertError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Alert not found")
+		return
+	}
+	response.Error(c, http.StatusInternalServerError, response.CodeInternalError, message)
+}
+
+// calendarDays estimates how many calendar days span the trading days, with
+// room for holidays
+func calendarDays(tradingDays int) int {
+	return tradingDays*7/5 + 10
+}
@@ -0,0 +1,257 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"go-flow/internal/api/response"
+	"go-flow/internal/backtest"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxAnalyticsBars caps how much history an analytics request loads per symbol
+	maxAnalyticsBars = 5000
+	// maxCompareBenchmarks caps how many benchmarks one comparison includes
+	maxCompareBenchmarks = 10
+	// defaultBacktestCapital is the starting cash of a backtest
+	defaultBacktestCapital = 10000
+)
+
+type AnalyticsHandler struct {
+	stockRepo repository.StockRepository
+}
+
+func NewAnalyticsHandler(stockRepo repository.StockRepository) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		stockRepo: stockRepo,
+	}
+}
+
+// comparison holds the parsed benchmarks and range of a comparison request
+type comparison struct {
+	benchmarks []string
+	indexes    map[string][]service.IndexPoint
+	start, end *time.Time
+	riskFree   float64
+}
+
+// Compare returns a symbol's cumulative returns next to its benchmarks' with
+// alpha, tracking error and information ratio against each
+func (h *AnalyticsHandler) Compare(c *gin.Context) {
+	var req models.CompareRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol == "" {
+		response.InvalidField(c, "symbol", "is required")
+		return
+	}
+
+	bench, ok := loadComparison(c, h.stockRepo, req, symbol)
+	if !ok {
+		return
+	}
+
+	history, err := h.stockRepo.QueryHistory(symbol, models.StockHistoryQuery{Start: bench.start, End: bench.end, Limit: maxAnalyticsBars})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve stock history")
+		return
+	}
+	if len(history) == 0 {
+		response.InvalidField(c, "symbol", "no stored history for "+symbol+"; fetch it first")
+		return
+	}
+
+	response.OK(c, http.StatusOK, service.Compare(symbol, service.SymbolIndex(history), bench.benchmarks, bench.indexes, bench.start, bench.end, bench.riskFree))
+}
+
+// loadComparison parses a comparison's range and benchmarks, leaving out the
+// subject itself, and loads each benchmark's history. It writes the error
+// response and returns false on failure.
+func loadComparison(c *gin.Context, repo repository.StockRepository, req models.CompareRequest, subject string) (comparison, bool) {
+	var bench comparison
+
+	// Dates were validated by the binding
+	if req.Start != "" {
+		start, _ := time.Parse("2006-01-02", req.Start)
+		bench.start = &start
+	}
+	if req.End != "" {
+		end, _ := time.Parse("2006-01-02", req.End)
+		if bench.start != nil && end.Before(*bench.start) {
+			response.InvalidField(c, "end", "must not be before start")
+			return bench, false
+		}
+		bench.end = &end
+	}
+	if req.RiskFreeRate != nil {
+		bench.riskFree = *req.RiskFreeRate
+	}
+
+	symbols := normalizeSymbols(strings.Split(req.Benchmarks, ","))
+	if req.Benchmarks == "" {
+		symbols = []string{service.DefaultCompareBenchmark}
+	}
+	for _, symbol := range symbols {
+		if symbol != subject {
+			bench.benchmarks = append(bench.benchmarks, symbol)
+		}
+	}
+	if len(bench.benchmarks) == 0 {
+		message := "must list at least one symbol"
+		if subject != "" {
+			message += " other than " + subject
+		}
+		response.InvalidField(c, "benchmarks", message)
+		return bench, false
+	}
+	if len(bench.benchmarks) > maxCompareBenchmarks {
+		response.InvalidField(c, "benchmarks", "must list at most 10 symbols")
+		return bench, false
+	}
+
+	bench.indexes = make(map[string][]service.IndexPoint, len(bench.benchmarks))
+	for _, symbol := range bench.benchmarks {
+		history, err := repo.QueryHistory(symbol, models.StockHistoryQuery{Start: bench.start, End: bench.end, Limit: maxAnalyticsBars})
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve benchmark history")
+			return bench, false
+		}
+		if len(history) == 0 {
+			response.InvalidField(c, "benchmarks", "no stored history for "+symbol+"; fetch it first")
+			return bench, false
+		}
+		bench.indexes[symbol] = service.SymbolIndex(history)
+	}
+
+	return bench, true
+}
+
+// Correlation returns the correlation and covariance matrices of the daily
+// returns of the requested symbols
+func (h *AnalyticsHandler) Correlation(c *gin.Context) {
+	var req models.CorrelationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	symbols := normalizeSymbols(req.Symbols)
+	if len(symbols) < 2 {
+		response.InvalidField(c, "symbols", "must list at least two different symbols")
+		return
+	}
+	lookback := req.Lookback
+	if lookback == 0 {
+		lookback = service.DefaultCorrelationLookback
+	}
+
+	histories, err := h.stockRepo.QueryHistories(symbols, models.StockHistoryQuery{Limit: lookback + 1})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve stock history")
+		return
+	}
+	for _, symbol := range symbols {
+		if len(histories[symbol]) < 2 {
+			response.InvalidField(c, "symbols", "not enough stored history for "+symbol+"; fetch it first")
+			return
+		}
+	}
+
+	response.OK(c, http.StatusOK, service.Correlation(symbols, histories, lookback))
+}
+
+// BacktestStrategies lists the strategies a backtest can run with their
+// parameters
+func (h *AnalyticsHandler) BacktestStrategies(c *gin.Context) {
+	response.OK(c, http.StatusOK, backtest.Strategies())
+}
+
+// Backtest runs a built-in strategy over a symbol's stored daily history and
+// reports its equity curve, trades and performance
+func (h *AnalyticsHandler) Backtest(c *gin.Context) {
+	var req models.BacktestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	strategy, params, err := backtest.New(req.Strategy, req.Params)
+	var paramErr *backtest.ParamError
+	switch {
+	case errors.Is(err, backtest.ErrUnknownStrategy):
+		response.InvalidField(c, "strategy", "unknown strategy; see /api/analytics/backtest/strategies")
+		return
+	case errors.As(err, &paramErr):
+		response.InvalidField(c, "params."+paramErr.Name, paramErr.Message)
+		return
+	case err != nil:
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to build strategy")
+		return
+	}
+
+	cfg := backtest.Config{
+		InitialCapital:    req.InitialCapital,
+		SlippageBps:       req.SlippageBps,
+		Commission:        req.Commission,
+		CommissionPercent: req.CommissionPercent,
+	}
+	if cfg.InitialCapital == 0 {
+		cfg.InitialCapital = defaultBacktestCapital
+	}
+
+	// Dates were validated by the binding
+	query := models.StockHistoryQuery{Limit: maxAnalyticsBars}
+	if req.Start != "" {
+		cfg.Start, _ = time.Parse("2006-01-02", req.Start)
+		// The most recent bars are returned, so one more than fits tells a
+		// start too far back from one that fits exactly
+		query.Limit = maxAnalyticsBars + 1
+	}
+	if req.End != "" {
+		cfg.End, _ = time.Parse("2006-01-02", req.End)
+		if cfg.End.Before(cfg.Start) {
+			response.InvalidField(c, "end", "must not be before start")
+			return
+		}
+		query.End = &cfg.End
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	history, err := h.stockRepo.QueryHistory(symbol, query)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve stock history")
+		return
+	}
+	if len(history) == 0 {
+		response.InvalidField(c, "symbol", "no stored history for "+symbol+"; fetch it first")
+		return
+	}
+	if len(history) > maxAnalyticsBars && !history[0].Date.Before(cfg.Start) {
+		response.InvalidField(c, "start", fmt.Sprintf("must be at most %d trading days before end", maxAnalyticsBars))
+		return
+	}
+
+	result, err := backtest.Run(strategy, history, cfg)
+	if errors.Is(err, backtest.ErrNoBars) {
+		response.InvalidField(c, "start", "no stored bars for "+symbol+" between start and end")
+		return
+	}
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to run backtest")
+		return
+	}
+
+	result.Strategy, result.Symbol, result.Params = req.Strategy, symbol, params
+	response.OK(c, http.StatusOK, result)
+}
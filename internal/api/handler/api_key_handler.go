@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"errors"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	keyRepo repository.APIKeyRepository
+}
+
+func NewAPIKeyHandler(repo repository.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{
+		keyRepo: repo,
+	}
+}
+
+// ListKeys returns the current user's active API keys
+func (h *APIKeyHandler) ListKeys(c *gin.Context) {
+	keys, err := h.keyRepo.ListByUser(middleware.UserID(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve API keys")
+		return
+	}
+
+	response.OK(c, http.StatusOK, keys)
+}
+
+// CreateKey issues a new API key; the key is only returned in this response
+func (h *APIKeyHandler) CreateKey(c *gin.Context) {
+	var req models.APIKeyRequest
+	if !bindAPIKeyRequest(c, &req) {
+		return
+	}
+
+	key, prefix, hash := service.GenerateAPIKey()
+	apiKey := models.APIKey{
+		UserID:    middleware.UserID(c),
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := h.keyRepo.Create(&apiKey); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to create API key")
+		return
+	}
+
+	response.OKWithMessage(c, http.StatusCreated, models.CreatedAPIKey{APIKey: apiKey, Key: key},
+		"Store this key now; it cannot be shown again")
+}
+
+// GetKey returns one of the current user's API keys
+func (h *APIKeyHandler) GetKey(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.keyRepo.GetForUser(id, middleware.UserID(c))
+	if err != nil {
+		respondKeyError(c, err, "Failed to retrieve API key")
+		return
+	}
+
+	response.OK(c, http.StatusOK, key)
+}
+
+// UpdateKey changes the name, scopes or expiry of an API key
+func (h *APIKeyHandler) UpdateKey(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.APIKeyRequest
+	if !bindAPIKeyRequest(c, &req) {
+		return
+	}
+
+	key := models.APIKey{
+		ID:        id,
+		UserID:    middleware.UserID(c),
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := h.keyRepo.Update(&key); err != nil {
+		respondKeyError(c, err, "Failed to update API key")
+		return
+	}
+
+	updated, err := h.keyRepo.GetForUser(id, key.UserID)
+	if err != nil {
+		respondKeyError(c, err, "Failed to retrieve API key")
+		return
+	}
+
+	response.OK(c, http.StatusOK, updated)
+}
+
+// DeleteKey revokes an API key
+func (h *APIKeyHandler) DeleteKey(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.keyRepo.Revoke(id, middleware.UserID(c)); err != nil {
+		respondKeyError(c, err, "Failed to revoke API key")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func bindAPIKeyRequest(c *gin.Context, req *models.APIKeyRequest) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		response.ValidationError(c, err)
+		return false
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		response.InvalidField(c, "expires_at", "must be in the future")
+		return false
+	}
+	return true
+}
+
+func respondKeyError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "API key not found")
+		return
+	}
+	response.Error(c, http.StatusInternalServerError, response.CodeInternalError, message)
+}
+
+// paramID parses the numeric :id route parameter, answering 404 when it is
+// not a valid ID
+func paramID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Resource not found")
+		return 0, false
+	}
+	return id, true
+}
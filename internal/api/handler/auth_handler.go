@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"errors"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthHandler struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.RefreshTokenRepository
+	tokens    *service.TokenService
+}
+
+func NewAuthHandler(userRepo repository.UserRepository, tokenRepo repository.RefreshTokenRepository, tokens *service.TokenService) *AuthHandler {
+	return &AuthHandler{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		tokens:    tokens,
+	}
+}
+
+// Register creates an account and starts a session for it
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req models.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	hash, err := service.HashPassword(req.Password)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to create user")
+		return
+	}
+
+	user := &models.User{
+		Email:        strings.ToLower(strings.TrimSpace(req.Email)),
+		Username:     req.Username,
+		PasswordHash: hash,
+	}
+	if err := h.userRepo.Create(user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			response.Error(c, http.StatusConflict, response.CodeConflict, "Email or username is already registered")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to create user")
+		return
+	}
+
+	tokens, err := h.startSession(user.ID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to issue tokens")
+		return
+	}
+
+	response.OK(c, http.StatusCreated, models.AuthResponse{User: user, Tokens: tokens})
+}
+
+// Login verifies the credentials and starts a new session
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req models.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	user, err := h.userRepo.GetByLogin(strings.TrimSpace(req.Login))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to log in")
+		return
+	}
+
+	// Unknown users still run a password comparison to keep timing uniform
+	hash := ""
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !service.CheckPassword(hash, req.Password) {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidCredentials, "Invalid login or password")
+		return
+	}
+
+	tokens, err := h.startSession(user.ID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to issue tokens")
+		return
+	}
+
+	response.OK(c, http.StatusOK, models.AuthResponse{User: user, Tokens: tokens})
+}
+
+// Refresh exchanges a refresh token for a new pair. Each refresh token works
+// once; presenting a used token again revokes the whole session.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	claims, err := h.tokens.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		response.Error(c, http.StatusUnauthorized, response.CodeInvalidCredentials, "The refresh token is invalid or expired")
+		return
+	}
+
+	stored, err := h.tokenRepo.Get(claims.ID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.Error(c, http.StatusUnauthorized, response.CodeInvalidCredentials, "The refresh token is invalid or expired")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to refresh tokens")
+		return
+	}
+
+	tokens, next, err := h.issueTokens(stored.UserID, stored.FamilyID)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to issue tokens")
+		return
+	}
+
+	if err := h.tokenRepo.Rotate(stored.ID, next); err != nil {
+		if errors.Is(err, repository.ErrTokenReused) {
+			// A used token showing up again means it may have been stolen
+			h.tokenRepo.RevokeFamily(stored.FamilyID)
+			response.Error(c, http.StatusUnauthorized, response.CodeInvalidCredentials, "The refresh token has already been used; the session was revoked")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to refresh tokens")
+		return
+	}
+
+	response.OK(c, http.StatusOK, tokens)
+}
+
+// Logout revokes the current session, invalidating its access and refresh tokens
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.tokenRepo.RevokeFamily(middleware.SessionID(c)); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to log out")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll revokes every session of the current user
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	if err := h.tokenRepo.RevokeAllForUser(middleware.UserID(c)); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to log out")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Me returns the authenticated user
+func (h *AuthHandler) Me(c *gin.Context) {
+	user, err := h.userRepo.GetByID(middleware.UserID(c))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.Error(c, http.StatusNotFound, response.CodeNotFound, "User not found")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve user")
+		return
+	}
+
+	response.OK(c, http.StatusOK, user)
+}
+
+// startSession issues the first token pair of a new session
+func (h *AuthHandler) startSession(userID int64) (*models.TokenPair, error) {
+	tokens, refresh, err := h.issueTokens(userID, service.NewTokenID())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.tokenRepo.Create(refresh); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// issueTokens signs an access and refresh token for a session and returns the
+// refresh token record that still needs to be stored
+func (h *AuthHandler) issueTokens(userID int64, sessionID string) (*models.TokenPair, *models.RefreshToken, error) {
+	access, err := h.tokens.IssueAccessToken(userID, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refresh, err := h.tokens.IssueRefreshToken(userID, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pair := &models.TokenPair{
+		AccessToken:      access.Token,
+		RefreshToken:     refresh.Token,
+		TokenType:        "Bearer",
+		ExpiresAt:        access.ExpiresAt,
+		RefreshExpiresAt: refresh.ExpiresAt,
+	}
+	record := &models.RefreshToken{
+		ID:        refresh.ID,
+		UserID:    userID,
+		FamilyID:  sessionID,
+		ExpiresAt: refresh.ExpiresAt,
+	}
+	return pair, record, nil
+}
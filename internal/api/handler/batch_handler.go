@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// syncBatchLimit is the largest batch answered inline; bigger batches run as jobs
+const syncBatchLimit = 5
+
+type BatchHandler struct {
+	stockRepo repository.StockRepository
+	avService *service.AlphaVantageService
+	runner    *service.BatchRunner
+	prices    *service.PriceBus
+}
+
+func NewBatchHandler(repo repository.StockRepository, avService *service.AlphaVantageService, runner *service.BatchRunner, prices *service.PriceBus) *BatchHandler {
+	return &BatchHandler{
+		stockRepo: repo,
+		avService: avService,
+		runner:    runner,
+		prices:    prices,
+	}
+}
+
+// BatchFetch fetches and stores daily data for a list of symbols
+func (h *BatchHandler) BatchFetch(c *gin.Context) {
+	h.runBatch(c, "fetch", fetchAndStore(h.stockRepo, h.avService, h.prices))
+}
+
+// BatchQuotes returns the latest quote for a list of symbols
+func (h *BatchHandler) BatchQuotes(c *gin.Context) {
+	h.runBatch(c, "quotes", func(ctx context.Context, symbol string) (any, error) {
+		return h.avService.GetQuote(ctx, symbol)
+	})
+}
+
+// GetJob returns the status and results of an asynchronous batch
+func (h *BatchHandler) GetJob(c *gin.Context) {
+	job, ok := h.runner.Job(c.Param("id"))
+	if !ok {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Job not found")
+		return
+	}
+
+	response.OK(c, http.StatusOK, job)
+}
+
+// runBatch answers small batches inline, stopping if the client goes away,
+// and submits large ones as a job
+func (h *BatchHandler) runBatch(c *gin.Context, jobType string, fn service.BatchFunc) {
+	var req models.StockListRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	symbols := normalizeSymbols(req.Symbols)
+	if len(symbols) == 0 {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, "At least one symbol is required")
+		return
+	}
+
+	if len(symbols) > syncBatchLimit {
+		job := h.runner.Submit(jobType, symbols, fn)
+		c.Header("Location", "/api/stocks/batch/jobs/"+job.ID)
+		response.OK(c, http.StatusAccepted, job)
+		return
+	}
+
+	response.OK(c, http.StatusOK, h.runner.Run(c.Request.Context(), symbols, fn))
+}
+
+// fetchAndStore returns a batch function that fetches daily data for a symbol
+// and stores it
+func fetchAndStore(repo repository.StockRepository, avService *service.AlphaVantageService, prices *service.PriceBus) service.BatchFunc {
+	return func(ctx context.Context, symbol string) (any, error) {
+		stockData, err := avService.GetDailyStockData(ctx, symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := storeStockData(repo, prices, stockData); err != nil {
+			return nil, err
+		}
+
+		return gin.H{"count": len(stockData)}, nil
+	}
+}
+
+// storeStockData saves daily data and publishes its latest bar so alerts and
+// paper orders are evaluated against it
+func storeStockData(repo repository.StockRepository, prices *service.PriceBus, data []service.StockData) error {
+	if err := repo.SaveStockData(data); err != nil {
+		return err
+	}
+
+	if len(data) > 0 {
+		update := service.PriceUpdate{
+			Symbol: data[0].Symbol,
+			Price:  data[0].Close,
+			Time:   time.Now(),
+			High:   data[0].High,
+			Low:    data[0].Low,
+		}
+		update.BarDate, _ = time.Parse("2006-01-02", data[0].Date)
+		prices.Publish(update)
+	}
+
+	return nil
+}
+
+// normalizeSymbols upper-cases symbols and drops blanks and duplicates
+func normalizeSymbols(symbols []string) []string {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		normalized = append(normalized, symbol)
+	}
+	return normalized
+}
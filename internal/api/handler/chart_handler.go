@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-flow/internal/api/response"
+	"go-flow/internal/chart"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultChartWidth  = 800
+	defaultChartHeight = 450
+	defaultChartRange  = "6m"
+	defaultChartTheme  = "light"
+	// maxChartBars caps how many bars a chart with an explicit start plots;
+	// longer ranges are rejected
+	maxChartBars = 2000
+	// maxChartSMAs caps how many moving averages one chart overlays
+	maxChartSMAs = 4
+)
+
+// chartRangeMonths is how far back each chart range reaches from the last bar
+var chartRangeMonths = map[string]int{"1m": 1, "3m": 3, "6m": 6, "ytd": 12, "1y": 12, "2y": 24, "5y": 60}
+
+type ChartHandler struct {
+	stockRepo repository.StockRepository
+}
+
+func NewChartHandler(stockRepo repository.StockRepository) *ChartHandler {
+	return &ChartHandler{
+		stockRepo: stockRepo,
+	}
+}
+
+// ChartPNG renders a stock's candlestick chart as a PNG image
+func (h *ChartHandler) ChartPNG(c *gin.Context) {
+	h.render(c, chart.FormatPNG)
+}
+
+// ChartSVG renders a stock's candlestick chart as an SVG image
+func (h *ChartHandler) ChartSVG(c *gin.Context) {
+	h.render(c, chart.FormatSVG)
+}
+
+// render draws the chart the query describes from stored history. Bars
+// before the range are loaded too so the overlays are defined from the
+// first plotted bar.
+func (h *ChartHandler) render(c *gin.Context, format string) {
+	var req models.ChartRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	opts := chart.Options{
+		Width:     cmp.Or(req.Width, defaultChartWidth),
+		Height:    cmp.Or(req.Height, defaultChartHeight),
+		Theme:     cmp.Or(req.Theme, defaultChartTheme),
+		Bollinger: req.Bollinger,
+		Volume:    req.Volume == nil || *req.Volume,
+	}
+	if req.SMA != "" {
+		for _, raw := range strings.Split(req.SMA, ",") {
+			period, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil || period < 2 || period > 200 {
+				response.InvalidField(c, "sma", "must list periods between 2 and 200")
+				return
+			}
+			if !slices.Contains(opts.SMA, period) {
+				opts.SMA = append(opts.SMA, period)
+			}
+		}
+		if len(opts.SMA) > maxChartSMAs {
+			response.InvalidField(c, "sma", "must list at most 4 periods")
+			return
+		}
+	}
+
+	// Dates were validated by the binding
+	var start *time.Time
+	query := models.StockHistoryQuery{}
+	if req.Start != "" {
+		date, _ := time.Parse("2006-01-02", req.Start)
+		start = &date
+	}
+	if req.End != "" {
+		end, _ := time.Parse("2006-01-02", req.End)
+		if start != nil && end.Before(*start) {
+			response.InvalidField(c, "end", "must not be before start")
+			return
+		}
+		query.End = &end
+	}
+
+	warmup := opts.Warmup()
+	rangeName := cmp.Or(req.Range, defaultChartRange)
+	if start != nil {
+		// The most recent bars are returned, so one more than fits tells a
+		// range that is too long from one that fits exactly
+		since := start.AddDate(0, 0, -calendarDays(warmup))
+		query.Start, query.Limit = &since, maxChartBars+warmup+1
+	} else {
+		query.Limit = chartRangeMonths[rangeName]*23 + warmup
+	}
+
+	symbol := strings.ToUpper(c.Param("id"))
+	history, err := h.stockRepo.QueryHistory(symbol, query)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve stock history")
+		return
+	}
+
+	if start == nil && len(history) > 0 {
+		last := history[len(history)-1].Date
+		since := last.AddDate(0, -chartRangeMonths[rangeName], 0)
+		if rangeName == "ytd" {
+			since = time.Date(last.Year(), time.January, 1, 0, 0, 0, 0, last.Location())
+		}
+		start = &since
+	}
+	from := len(history)
+	if start != nil {
+		from, _ = slices.BinarySearchFunc(history, *start, func(e models.StockHistoryEntry, t time.Time) int {
+			return e.Date.Compare(t)
+		})
+	}
+	if from == len(history) {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "No stored history for "+symbol+" in the requested range")
+		return
+	}
+	if req.Start != "" && len(history)-from > maxChartBars {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest,
+			fmt.Sprintf("The range covers more than %d trading days; pass a later start or an earlier end", maxChartBars))
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := chart.Render(&buf, format, symbol, history, from, opts); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to render chart")
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, chart.ContentType(format), buf.Bytes())
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/export"
+	"go-flow/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	portfolioRepo repository.PortfolioRepository
+	exporter      *export.Exporter
+}
+
+func NewExportHandler(portfolioRepo repository.PortfolioRepository, exporter *export.Exporter) *ExportHandler {
+	return &ExportHandler{
+		portfolioRepo: portfolioRepo,
+		exporter:      exporter,
+	}
+}
+
+// ExportHistory streams a stock's daily history, optionally limited to a
+// date range
+func (h *ExportHandler) ExportHistory(c *gin.Context) {
+	exportStock(c, export.DatasetHistory, h.exporter.History)
+}
+
+// ExportIndicators streams a stock's daily technical indicators, optionally
+// limited to a date range
+func (h *ExportHandler) ExportIndicators(c *gin.Context) {
+	exportStock(c, export.DatasetIndicators, h.exporter.Indicators)
+}
+
+// ExportTransactions streams a portfolio's ledger
+func (h *ExportHandler) ExportTransactions(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.portfolioRepo.Get(id, middleware.UserID(c)); err != nil {
+		respondPortfolioError(c, err, "Failed to export transactions")
+		return
+	}
+
+	w := newExportWriter(c, format, fmt.Sprintf("portfolio-%d-%s", id, export.DatasetTransactions))
+	finishExport(c, w, h.exporter.Transactions(w, format, id), "Failed to export transactions")
+}
+
+// ExportValuations streams a portfolio's daily valuations
+func (h *ExportHandler) ExportValuations(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+
+	// The ownership check runs inside the export, before any row is written
+	w := newExportWriter(c, format, fmt.Sprintf("portfolio-%d-%s", id, export.DatasetValuations))
+	err := h.exporter.Valuations(w, format, id, middleware.UserID(c))
+	if errors.Is(err, repository.ErrNotFound) {
+		respondPortfolioError(c, err, "Failed to export valuations")
+		return
+	}
+	finishExport(c, w, err, "Failed to export valuations")
+}
+
+// exportStock streams one of a stock's datasets over the requested range
+func exportStock(c *gin.Context, dataset string, write func(w io.Writer, format, symbol string, start, end *time.Time) error) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	start, err := parseDateQuery(c, "start")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, err.Error())
+		return
+	}
+	end, err := parseDateQuery(c, "end")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	symbol := strings.ToUpper(c.Param("id"))
+	w := newExportWriter(c, format, symbol+"-"+dataset)
+	err = write(w, format, symbol, start, end)
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Stock not found")
+		return
+	}
+	finishExport(c, w, err, "Failed to export "+dataset)
+}
+
+// exportFormat negotiates the export format from the format parameter and
+// the Accept header
+func exportFormat(c *gin.Context) (string, bool) {
+	format, err := export.Negotiate(c.GetHeader("Accept"), c.Query("format"))
+	if err != nil {
+		response.InvalidField(c, "format", err.Error())
+		return "", false
+	}
+	return format, true
+}
+
+// finishExport reports an export error. Once rows have been sent the status
+// can no longer change, so the error is only logged and the response ends
+// early; a truncated Parquet file lacks its footer and will not open.
+func finishExport(c *gin.Context, w *exportWriter, err error, message string) {
+	if err == nil {
+		return
+	}
+	if !w.started {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, message)
+		return
+	}
+	log.Printf("export %s failed after it started: %v", c.Request.URL.Path, err)
+	c.Abort()
+}
+
+// exportWriter sends the export's headers with its first bytes, so an export
+// that fails before producing anything can still answer with an error
+type exportWriter struct {
+	c        *gin.Context
+	format   string
+	filename string
+	started  bool
+}
+
+func newExportWriter(c *gin.Context, format, name string) *exportWriter {
+	return &exportWriter{c: c, format: format, filename: name + "." + format}
+}
+
+func (w *exportWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		header := w.c.Writer.Header()
+		header.Set("Content-Type", export.ContentType(w.format))
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+		header.Set("X-Content-Type-Options", "nosniff")
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
+func (w *exportWriter) Flush() {
+	if w.started {
+		w.c.Writer.Flush()
+	}
+}
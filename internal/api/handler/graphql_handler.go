@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/graph"
+
+	gqlhandler "github.com/99designs/gqlgen/graphql/handler"
+	"github.com/gin-gonic/gin"
+)
+
+type GraphQLHandler struct {
+	resolver *graph.Resolver
+	server   *gqlhandler.Server
+}
+
+func NewGraphQLHandler(resolver *graph.Resolver, complexityLimit int) *GraphQLHandler {
+	return &GraphQLHandler{
+		resolver: resolver,
+		server:   graph.NewServer(resolver, complexityLimit),
+	}
+}
+
+// Serve executes GraphQL queries over GET and POST and subscriptions over
+// WebSocket, on behalf of the authenticated user if there is one
+func (h *GraphQLHandler) Serve(c *gin.Context) {
+	ctx := h.resolver.WithRequest(c.Request.Context(), graph.Viewer{
+		UserID: middleware.UserID(c),
+		Scopes: middleware.Scopes(c),
+	})
+	h.server.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
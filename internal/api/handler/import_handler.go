@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/importer"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxStatementSize bounds an uploaded statement; the request body may be a
+// little larger for the other form fields and multipart framing
+const (
+	maxStatementSize  = 10 << 20
+	maxImportBodySize = maxStatementSize + 64<<10
+)
+
+type ImportHandler struct {
+	importRepo repository.ImportRepository
+}
+
+func NewImportHandler(importRepo repository.ImportRepository) *ImportHandler {
+	return &ImportHandler{
+		importRepo: importRepo,
+	}
+}
+
+// ListLayouts returns the built-in CSV layouts
+func (h *ImportHandler) ListLayouts(c *gin.Context) {
+	response.OK(c, http.StatusOK, importer.Layouts())
+}
+
+// CreateImport reads an uploaded OFX, QFX or CSV statement into a
+// portfolio's ledger. With dry_run it returns what the import would do
+// without changing anything.
+func (h *ImportHandler) CreateImport(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	// Stop reading an oversized upload instead of spooling it to disk
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodySize)
+
+	var req models.ImportRequest
+	err := c.ShouldBind(&req)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		response.InvalidField(c, "file", "must not be larger than 10 MB")
+		return
+	}
+	if err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.InvalidField(c, "file", "is required")
+		return
+	}
+	if file.Size > maxStatementSize {
+		response.InvalidField(c, "file", "must not be larger than 10 MB")
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to read statement")
+		return
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to read statement")
+		return
+	}
+
+	imp := models.TransactionImport{
+		PortfolioID: id,
+		Format:      req.Format,
+		Filename:    filepath.Base(file.Filename),
+	}
+	if imp.Format == "qfx" {
+		imp.Format = models.ImportFormatOFX
+	}
+	if imp.Format == "" {
+		imp.Format = importer.DetectFormat(file.Filename, data)
+	}
+
+	var rows []models.ImportRow
+	if imp.Format == models.ImportFormatOFX {
+		rows, err = importer.ParseOFX(bytes.NewReader(data))
+	} else {
+		layout, ok := csvLayout(c, req)
+		if !ok {
+			return
+		}
+		imp.Layout = layout.Name
+		rows, err = importer.ParseCSV(bytes.NewReader(data), layout)
+	}
+	if err != nil {
+		response.InvalidField(c, "file", err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		response.InvalidField(c, "file", "contains no transactions")
+		return
+	}
+
+	result, err := h.importRepo.Import(middleware.UserID(c), &imp, rows, req.DryRun)
+	var importErr *repository.ImportError
+	switch {
+	case errors.As(err, &importErr):
+		response.Error(c, http.StatusConflict, response.CodeConflict,
+			"The ledger would become inconsistent at "+importErr.Error()+"; preview the import with dry_run to see every row")
+		return
+	case err != nil:
+		respondImportError(c, err, "Failed to import statement")
+		return
+	}
+
+	status := http.StatusCreated
+	if req.DryRun {
+		status = http.StatusOK
+	}
+	response.OK(c, status, result)
+}
+
+// csvLayout returns the built-in layout the request names or the custom
+// layout it carries
+func csvLayout(c *gin.Context, req models.ImportRequest) (models.CSVLayout, bool) {
+	if req.LayoutConfig == "" {
+		if req.Layout == "" {
+			response.InvalidField(c, "layout", "is required for CSV statements; see /api/imports/layouts")
+			return models.CSVLayout{}, false
+		}
+		layout, ok := importer.Layout(req.Layout)
+		if !ok {
+			response.InvalidField(c, "layout", "is not a built-in layout; see /api/imports/layouts")
+		}
+		return layout, ok
+	}
+
+	var layout models.CSVLayout
+	if err := json.Unmarshal([]byte(req.LayoutConfig), &layout); err != nil {
+		response.InvalidField(c, "layout_config", "is not valid JSON: "+err.Error())
+		return models.CSVLayout{}, false
+	}
+	if err := binding.Validator.ValidateStruct(&layout); err != nil {
+		response.ValidationError(c, err)
+		return models.CSVLayout{}, false
+	}
+	layout.Name = strings.TrimSpace(layout.Name)
+	if layout.Name == "" {
+		layout.Name = "custom"
+	}
+	return layout, true
+}
+
+// ListImports returns a portfolio's import history, newest first
+func (h *ImportHandler) ListImports(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	imports, err := h.importRepo.List(id, middleware.UserID(c))
+	if err != nil {
+		respondImportError(c, err, "Failed to retrieve imports")
+		return
+	}
+
+	response.OK(c, http.StatusOK, imports)
+}
+
+// GetImport returns one import of a portfolio
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	id, importID, ok := importParams(c)
+	if !ok {
+		return
+	}
+
+	imp, err := h.importRepo.Get(id, middleware.UserID(c), importID)
+	if err != nil {
+		respondImportError(c, err, "Failed to retrieve import")
+		return
+	}
+
+	response.OK(c, http.StatusOK, imp)
+}
+
+// RollbackImport removes every transaction an import recorded
+func (h *ImportHandler) RollbackImport(c *gin.Context) {
+	id, importID, ok := importParams(c)
+	if !ok {
+		return
+	}
+
+	imp, err := h.importRepo.Rollback(id, middleware.UserID(c), importID)
+	if err != nil {
+		respondImportError(c, err, "Failed to roll back import")
+		return
+	}
+
+	response.OK(c, http.StatusOK, imp)
+}
+
+// importParams reads the portfolio and import IDs from the path
+func importParams(c *gin.Context) (int64, int64, bool) {
+	id, ok := paramID(c)
+	if !ok {
+		return 0, 0, false
+	}
+
+	importID, err := strconv.ParseInt(c.Param("import_id"), 10, 64)
+	if err != nil || importID <= 0 {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Import not found")
+		return 0, 0, false
+	}
+
+	return id, importID, true
+}
+
+func respondImportError(c *gin.Context, err error, message string) {
+	var ledgerErr *service.LedgerError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Portfolio or import not found")
+	case errors.Is(err, repository.ErrAlreadyRolledBack):
+		response.Error(c, http.StatusConflict, response.CodeConflict, "The import has already been rolled back")
+	case errors.Is(err, repository.ErrPaperPortfolio):
+		response.Error(c, http.StatusConflict, response.CodeConflict, "Statements cannot be imported into a paper account")
+	case errors.As(err, &ledgerErr):
+		response.Error(c, http.StatusConflict, response.CodeConflict, "The ledger would become inconsistent at "+ledgerErr.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, message)
+	}
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/notify"
+	"go-flow/internal/repository"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+)
+
+type NotificationHandler struct {
+	notificationRepo repository.NotificationRepository
+}
+
+func NewNotificationHandler(repo repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{
+		notificationRepo: repo,
+	}
+}
+
+// ListNotifications returns a page of the user's inbox; ?unread=true hides
+// notifications already read
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	limit, offset, ok := pageParams(c, defaultNotificationLimit, maxNotificationLimit)
+	if !ok {
+		return
+	}
+
+	unreadOnly := false
+	if raw := c.Query("unread"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			response.InvalidField(c, "unread", "must be true or false")
+			return
+		}
+		unreadOnly = value
+	}
+
+	notifications, total, err := h.notificationRepo.List(middleware.UserID(c), unreadOnly, limit, offset)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve notifications")
+		return
+	}
+
+	response.OKWithMeta(c, http.StatusOK, notifications, &models.ResponseMeta{
+		Total:  &total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// UnreadCount returns how many inbox notifications are unread
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	count, err := h.notificationRepo.UnreadCount(middleware.UserID(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to count notifications")
+		return
+	}
+
+	response.OK(c, http.StatusOK, gin.H{"unread": count})
+}
+
+// UpdateNotification marks a notification read or unread
+func (h *NotificationHandler) UpdateNotification(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.NotificationUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	notification, err := h.notificationRepo.SetRead(id, middleware.UserID(c), *req.Read)
+	if err != nil {
+		respondNotificationError(c, err, "Failed to update notification")
+		return
+	}
+
+	response.OK(c, http.StatusOK, notification)
+}
+
+// MarkAllRead marks every unread notification read
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	updated, err := h.notificationRepo.MarkAllRead(middleware.UserID(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to update notifications")
+		return
+	}
+
+	response.OK(c, http.StatusOK, gin.H{"updated": updated})
+}
+
+// DeleteNotification removes a notification from the inbox
+func (h *NotificationHandler) DeleteNotification(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.notificationRepo.Delete(id, middleware.UserID(c)); err != nil {
+		respondNotificationError(c, err, "Failed to delete notification")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetPreferences returns the user's notification preferences
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	prefs, err := h.notificationRepo.GetPreferences(middleware.UserID(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve notification preferences")
+		return
+	}
+
+	response.OK(c, http.StatusOK, prefs)
+}
+
+// UpdatePreferences replaces the user's notification preferences. A webhook
+// signing secret is generated the first time a webhook URL is set, and
+// again when rotate_secret is true.
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	var req models.NotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	if req.WebhookEnabled && req.WebhookURL == "" {
+		response.InvalidField(c, "webhook_url", "is required when webhooks are enabled")
+		return
+	}
+	if req.WebhookURL != "" && notify.ValidateWebhookURL(req.WebhookURL) != nil {
+		response.InvalidField(c, "webhook_url", "must be an https URL on a public host")
+		return
+	}
+	if (req.QuietHoursStart == nil) != (req.QuietHoursEnd == nil) {
+		response.InvalidField(c, "quiet_hours_end", "quiet hours need both a start and an end")
+		return
+	}
+
+	prefs, err := h.notificationRepo.GetPreferences(middleware.UserID(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve notification preferences")
+		return
+	}
+
+	prefs.InAppEnabled = req.InAppEnabled
+	prefs.EmailEnabled = req.EmailEnabled
+	prefs.WebhookEnabled = req.WebhookEnabled
+	prefs.WebhookURL = req.WebhookURL
+	prefs.QuietHoursStart = req.QuietHoursStart
+	prefs.QuietHoursEnd = req.QuietHoursEnd
+	prefs.Timezone = req.Timezone
+	if prefs.Timezone == "" {
+		prefs.Timezone = "UTC"
+	}
+	if req.MaxPerHour != nil {
+		prefs.MaxPerHour = *req.MaxPerHour
+	}
+	if req.RotateSecret || (prefs.WebhookURL != "" && prefs.WebhookSecret == "") {
+		prefs.WebhookSecret = notify.NewWebhookSecret()
+	}
+
+	if err := h.notificationRepo.SavePreferences(prefs); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to save notification preferences")
+		return
+	}
+
+	response.OK(c, http.StatusOK, prefs)
+}
+
+func respondNotificationError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrNotFound) {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Notification not found")
+		return
+	}
+	response.Error(c, http.StatusInternalServerError, response.CodeInternalError, message)
+}
@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"cmp"
+	"errors"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/paper"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultOrderLimit = 50
+	maxOrderLimit     = 500
+)
+
+type PaperHandler struct {
+	portfolioRepo repository.PortfolioRepository
+	paperRepo     repository.PaperRepository
+	engine        *paper.Engine
+}
+
+func NewPaperHandler(portfolioRepo repository.PortfolioRepository, paperRepo repository.PaperRepository, engine *paper.Engine) *PaperHandler {
+	return &PaperHandler{
+		portfolioRepo: portfolioRepo,
+		paperRepo:     paperRepo,
+		engine:        engine,
+	}
+}
+
+// ListAccounts returns the current user's paper accounts with their totals
+func (h *PaperHandler) ListAccounts(c *gin.Context) {
+	portfolios, err := h.portfolioRepo.List(middleware.UserID(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve paper accounts")
+		return
+	}
+
+	accounts := slices.DeleteFunc(portfolios, func(p models.Portfolio) bool { return !p.IsPaper })
+	for i := range accounts {
+		service.ValuePortfolio(&accounts[i])
+		accounts[i].Positions = nil
+	}
+
+	response.OK(c, http.StatusOK, accounts)
+}
+
+// CreateAccount opens a paper account funded with simulated cash. The
+// account is a portfolio, so the portfolio endpoints report its positions,
+// transactions and performance.
+func (h *PaperHandler) CreateAccount(c *gin.Context) {
+	var req models.PaperAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	account := models.Portfolio{
+		UserID:          middleware.UserID(c),
+		Name:            strings.TrimSpace(req.Name),
+		CostBasisMethod: cmp.Or(req.CostBasisMethod, models.CostBasisFIFO),
+		IsPaper:         true,
+	}
+	if err := h.portfolioRepo.Create(&account, req.InitialCash); err != nil {
+		respondPortfolioError(c, err, "Failed to create paper account")
+		return
+	}
+
+	service.ValuePortfolio(&account)
+	response.OK(c, http.StatusCreated, account)
+}
+
+// ListOrders returns a page of a paper account's orders, newest first,
+// optionally filtered by ?status
+func (h *PaperHandler) ListOrders(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var filter models.PaperOrderFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	limit, offset, ok := pageParams(c, defaultOrderLimit, maxOrderLimit)
+	if !ok {
+		return
+	}
+
+	orders, total, err := h.paperRepo.ListOrders(id, middleware.UserID(c), filter.Status, limit, offset)
+	if err != nil {
+		respondPaperError(c, err, "Failed to retrieve orders")
+		return
+	}
+
+	response.OKWithMeta(c, http.StatusOK, orders, &models.ResponseMeta{
+		Total:  &total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// CreateOrder places an order in a paper account. It is matched at once
+// against the symbol's latest stored price and afterwards against every new
+// quote or bar, so the response may already show it filled.
+func (h *PaperHandler) CreateOrder(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PaperOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	order := models.PaperOrder{
+		PortfolioID: id,
+		Symbol:      strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		Side:        req.Side,
+		Type:        req.Type,
+		Quantity:    req.Quantity,
+		LimitPrice:  req.LimitPrice,
+		StopPrice:   req.StopPrice,
+		TimeInForce: req.TimeInForce,
+	}
+	var orderErr *service.OrderError
+	if err := service.PrepareOrder(&order, time.Now()); errors.As(err, &orderErr) {
+		response.InvalidField(c, orderErr.Field, orderErr.Message)
+		return
+	}
+
+	err := h.paperRepo.CreateOrder(middleware.UserID(c), &order)
+	if errors.Is(err, repository.ErrInvalidReference) {
+		response.InvalidField(c, "symbol", "no stored stock for "+order.Symbol+"; fetch it first")
+		return
+	}
+	if err != nil {
+		respondPaperError(c, err, "Failed to place order")
+		return
+	}
+
+	submitted, err := h.engine.Submit(&order)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to match order")
+		return
+	}
+
+	response.OK(c, http.StatusCreated, submitted)
+}
+
+// GetOrder returns one order of a paper account
+func (h *PaperHandler) GetOrder(c *gin.Context) {
+	id, orderID, ok := orderParams(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.paperRepo.GetOrder(id, middleware.UserID(c), orderID)
+	if err != nil {
+		respondPaperError(c, err, "Failed to retrieve order")
+		return
+	}
+
+	response.OK(c, http.StatusOK, order)
+}
+
+// CancelOrder cancels an open order
+func (h *PaperHandler) CancelOrder(c *gin.Context) {
+	id, orderID, ok := orderParams(c)
+	if !ok {
+		return
+	}
+
+	order, err := h.paperRepo.CancelOrder(id, middleware.UserID(c), orderID)
+	if err != nil {
+		respondPaperError(c, err, "Failed to cancel order")
+		return
+	}
+
+	response.OK(c, http.StatusOK, order)
+}
+
+// orderParams reads the account and order IDs from the path
+func orderParams(c *gin.Context) (int64, int64, bool) {
+	id, ok := paramID(c)
+	if !ok {
+		return 0, 0, false
+	}
+
+	orderID, err := strconv.ParseInt(c.Param("order_id"), 10, 64)
+	if err != nil || orderID <= 0 {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Order not found")
+		return 0, 0, false
+	}
+
+	return id, orderID, true
+}
+
+func respondPaperError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Paper account or order not found")
+	case errors.Is(err, repository.ErrOrderNotOpen):
+		response.Error(c, http.StatusConflict, response.CodeConflict, "The order is no longer open")
+	default:
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, message)
+	}
+}
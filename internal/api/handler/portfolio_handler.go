@@ -0,0 +1,430 @@
+package handler
+
+import (
+	"cmp"
+	"errors"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTransactionLimit = 50
+	maxTransactionLimit     = 500
+)
+
+type PortfolioHandler struct {
+	portfolioRepo repository.PortfolioRepository
+	stockRepo     repository.StockRepository
+}
+
+func NewPortfolioHandler(portfolioRepo repository.PortfolioRepository, stockRepo repository.StockRepository) *PortfolioHandler {
+	return &PortfolioHandler{
+		portfolioRepo: portfolioRepo,
+		stockRepo:     stockRepo,
+	}
+}
+
+// ListPortfolios returns the current user's portfolios with their totals
+func (h *PortfolioHandler) ListPortfolios(c *gin.Context) {
+	portfolios, err := h.portfolioRepo.List(middleware.UserID(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve portfolios")
+		return
+	}
+
+	// Positions are only listed on the single portfolio view
+	for i := range portfolios {
+		service.ValuePortfolio(&portfolios[i])
+		portfolios[i].Positions = nil
+	}
+
+	response.OK(c, http.StatusOK, portfolios)
+}
+
+// CreatePortfolio creates a portfolio, optionally funded with an initial
+// deposit
+func (h *PortfolioHandler) CreatePortfolio(c *gin.Context) {
+	var req models.PortfolioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	portfolio := models.Portfolio{
+		UserID:          middleware.UserID(c),
+		Name:            strings.TrimSpace(req.Name),
+		CostBasisMethod: cmp.Or(req.CostBasisMethod, models.CostBasisFIFO),
+	}
+	var deposit float64
+	if req.InitialDeposit != nil {
+		deposit = *req.InitialDeposit
+	}
+	if err := h.portfolioRepo.Create(&portfolio, deposit); err != nil {
+		respondPortfolioError(c, err, "Failed to create portfolio")
+		return
+	}
+
+	service.ValuePortfolio(&portfolio)
+	response.OK(c, http.StatusCreated, portfolio)
+}
+
+// GetPortfolio returns a portfolio valued at the latest prices, with the
+// unrealized gain of each position
+func (h *PortfolioHandler) GetPortfolio(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	portfolio, err := h.portfolioRepo.Get(id, middleware.UserID(c))
+	if err != nil {
+		respondPortfolioError(c, err, "Failed to retrieve portfolio")
+		return
+	}
+
+	service.ValuePortfolio(portfolio)
+	response.OK(c, http.StatusOK, portfolio)
+}
+
+// UpdatePortfolio renames a portfolio or changes its cost basis method,
+// which re-derives its positions from the ledger
+func (h *PortfolioHandler) UpdatePortfolio(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PortfolioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	userID := middleware.UserID(c)
+	portfolio, err := h.portfolioRepo.Get(id, userID)
+	if err != nil {
+		respondPortfolioError(c, err, "Failed to retrieve portfolio")
+		return
+	}
+
+	portfolio.Name = strings.TrimSpace(req.Name)
+	portfolio.CostBasisMethod = cmp.Or(req.CostBasisMethod, portfolio.CostBasisMethod)
+	if err := h.portfolioRepo.Update(portfolio); err != nil {
+		respondPortfolioError(c, err, "Failed to update portfolio")
+		return
+	}
+
+	// Reload so positions reflect a changed cost basis method
+	if portfolio, err = h.portfolioRepo.Get(id, userID); err != nil {
+		respondPortfolioError(c, err, "Failed to retrieve portfolio")
+		return
+	}
+
+	service.ValuePortfolio(portfolio)
+	response.OK(c, http.StatusOK, portfolio)
+}
+
+// DeletePortfolio removes a portfolio with its positions and transactions
+func (h *PortfolioHandler) DeletePortfolio(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.portfolioRepo.Delete(id, middleware.UserID(c)); err != nil {
+		respondPortfolioError(c, err, "Failed to delete portfolio")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListLots returns the open tax lots of a portfolio with their split-adjusted
+// quantity and cost
+func (h *PortfolioHandler) ListLots(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	ledger, err := h.portfolioRepo.Ledger(id, middleware.UserID(c))
+	if err != nil {
+		respondPortfolioError(c, err, "Failed to retrieve lots")
+		return
+	}
+
+	lots := ledger.OpenLots()
+	if symbol := strings.ToUpper(c.Query("symbol")); symbol != "" {
+		lots = slices.DeleteFunc(lots, func(lot models.TaxLot) bool { return lot.Symbol != symbol })
+	}
+
+	response.OK(c, http.StatusOK, lots)
+}
+
+// RealizedGains returns the gain realized on each lot a sale drew from,
+// optionally limited to sales between ?start and ?end (inclusive)
+func (h *PortfolioHandler) RealizedGains(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	start, err := parseDateQuery(c, "start")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, err.Error())
+		return
+	}
+	end, err := parseDateQuery(c, "end")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	var from, to time.Time
+	if start != nil {
+		from = *start
+	}
+	if end != nil {
+		to = end.AddDate(0, 0, 1)
+	}
+
+	ledger, err := h.portfolioRepo.Ledger(id, middleware.UserID(c))
+	if err != nil {
+		respondPortfolioError(c, err, "Failed to retrieve realized gains")
+		return
+	}
+
+	response.OK(c, http.StatusOK, ledger.RealizedGains(from, to))
+}
+
+// Performance returns a portfolio's daily value series with time-weighted
+// and money-weighted returns over ?start to ?end and the standard periods
+func (h *PortfolioHandler) Performance(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	start, err := parseDateQuery(c, "start")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, err.Error())
+		return
+	}
+	end, err := parseDateQuery(c, "end")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, err.Error())
+		return
+	}
+	if start != nil && end != nil && end.Before(*start) {
+		response.InvalidField(c, "end", "must not be before start")
+		return
+	}
+
+	snapshots, err := h.portfolioRepo.Snapshots(id, middleware.UserID(c))
+	if err != nil {
+		respondPortfolioError(c, err, "Failed to compute performance")
+		return
+	}
+
+	performance := service.Performance(snapshots, start, end)
+	performance.PortfolioID = id
+	response.OK(c, http.StatusOK, performance)
+}
+
+// Risk returns a portfolio's volatility, beta against a benchmark, Sharpe
+// and Sortino ratios, maximum drawdown and value at risk, each over its own
+// lookback of trading days
+func (h *PortfolioHandler) Risk(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.RiskRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	req.Benchmark = strings.ToUpper(cmp.Or(strings.TrimSpace(req.Benchmark), service.DefaultRiskBenchmark))
+	service.RiskLookbacks(&req)
+
+	snapshots, err := h.portfolioRepo.Snapshots(id, middleware.UserID(c))
+	if err != nil {
+		respondPortfolioError(c, err, "Failed to compute risk")
+		return
+	}
+
+	// One extra bar gives the first return of the lookback
+	benchmark, err := h.stockRepo.QueryHistory(req.Benchmark, models.StockHistoryQuery{Limit: req.BetaLookback + 1})
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve benchmark history")
+		return
+	}
+
+	risk := service.PortfolioRisk(snapshots, benchmark, req)
+	risk.PortfolioID = id
+	response.OK(c, http.StatusOK, risk)
+}
+
+// Compare returns the portfolio's time-weighted cumulative returns next to
+// its benchmarks' with alpha, tracking error and information ratio against each
+func (h *PortfolioHandler) Compare(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CompareRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	snapshots, err := h.portfolioRepo.Snapshots(id, middleware.UserID(c))
+	if err != nil {
+		respondPortfolioError(c, err, "Failed to compute performance")
+		return
+	}
+
+	bench, ok := loadComparison(c, h.stockRepo, req, "")
+	if !ok {
+		return
+	}
+
+	subject := "portfolio:" + strconv.FormatInt(id, 10)
+	response.OK(c, http.StatusOK, service.Compare(subject, service.PortfolioIndex(snapshots), bench.benchmarks, bench.indexes, bench.start, bench.end, bench.riskFree))
+}
+
+// ListTransactions returns a page of a portfolio's transactions, newest first
+func (h *PortfolioHandler) ListTransactions(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := pageParams(c, defaultTransactionLimit, maxTransactionLimit)
+	if !ok {
+		return
+	}
+
+	transactions, total, err := h.portfolioRepo.ListTransactions(id, middleware.UserID(c), limit, offset)
+	if err != nil {
+		respondPortfolioError(c, err, "Failed to retrieve transactions")
+		return
+	}
+
+	response.OKWithMeta(c, http.StatusOK, transactions, &models.ResponseMeta{
+		Total:  &total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// CreateTransaction records a ledger entry and re-derives the portfolio's
+// positions and cash balance from it
+func (h *PortfolioHandler) CreateTransaction(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.TransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	transaction := models.Transaction{
+		PortfolioID:   id,
+		Symbol:        strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		Type:          req.Type,
+		Quantity:      req.Quantity,
+		Price:         req.Price,
+		Fees:          req.Fees,
+		TotalAmount:   req.Amount,
+		SplitRatio:    req.SplitRatio,
+		LotSelections: req.Lots,
+		Notes:         strings.TrimSpace(req.Notes),
+	}
+	if req.ExecutedAt != nil {
+		if req.ExecutedAt.After(time.Now()) {
+			response.InvalidField(c, "executed_at", "must not be in the future")
+			return
+		}
+		transaction.ExecutedAt = *req.ExecutedAt
+	}
+
+	err := h.portfolioRepo.RecordTransaction(middleware.UserID(c), &transaction)
+	var txErr *service.TransactionError
+	var ledgerErr *service.LedgerError
+	switch {
+	case errors.As(err, &txErr):
+		response.InvalidField(c, txErr.Field, txErr.Message)
+		return
+	case errors.As(err, &ledgerErr) && ledgerErr.TransactionID == transaction.ID:
+		switch {
+		case errors.Is(err, service.ErrInsufficientQuantity):
+			response.InvalidField(c, "quantity", "exceeds the shares held at executed_at")
+		case errors.Is(err, service.ErrUnknownLot):
+			response.InvalidField(c, "lots", "refers to a lot that is not open for "+transaction.Symbol)
+		default:
+			response.InvalidField(c, "type", ledgerErr.Err.Error())
+		}
+		return
+	case errors.Is(err, repository.ErrInvalidReference):
+		response.InvalidField(c, "symbol", "no stored stock for "+transaction.Symbol+"; fetch it first")
+		return
+	case err != nil:
+		respondPortfolioError(c, err, "Failed to record transaction")
+		return
+	}
+
+	response.OK(c, http.StatusCreated, transaction)
+}
+
+// DeleteTransaction removes a ledger entry and re-derives the portfolio
+func (h *PortfolioHandler) DeleteTransaction(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(c.Param("transaction_id"), 10, 64)
+	if err != nil || transactionID <= 0 {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Transaction not found")
+		return
+	}
+
+	if err := h.portfolioRepo.DeleteTransaction(id, middleware.UserID(c), transactionID); err != nil {
+		respondPortfolioError(c, err, "Failed to delete transaction")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondPortfolioError(c *gin.Context, err error, message string) {
+	var ledgerErr *service.LedgerError
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Portfolio or transaction not found")
+	case errors.Is(err, repository.ErrDuplicate):
+		response.Error(c, http.StatusConflict, response.CodeConflict, "A portfolio with this name already exists")
+	case errors.Is(err, repository.ErrPaperPortfolio):
+		response.Error(c, http.StatusConflict, response.CodeConflict, "Paper account transactions come from order fills and cannot be changed by hand")
+	case errors.As(err, &ledgerErr):
+		response.Error(c, http.StatusConflict, response.CodeConflict, "The ledger would become inconsistent at "+ledgerErr.Error())
+	default:
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, message)
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"fmt"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultScreenerLimit = 50
+	maxScreenerLimit     = 500
+
+	// indicatorLookback is enough history for the 200-day SMA plus warm-up
+	indicatorLookback = 250
+	// maxIndicatorCandidates caps how many fundamental matches indicator
+	// filters are evaluated over; histories load indicatorBatch at a time
+	maxIndicatorCandidates = 2000
+	indicatorBatch         = 100
+)
+
+type ScreenerHandler struct {
+	stockRepo repository.StockRepository
+}
+
+func NewScreenerHandler(repo repository.StockRepository) *ScreenerHandler {
+	return &ScreenerHandler{
+		stockRepo: repo,
+	}
+}
+
+// Screen returns the stocks that match the fundamental and indicator filters
+func (h *ScreenerHandler) Screen(c *gin.Context) {
+	var req models.StockScreenerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	if msg := validateScreenerRequest(&req); msg != "" {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, msg)
+		return
+	}
+
+	// Without indicator filters the database can page the results itself
+	if len(req.Indicators) == 0 {
+		stocks, total, err := h.stockRepo.Screen(&req)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to screen stocks")
+			return
+		}
+
+		response.OKWithMeta(c, http.StatusOK, stocks, &models.ResponseMeta{
+			Total:  &total,
+			Limit:  req.Limit,
+			Offset: req.Offset,
+		})
+		return
+	}
+
+	// Indicator filters need every fundamental match before paging
+	candidatesReq := req
+	candidatesReq.Limit = maxIndicatorCandidates
+	candidatesReq.Offset = 0
+	candidates, found, err := h.stockRepo.Screen(&candidatesReq)
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to screen stocks")
+		return
+	}
+	if found > maxIndicatorCandidates {
+		response.InvalidField(c, "indicators", fmt.Sprintf("can only be applied to at most %d stocks; narrow the other filters first", maxIndicatorCandidates))
+		return
+	}
+
+	matches := []models.Stock{}
+	for batch := range slices.Chunk(candidates, indicatorBatch) {
+		symbols := make([]string, len(batch))
+		for i, stock := range batch {
+			symbols[i] = stock.Symbol
+		}
+		histories, err := h.stockRepo.QueryHistories(symbols, models.StockHistoryQuery{Limit: indicatorLookback})
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to load stock history")
+			return
+		}
+
+		for _, stock := range batch {
+			history := histories[stock.Symbol]
+			if len(history) == 0 {
+				continue
+			}
+
+			latestClose := history[len(history)-1].Close
+			indicators := service.CalculateIndicators(stock.Symbol, history)
+			if service.MatchesIndicatorFilters(indicators, latestClose, req.Indicators) {
+				matches = append(matches, stock)
+			}
+		}
+	}
+
+	total := len(matches)
+	start := min(req.Offset, total)
+	end := min(start+req.Limit, total)
+
+	response.OKWithMeta(c, http.StatusOK, matches[start:end], &models.ResponseMeta{
+		Total:  &total,
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	})
+}
+
+// validateScreenerRequest applies defaults and returns an error message for
+// invalid requests
+func validateScreenerRequest(req *models.StockScreenerRequest) string {
+	if req.Limit <= 0 {
+		req.Limit = defaultScreenerLimit
+	}
+	if req.Limit > maxScreenerLimit {
+		req.Limit = maxScreenerLimit
+	}
+	if req.Offset < 0 {
+		return "offset must not be negative"
+	}
+
+	if req.SortBy != "" && !repository.IsValidScreenerSort(req.SortBy) {
+		return "Unsupported sort_by: " + req.SortBy
+	}
+	if req.SortOrder != "" && !strings.EqualFold(req.SortOrder, "asc") && !strings.EqualFold(req.SortOrder, "desc") {
+		return "sort_order must be \"asc\" or \"desc\""
+	}
+
+	for _, f := range req.Indicators {
+		if !service.IsValidIndicator(f.Indicator) {
+			return "Unsupported indicator: " + f.Indicator
+		}
+		switch f.Operator {
+		case "<", "<=", ">", ">=", "above", "below":
+		default:
+			return "Unsupported indicator operator: " + f.Operator
+		}
+		if f.CompareTo != "" && !service.IsValidIndicator(f.CompareTo) {
+			return "Unsupported indicator: " + f.CompareTo
+		}
+		if f.CompareTo == "" && f.Value == nil {
+			return "Indicator filter on " + f.Indicator + " needs a value or compare_to"
+		}
+	}
+
+	return ""
+}
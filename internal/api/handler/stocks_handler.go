@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
 	"go-flow/internal/repository"
 	"go-flow/internal/service"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,61 +17,182 @@ import (
 type StocksHandler struct {
 	stockRepo repository.StockRepository
 	avService *service.AlphaVantageService
+	prices    *service.PriceBus
 }
 
-func NewStocksHandler(repo repository.StockRepository, avService *service.AlphaVantageService) *StocksHandler {
+func NewStocksHandler(repo repository.StockRepository, avService *service.AlphaVantageService, prices *service.PriceBus) *StocksHandler {
 	return &StocksHandler{
 		stockRepo: repo,
 		avService: avService,
+		prices:    prices,
 	}
 }
 
-// GetStocks returns all stocks from the database
+const (
+	defaultStockListLimit = 50
+
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 5000
+)
+
+// GetStocks returns a filtered, sorted page of stocks from the database
 func (h *StocksHandler) GetStocks(c *gin.Context) {
-	stocks, err := h.stockRepo.GetAll()
+	var query models.StockListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		response.InvalidField(c, "min_price", "must not be greater than max_price")
+		return
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultStockListLimit
+	}
+
+	fields, err := repository.ParseStockFields(c.Query("fields"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve stocks"})
+		response.InvalidField(c, "fields", err.Error())
+		return
+	}
+	query.Fields = fields
+
+	page, err := h.stockRepo.GetAll(query)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			response.InvalidField(c, "cursor", "is malformed or does not match the sort order")
+			return
+		}
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve stocks")
+		return
+	}
+
+	meta := &models.ResponseMeta{
+		Total:      &page.Total,
+		Limit:      query.Limit,
+		NextCursor: page.NextCursor,
+	}
+
+	if len(fields) > 0 {
+		response.OKWithMeta(c, http.StatusOK, selectEach(page.Stocks, fields), meta)
 		return
 	}
 
-	c.JSON(http.StatusOK, stocks)
+	response.OKWithMeta(c, http.StatusOK, page.Stocks, meta)
 }
 
 // GetStockByID returns a specific stock by ID
 func (h *StocksHandler) GetStockByID(c *gin.Context) {
-	id := c.Param("id")
-	stock, err := h.stockRepo.GetByID(id)
+	req := models.StockRequest{Symbol: c.Param("id")}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	fields, err := repository.ParseStockFields(req.Fields)
+	if err != nil {
+		response.InvalidField(c, "fields", err.Error())
+		return
+	}
+
+	stock, err := h.stockRepo.GetByID(req.Symbol, fields)
+	if err != nil {
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Stock not found")
+		return
+	}
+
+	if len(fields) > 0 {
+		response.OK(c, http.StatusOK, models.SelectFields(stock, fields))
+		return
+	}
+
+	response.OK(c, http.StatusOK, stock)
+}
+
+// GetStockHistory returns daily history for a stock, optionally limited to a
+// date range and a subset of fields
+func (h *StocksHandler) GetStockHistory(c *gin.Context) {
+	fields, err := repository.ParseHistoryFields(c.Query("fields"))
+	if err != nil {
+		response.InvalidField(c, "fields", err.Error())
+		return
+	}
+
+	query := models.StockHistoryQuery{Fields: fields, Limit: defaultHistoryLimit}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+		query.Limit = min(limit, maxHistoryLimit)
+	}
+	if query.Start, err = parseDateQuery(c, "start"); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, err.Error())
+		return
+	}
+	if query.End, err = parseDateQuery(c, "end"); err != nil {
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	history, err := h.stockRepo.QueryHistory(c.Param("id"), query)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Stock not found"})
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve stock history")
 		return
 	}
 
-	c.JSON(http.StatusOK, stock)
+	if len(fields) > 0 {
+		response.OK(c, http.StatusOK, selectEach(history, fields))
+		return
+	}
+
+	response.OK(c, http.StatusOK, history)
+}
+
+// parseDateQuery reads an optional YYYY-MM-DD query parameter
+func parseDateQuery(c *gin.Context, param string) (*time.Time, error) {
+	raw := c.Query(param)
+	if raw == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date in YYYY-MM-DD format", param)
+	}
+	return &date, nil
+}
+
+// selectEach trims every item down to the requested JSON fields
+func selectEach[T any](items []T, fields []string) []map[string]any {
+	selected := make([]map[string]any, len(items))
+	for i := range items {
+		selected[i] = models.SelectFields(&items[i], fields)
+	}
+	return selected
 }
 
 // FetchStockData gets data from Alpha Vantage and stores it
 func (h *StocksHandler) FetchStockData(c *gin.Context) {
 	symbol := c.Param("symbol")
 	if symbol == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Symbol is required"})
+		response.Error(c, http.StatusBadRequest, response.CodeInvalidRequest, "Symbol is required")
 		return
 	}
 
 	// Get data from Alpha Vantage
-	stockData, err := h.avService.GetDailyStockData(symbol)
+	stockData, err := h.avService.GetDailyStockData(c.Request.Context(), symbol)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stock data: " + err.Error()})
+		response.Error(c, http.StatusBadGateway, response.CodeUpstreamError, "Failed to fetch stock data: "+err.Error())
 		return
 	}
 
 	// Store in database
-	if err := h.stockRepo.SaveStockData(stockData); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save stock data"})
+	if err := storeStockData(h.stockRepo, h.prices, stockData); err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to save stock data")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Successfully fetched and stored stock data",
-		"count":   len(stockData),
-	})
+	response.OKWithMessage(c, http.StatusOK, gin.H{"count": len(stockData)}, "Successfully fetched and stored stock data")
 }
@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"errors"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSparklineDays = 30
+	maxSparklineDays     = 365
+)
+
+type WatchlistHandler struct {
+	watchlistRepo repository.WatchlistRepository
+}
+
+func NewWatchlistHandler(repo repository.WatchlistRepository) *WatchlistHandler {
+	return &WatchlistHandler{
+		watchlistRepo: repo,
+	}
+}
+
+// ListWatchlists returns the current user's watchlists in display order
+func (h *WatchlistHandler) ListWatchlists(c *gin.Context) {
+	watchlists, err := h.watchlistRepo.List(middleware.UserID(c))
+	if err != nil {
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to retrieve watchlists")
+		return
+	}
+
+	response.OK(c, http.StatusOK, watchlists)
+}
+
+// CreateWatchlist creates a named watchlist, appended after the existing ones
+// unless a position is given
+func (h *WatchlistHandler) CreateWatchlist(c *gin.Context) {
+	var req models.WatchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	watchlist := models.Watchlist{
+		UserID:   middleware.UserID(c),
+		Name:     strings.TrimSpace(req.Name),
+		Position: -1,
+	}
+	if req.Description != nil {
+		watchlist.Description = *req.Description
+	}
+	if req.Position != nil {
+		watchlist.Position = *req.Position
+	}
+	if err := h.watchlistRepo.Create(&watchlist); err != nil {
+		respondWatchlistError(c, err, "Failed to create watchlist")
+		return
+	}
+
+	response.OK(c, http.StatusCreated, watchlist)
+}
+
+// GetWatchlist returns a watchlist with the latest price, day change and a
+// sparkline of recent closes for each symbol
+func (h *WatchlistHandler) GetWatchlist(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	days := defaultSparklineDays
+	if raw := c.Query("sparkline_days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 || parsed > maxSparklineDays {
+			response.InvalidField(c, "sparkline_days", "must be between 0 and "+strconv.Itoa(maxSparklineDays))
+			return
+		}
+		days = parsed
+	}
+
+	userID := middleware.UserID(c)
+	watchlist, err := h.watchlistRepo.Get(id, userID)
+	if err != nil {
+		respondWatchlistError(c, err, "Failed to retrieve watchlist")
+		return
+	}
+
+	items, err := h.watchlistRepo.GetView(id, userID, days)
+	if err != nil {
+		respondWatchlistError(c, err, "Failed to retrieve watchlist prices")
+		return
+	}
+
+	response.OK(c, http.StatusOK, models.WatchlistViewResponse{
+		Watchlist: *watchlist,
+		Items:     items,
+	})
+}
+
+// UpdateWatchlist renames, describes or moves a watchlist
+func (h *WatchlistHandler) UpdateWatchlist(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.WatchlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	watchlist, err := h.watchlistRepo.Get(id, middleware.UserID(c))
+	if err != nil {
+		respondWatchlistError(c, err, "Failed to retrieve watchlist")
+		return
+	}
+
+	watchlist.Name = strings.TrimSpace(req.Name)
+	if req.Description != nil {
+		watchlist.Description = *req.Description
+	}
+	if req.Position != nil {
+		watchlist.Position = *req.Position
+	}
+	if err := h.watchlistRepo.Update(watchlist); err != nil {
+		respondWatchlistError(c, err, "Failed to update watchlist")
+		return
+	}
+
+	updated, err := h.watchlistRepo.Get(id, watchlist.UserID)
+	if err != nil {
+		respondWatchlistError(c, err, "Failed to retrieve watchlist")
+		return
+	}
+
+	response.OK(c, http.StatusOK, updated)
+}
+
+// DeleteWatchlist removes a watchlist and all of its entries
+func (h *WatchlistHandler) DeleteWatchlist(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.watchlistRepo.Delete(id, middleware.UserID(c)); err != nil {
+		respondWatchlistError(c, err, "Failed to delete watchlist")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListItems returns a watchlist's entries without prices
+func (h *WatchlistHandler) ListItems(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	items, err := h.watchlistRepo.ListItems(id, middleware.UserID(c))
+	if err != nil {
+		respondWatchlistError(c, err, "Failed to retrieve watchlist items")
+		return
+	}
+
+	response.OK(c, http.StatusOK, models.StockWatchlistResponse{
+		Watchlist: items,
+		Count:     len(items),
+	})
+}
+
+// AddItem adds a stored stock to a watchlist
+func (h *WatchlistHandler) AddItem(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.WatchlistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	item := models.StockWatchlist{
+		UserID:      middleware.UserID(c),
+		WatchlistID: id,
+		Symbol:      strings.ToUpper(strings.TrimSpace(req.Symbol)),
+		Notes:       req.Notes,
+		Position:    -1,
+	}
+	if req.Position != nil {
+		item.Position = *req.Position
+	}
+
+	err := h.watchlistRepo.AddItem(&item)
+	switch {
+	case errors.Is(err, repository.ErrDuplicate):
+		response.Error(c, http.StatusConflict, response.CodeConflict, item.Symbol+" is already on this watchlist")
+		return
+	case errors.Is(err, repository.ErrInvalidReference):
+		response.InvalidField(c, "symbol", "no stored stock for "+item.Symbol+"; fetch it first")
+		return
+	case err != nil:
+		respondWatchlistError(c, err, "Failed to add symbol to watchlist")
+		return
+	}
+
+	response.OK(c, http.StatusCreated, item)
+}
+
+// UpdateItem changes the notes or position of a watchlist entry
+func (h *WatchlistHandler) UpdateItem(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.WatchlistItemUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	symbol := strings.ToUpper(c.Param("symbol"))
+	item, err := h.watchlistRepo.UpdateItem(id, middleware.UserID(c), symbol, req.Notes, req.Position)
+	if err != nil {
+		respondWatchlistError(c, err, "Failed to update watchlist item")
+		return
+	}
+
+	response.OK(c, http.StatusOK, item)
+}
+
+// RemoveItem removes a symbol from a watchlist
+func (h *WatchlistHandler) RemoveItem(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	symbol := strings.ToUpper(c.Param("symbol"))
+	if err := h.watchlistRepo.RemoveItem(id, middleware.UserID(c), symbol); err != nil {
+		respondWatchlistError(c, err, "Failed to remove symbol from watchlist")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ReorderItems moves the given symbols to the top of the watchlist in the
+// order listed
+func (h *WatchlistHandler) ReorderItems(c *gin.Context) {
+	id, ok := paramID(c)
+	if !ok {
+		return
+	}
+
+	var req models.WatchlistOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidationError(c, err)
+		return
+	}
+
+	userID := middleware.UserID(c)
+	if err := h.watchlistRepo.ReorderItems(id, userID, normalizeSymbols(req.Symbols)); err != nil {
+		respondWatchlistError(c, err, "Failed to reorder watchlist")
+		return
+	}
+
+	items, err := h.watchlistRepo.ListItems(id, userID)
+	if err != nil {
+		respondWatchlistError(c, err, "Failed to retrieve watchlist items")
+		return
+	}
+
+	response.OK(c, http.StatusOK, models.StockWatchlistResponse{
+		Watchlist: items,
+		Count:     len(items),
+	})
+}
+
+func respondWatchlistError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		response.Error(c, http.StatusNotFound, response.CodeNotFound, "Watchlist or entry not found")
+	case errors.Is(err, repository.ErrDuplicate):
+		response.Error(c, http.StatusConflict, response.CodeConflict, "A watchlist with this name already exists")
+	default:
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, message)
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	UserIDKey     = "user_id"
+	SessionIDKey  = "session_id"
+	AuthMethodKey = "auth_method"
+	ScopesKey     = "scopes"
+	RoleKey       = "role"
+)
+
+// Authentication methods stored under AuthMethodKey
+const (
+	AuthMethodSession = "session"
+	AuthMethodAPIKey  = "api_key"
+)
+
+// RequireAuth accepts either a bearer access token or an API key (as
+// "Bearer gf_..." or "ApiKey gf_..."), rejects revoked sessions and keys, and
+// stores the user ID, role, auth method and granted scopes in the request
+// context. The role is read on every request so changes apply immediately.
+func RequireAuth(tokens *service.TokenService, sessions repository.RefreshTokenRepository, keys repository.APIKeyRepository, users repository.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, credential, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		credential = strings.TrimSpace(credential)
+		if credential == "" || (!strings.EqualFold(scheme, "Bearer") && !strings.EqualFold(scheme, "ApiKey")) {
+			response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "A bearer token or API key is required")
+			return
+		}
+
+		if service.IsAPIKey(credential) {
+			if authenticateAPIKey(c, keys, credential) && loadRole(c, users) {
+				c.Next()
+			}
+			return
+		}
+		if !strings.EqualFold(scheme, "Bearer") {
+			response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "The API key is invalid, expired or revoked")
+			return
+		}
+
+		claims, err := tokens.ParseAccessToken(credential)
+		if err != nil {
+			response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "The access token is invalid or expired")
+			return
+		}
+
+		active, err := sessions.IsSessionActive(claims.SessionID)
+		if err != nil {
+			response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to verify session")
+			return
+		}
+		if !active {
+			response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "The session has been revoked")
+			return
+		}
+
+		c.Set(UserIDKey, claims.UserID())
+		c.Set(SessionIDKey, claims.SessionID)
+		c.Set(AuthMethodKey, AuthMethodSession)
+		c.Set(ScopesKey, models.AllScopes)
+		if loadRole(c, users) {
+			c.Next()
+		}
+	}
+}
+
+// OptionalAuth runs requireAuth only when the request carries credentials,
+// letting anonymous requests through to endpoints that serve both
+func OptionalAuth(requireAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		requireAuth(c)
+	}
+}
+
+func authenticateAPIKey(c *gin.Context, keys repository.APIKeyRepository, credential string) bool {
+	key, err := keys.Authenticate(service.HashAPIKey(credential))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "The API key is invalid, expired or revoked")
+			return false
+		}
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to verify API key")
+		return false
+	}
+
+	// Usage tracking must not fail the request
+	if err := keys.TouchLastUsed(key.ID); err != nil {
+		log.Printf("failed to record API key usage: %v", err)
+	}
+
+	c.Set(UserIDKey, key.UserID)
+	c.Set(AuthMethodKey, AuthMethodAPIKey)
+	c.Set(ScopesKey, key.Scopes)
+	return true
+}
+
+// loadRole stores the authenticated user's current role in the context
+func loadRole(c *gin.Context, users repository.UserRepository) bool {
+	user, err := users.GetByID(UserID(c))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			response.Error(c, http.StatusUnauthorized, response.CodeUnauthorized, "The account no longer exists")
+			return false
+		}
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "Failed to load account")
+		return false
+	}
+
+	c.Set(RoleKey, user.Role)
+	return true
+}
+
+// RequirePermission rejects users whose role does not grant permission
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(models.RolePermissions[Role(c)], permission) {
+			response.Error(c, http.StatusForbidden, response.CodeForbidden, "Your role does not allow this operation")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose credentials were not granted scope
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(Scopes(c), scope) {
+			response.Error(c, http.StatusForbidden, response.CodeForbidden, "The credentials are missing the "+scope+" scope")
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireSession only admits interactive logins, e.g. for managing API keys
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString(AuthMethodKey) != AuthMethodSession {
+			response.Error(c, http.StatusForbidden, response.CodeForbidden, "This endpoint requires an interactive login")
+			return
+		}
+		c.Next()
+	}
+}
+
+// UserID returns the authenticated user's ID, or zero outside RequireAuth
+func UserID(c *gin.Context) int64 {
+	return c.GetInt64(UserIDKey)
+}
+
+// SessionID returns the authenticated session ID, or "" for API keys
+func SessionID(c *gin.Context) string {
+	return c.GetString(SessionIDKey)
+}
+
+// Role returns the authenticated user's role
+func Role(c *gin.Context) string {
+	return c.GetString(RoleKey)
+}
+
+// Scopes returns the scopes granted to the request's credentials
+func Scopes(c *gin.Context) []string {
+	return c.GetStringSlice(ScopesKey)
+}
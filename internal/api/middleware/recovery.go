@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+
+	"go-flow/internal/api/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns panics into an internal_error problem response
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, err any) {
+		log.Printf("panic serving %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		response.Error(c, http.StatusInternalServerError, response.CodeInternalError, "An unexpected error occurred")
+	})
+}
@@ -0,0 +1,115 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"go-flow/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// Stable error codes returned in the "code" member of problem details
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeRouteNotFound      = "route_not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeUpstreamError      = "upstream_error"
+	CodeInternalError      = "internal_error"
+)
+
+// problemTypeBase is where each error code is documented
+const problemTypeBase = "https://github.com/bashlui/go-flow/blob/main/docs/errors.md#"
+
+// requestIDKey matches the key set by the request ID middleware
+const requestIDKey = "request_id"
+
+// OK writes a successful response wrapped in the standard envelope
+func OK(c *gin.Context, status int, data any) {
+	c.JSON(status, envelope(c, data, "", nil))
+}
+
+// OKWithMessage writes a successful response with a human readable message
+func OKWithMessage(c *gin.Context, status int, data any, message string) {
+	c.JSON(status, envelope(c, data, message, nil))
+}
+
+// OKWithMeta writes a successful list response with paging information
+func OKWithMeta(c *gin.Context, status int, data any, meta *models.ResponseMeta) {
+	c.JSON(status, envelope(c, data, "", meta))
+}
+
+// Error writes an RFC 7807 problem details response and aborts the request
+func Error(c *gin.Context, status int, code string, detail string) {
+	writeProblem(c, problem(c, status, code, detail))
+}
+
+// ValidationError reports request binding failures, listing each invalid
+// field when the validator provides them
+func ValidationError(c *gin.Context, err error) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		Error(c, http.StatusBadRequest, CodeInvalidRequest, err.Error())
+		return
+	}
+
+	p := problem(c, http.StatusUnprocessableEntity, CodeValidationFailed, "One or more fields are invalid")
+	for _, fe := range validationErrors {
+		p.Errors = append(p.Errors, models.FieldError{
+			Field:   fieldName(fe.Namespace()),
+			Message: "failed on the '" + fe.Tag() + "' rule",
+		})
+	}
+	writeProblem(c, p)
+}
+
+// InvalidField reports a validation failure on a single field
+func InvalidField(c *gin.Context, field string, message string) {
+	p := problem(c, http.StatusUnprocessableEntity, CodeValidationFailed, "One or more fields are invalid")
+	p.Errors = []models.FieldError{{Field: field, Message: message}}
+	writeProblem(c, p)
+}
+
+func envelope(c *gin.Context, data any, message string, meta *models.ResponseMeta) models.APIResponse[any] {
+	return models.APIResponse[any]{
+		Data:      data,
+		Success:   true,
+		Message:   message,
+		Meta:      meta,
+		RequestID: c.GetString(requestIDKey),
+	}
+}
+
+func problem(c *gin.Context, status int, code string, detail string) models.ProblemDetails {
+	return models.ProblemDetails{
+		Type:      problemTypeBase + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: c.GetString(requestIDKey),
+	}
+}
+
+func writeProblem(c *gin.Context, p models.ProblemDetails) {
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// fieldName drops the struct name from a validator namespace such as
+// "StockListRequest.Symbols"
+func fieldName(namespace string) string {
+	if _, field, ok := strings.Cut(namespace, "."); ok {
+		return field
+	}
+	return namespace
+}
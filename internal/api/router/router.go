@@ -1,19 +1,216 @@
 package router
 
 import (
+	"net/http"
+	"reflect"
+	"strings"
+
 	"go-flow/internal/api/handler"
+	"go-flow/internal/api/middleware"
+	"go-flow/internal/api/response"
+	"go-flow/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 )
 
-func SetupRoutes(router *gin.Engine, stocksHandler *handler.StocksHandler) {
+// Handlers groups the HTTP handlers served by the API
+type Handlers struct {
+	Stocks        *handler.StocksHandler
+	Screener      *handler.ScreenerHandler
+	Batch         *handler.BatchHandler
+	Auth          *handler.AuthHandler
+	APIKeys       *handler.APIKeyHandler
+	Admin         *handler.AdminHandler
+	Watchlists    *handler.WatchlistHandler
+	Alerts        *handler.AlertHandler
+	Notifications *handler.NotificationHandler
+	Portfolios    *handler.PortfolioHandler
+	Paper         *handler.PaperHandler
+	Imports       *handler.ImportHandler
+	Exports       *handler.ExportHandler
+	Charts        *handler.ChartHandler
+	GraphQL       *handler.GraphQLHandler
+	Analytics     *handler.AnalyticsHandler
+}
+
+// SetupRoutes registers every route; requireAuth guards the user-scoped ones
+func SetupRoutes(router *gin.Engine, h Handlers, requireAuth gin.HandlerFunc) {
+	// Request models declare their rules with `validate` tags
+	// and validation errors report JSON field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.SetTagName("validate")
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				return field.Name
+			}
+			return name
+		})
+	}
+
+	router.Use(middleware.RequestID(), middleware.Recovery())
+
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		response.Error(c, http.StatusNotFound, response.CodeRouteNotFound, "No route matches "+c.Request.URL.Path)
+	})
+	router.NoMethod(func(c *gin.Context) {
+		response.Error(c, http.StatusMethodNotAllowed, response.CodeMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path)
+	})
+
+	fetchScope := middleware.RequireScope(models.ScopeWriteFetch)
+	readScope := middleware.RequireScope(models.ScopeReadStocks)
+
 	api := router.Group("/api")
 	{
 		stocks := api.Group("/stocks")
 		{
-			stocks.GET("", stocksHandler.GetStocks)
-			stocks.GET("/:id", stocksHandler.GetStockByID)
-			stocks.POST("/fetch/:symbol", stocksHandler.FetchStockData)
+			stocks.GET("", h.Stocks.GetStocks)
+			stocks.GET("/:id", h.Stocks.GetStockByID)
+			stocks.GET("/:id/history", h.Stocks.GetStockHistory)
+			stocks.GET("/:id/history/export", h.Exports.ExportHistory)
+			stocks.GET("/:id/indicators/export", h.Exports.ExportIndicators)
+			stocks.GET("/:id/chart.png", h.Charts.ChartPNG)
+			stocks.GET("/:id/chart.svg", h.Charts.ChartSVG)
+			stocks.GET("/batch/jobs/:id", h.Batch.GetJob)
+
+			// Provider calls spend the shared quota, so only roles allowed
+			// to fetch may trigger them
+			provider := stocks.Group("", requireAuth, middleware.RequirePermission(models.PermFetchStocks))
+			{
+				provider.POST("/fetch/:symbol", fetchScope, h.Stocks.FetchStockData)
+				provider.POST("/batch/fetch", fetchScope, h.Batch.BatchFetch)
+				provider.POST("/batch/quotes", readScope, h.Batch.BatchQuotes)
+			}
+		}
+
+		api.POST("/screener", h.Screener.Screen)
+
+		analytics := api.Group("/analytics")
+		{
+			analytics.GET("/compare", h.Analytics.Compare)
+			analytics.POST("/correlation", h.Analytics.Correlation)
+			analytics.GET("/backtest/strategies", h.Analytics.BacktestStrategies)
+			analytics.POST("/backtest", h.Analytics.Backtest)
+		}
+
+		// GraphQL serves stocks to anyone and the user's own data to
+		// authenticated requests; subscriptions upgrade a GET to WebSocket
+		graphql := api.Group("/graphql", middleware.OptionalAuth(requireAuth))
+		{
+			graphql.GET("", h.GraphQL.Serve)
+			graphql.POST("", h.GraphQL.Serve)
+			graphql.OPTIONS("", h.GraphQL.Serve)
+		}
+
+		auth := api.Group("/auth")
+		{
+			auth.POST("/register", h.Auth.Register)
+			auth.POST("/login", h.Auth.Login)
+			auth.POST("/refresh", h.Auth.Refresh)
+		}
+
+		// Routes below act on behalf of the authenticated user
+		user := api.Group("", requireAuth)
+		{
+			user.GET("/auth/me", h.Auth.Me)
+			// Signing out revokes refresh tokens, which an API key must not do
+			user.POST("/auth/logout", middleware.RequireSession(), h.Auth.Logout)
+			user.POST("/auth/logout-all", middleware.RequireSession(), h.Auth.LogoutAll)
+
+			// API keys can only be managed from an interactive login
+			keys := user.Group("/keys", middleware.RequireSession())
+			{
+				keys.GET("", h.APIKeys.ListKeys)
+				keys.POST("", h.APIKeys.CreateKey)
+				keys.GET("/:id", h.APIKeys.GetKey)
+				keys.PATCH("/:id", h.APIKeys.UpdateKey)
+				keys.DELETE("/:id", h.APIKeys.DeleteKey)
+			}
+
+			watchlists := user.Group("/watchlists", middleware.RequireScope(models.ScopePortfolio))
+			{
+				watchlists.GET("", h.Watchlists.ListWatchlists)
+				watchlists.POST("", h.Watchlists.CreateWatchlist)
+				watchlists.GET("/:id", h.Watchlists.GetWatchlist)
+				watchlists.PATCH("/:id", h.Watchlists.UpdateWatchlist)
+				watchlists.DELETE("/:id", h.Watchlists.DeleteWatchlist)
+				watchlists.GET("/:id/items", h.Watchlists.ListItems)
+				watchlists.POST("/:id/items", h.Watchlists.AddItem)
+				watchlists.PUT("/:id/items/order", h.Watchlists.ReorderItems)
+				watchlists.PATCH("/:id/items/:symbol", h.Watchlists.UpdateItem)
+				watchlists.DELETE("/:id/items/:symbol", h.Watchlists.RemoveItem)
+			}
+
+			alerts := user.Group("/alerts", middleware.RequireScope(models.ScopePortfolio))
+			{
+				alerts.GET("", h.Alerts.ListAlerts)
+				alerts.POST("", h.Alerts.CreateAlert)
+				alerts.POST("/backtest", h.Alerts.Backtest)
+				alerts.GET("/:id", h.Alerts.GetAlert)
+				alerts.PATCH("/:id", h.Alerts.UpdateAlert)
+				alerts.DELETE("/:id", h.Alerts.DeleteAlert)
+			}
+
+			notifications := user.Group("/notifications", middleware.RequireScope(models.ScopePortfolio))
+			{
+				notifications.GET("", h.Notifications.ListNotifications)
+				notifications.GET("/unread-count", h.Notifications.UnreadCount)
+				notifications.POST("/read-all", h.Notifications.MarkAllRead)
+				notifications.GET("/preferences", h.Notifications.GetPreferences)
+				notifications.PUT("/preferences", h.Notifications.UpdatePreferences)
+				notifications.PATCH("/:id", h.Notifications.UpdateNotification)
+				notifications.DELETE("/:id", h.Notifications.DeleteNotification)
+			}
+
+			portfolios := user.Group("/portfolios", middleware.RequireScope(models.ScopePortfolio))
+			{
+				portfolios.GET("", h.Portfolios.ListPortfolios)
+				portfolios.POST("", h.Portfolios.CreatePortfolio)
+				portfolios.GET("/:id", h.Portfolios.GetPortfolio)
+				portfolios.PATCH("/:id", h.Portfolios.UpdatePortfolio)
+				portfolios.DELETE("/:id", h.Portfolios.DeletePortfolio)
+				portfolios.GET("/:id/lots", h.Portfolios.ListLots)
+				portfolios.GET("/:id/realized-gains", h.Portfolios.RealizedGains)
+				portfolios.GET("/:id/performance", h.Portfolios.Performance)
+				portfolios.GET("/:id/risk", h.Portfolios.Risk)
+				portfolios.GET("/:id/compare", h.Portfolios.Compare)
+				portfolios.GET("/:id/transactions", h.Portfolios.ListTransactions)
+				portfolios.GET("/:id/transactions/export", h.Exports.ExportTransactions)
+				portfolios.GET("/:id/valuations/export", h.Exports.ExportValuations)
+				portfolios.POST("/:id/transactions", h.Portfolios.CreateTransaction)
+				portfolios.DELETE("/:id/transactions/:transaction_id", h.Portfolios.DeleteTransaction)
+				portfolios.GET("/:id/imports", h.Imports.ListImports)
+				portfolios.POST("/:id/imports", h.Imports.CreateImport)
+				portfolios.GET("/:id/imports/:import_id", h.Imports.GetImport)
+				portfolios.POST("/:id/imports/:import_id/rollback", h.Imports.RollbackImport)
+			}
+
+			user.GET("/imports/layouts", middleware.RequireScope(models.ScopePortfolio), h.Imports.ListLayouts)
+
+			paper := user.Group("/paper", middleware.RequireScope(models.ScopePortfolio))
+			{
+				paper.GET("/accounts", h.Paper.ListAccounts)
+				paper.POST("/accounts", h.Paper.CreateAccount)
+				paper.GET("/accounts/:id/orders", h.Paper.ListOrders)
+				paper.POST("/accounts/:id/orders", h.Paper.CreateOrder)
+				paper.GET("/accounts/:id/orders/:order_id", h.Paper.GetOrder)
+				paper.DELETE("/accounts/:id/orders/:order_id", h.Paper.CancelOrder)
+			}
+
+			// Administration needs an interactive login, so no API key,
+			// whatever its scopes, can act with an admin's role
+			admin := user.Group("/admin", middleware.RequireSession(), middleware.RequirePermission(models.PermAdmin))
+			{
+				admin.GET("/users", h.Admin.ListUsers)
+				admin.GET("/users/:id", h.Admin.GetUser)
+				admin.PATCH("/users/:id", h.Admin.UpdateUser)
+				admin.DELETE("/users/:id", h.Admin.DeleteUser)
+				admin.GET("/quota", h.Admin.GetQuota)
+				admin.POST("/refresh", h.Admin.Refresh)
+			}
 		}
 	}
 }
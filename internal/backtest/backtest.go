@@ -0,0 +1,264 @@
+// Package backtest simulates trading strategies over daily stock history.
+//
+// A strategy sees bars one at a time through a Context and places orders on
+// it. Orders are filled at the next bar's open, so a strategy can never trade
+// on a price it has not seen yet. Positions are long only.
+package backtest
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// ErrNoBars is returned when there is no history to trade over
+var ErrNoBars = errors.New("no bars in the backtest range")
+
+// Strategy decides what to trade after each bar closes
+type Strategy interface {
+	// OnBar is called once per bar, oldest first, after the bar has closed
+	OnBar(ctx *Context)
+}
+
+// Config sets the account and cost model of a backtest
+type Config struct {
+	InitialCapital    float64
+	SlippageBps       float64 // basis points added to buys and taken off sells
+	Commission        float64 // charged on every fill
+	CommissionPercent float64 // percent of the traded value
+
+	// Start is the first bar that trades; earlier bars are only visible to
+	// the strategy as history. End is the last bar. Either may be zero.
+	Start, End time.Time
+}
+
+// order is a pending order, filled at the next bar's open: either a signed
+// quantity or a target fraction of equity to hold
+type order struct {
+	quantity float64
+	target   *float64
+}
+
+// Context is a strategy's view of the market and the account at the close of
+// the current bar
+type Context struct {
+	bars     []models.StockHistoryEntry
+	closes   []float64
+	cash     float64
+	position float64
+	orders   []order
+}
+
+// Bar returns the bar that just closed
+func (c *Context) Bar() models.StockHistoryEntry {
+	return c.bars[len(c.bars)-1]
+}
+
+// Bars returns every bar up to and including the current one, oldest first
+func (c *Context) Bars() []models.StockHistoryEntry {
+	return c.bars
+}
+
+// Closes returns the closes of Bars; strategies must not modify it
+func (c *Context) Closes() []float64 {
+	return c.closes
+}
+
+// Position returns the shares held
+func (c *Context) Position() float64 {
+	return c.position
+}
+
+// Cash returns the cash balance
+func (c *Context) Cash() float64 {
+	return c.cash
+}
+
+// Equity returns cash plus the position at the current close
+func (c *Context) Equity() float64 {
+	return c.cash + c.position*c.Bar().Close
+}
+
+// Buy orders shares at the next open, limited to what the cash affords
+func (c *Context) Buy(quantity float64) {
+	if quantity > 0 {
+		c.orders = append(c.orders, order{quantity: quantity})
+	}
+}
+
+// Sell orders shares sold at the next open, limited to the position
+func (c *Context) Sell(quantity float64) {
+	if quantity > 0 {
+		c.orders = append(c.orders, order{quantity: -quantity})
+	}
+}
+
+// TargetPercent orders whatever trade brings the position to percent of
+// equity at the next open; 0 closes the position
+func (c *Context) TargetPercent(percent float64) {
+	fraction := math.Max(0, percent) / 100
+	c.orders = append(c.orders, order{target: &fraction})
+}
+
+// account tracks fills and round trips during a run
+type account struct {
+	cfg         Config
+	cash        float64
+	position    float64
+	commissions float64
+	trades      []models.BacktestTrade
+	open        *models.BacktestTrade
+	openCost    float64 // cost of the shares still held in the open trade
+	sold        float64 // shares sold so far in the open trade
+	soldValue   float64
+}
+
+// Run feeds bars, ordered oldest first, to the strategy and simulates its
+// orders. Only bars between cfg.Start and cfg.End trade and appear in the
+// equity curve.
+func Run(strategy Strategy, bars []models.StockHistoryEntry, cfg Config) (*models.BacktestResult, error) {
+	first, last := -1, -1
+	for i, bar := range bars {
+		if (!cfg.Start.IsZero() && bar.Date.Before(cfg.Start)) || (!cfg.End.IsZero() && bar.Date.After(cfg.End)) {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+	}
+	if first < 0 {
+		return nil, ErrNoBars
+	}
+
+	acct := &account{cfg: cfg, cash: cfg.InitialCapital}
+	result := &models.BacktestResult{
+		Start:  &bars[first].Date,
+		End:    &bars[last].Date,
+		Trades: []models.BacktestTrade{},
+		Equity: make([]models.EquityPoint, 0, last-first+1),
+	}
+
+	closes := make([]float64, len(bars))
+	for i, bar := range bars {
+		closes[i] = bar.Close
+	}
+
+	var pending []order
+	heldBars := 0
+	for i := first; i <= last; i++ {
+		bar := bars[i]
+		for _, o := range pending {
+			acct.fill(o, bar)
+		}
+		if acct.open != nil {
+			acct.open.BarsHeld++
+		}
+
+		equity := acct.cash + acct.position*bar.Close
+		result.Equity = append(result.Equity, models.EquityPoint{
+			Date:     bar.Date,
+			Equity:   equity,
+			Cash:     acct.cash,
+			Position: acct.position,
+		})
+		if acct.position > 0 {
+			heldBars++
+		}
+
+		ctx := &Context{bars: bars[: i+1 : i+1], closes: closes[: i+1 : i+1], cash: acct.cash, position: acct.position}
+		strategy.OnBar(ctx)
+		pending = ctx.orders
+	}
+
+	// A position still held is reported as an open trade at the last close
+	if acct.open != nil {
+		trade := *acct.open
+		trade.ProfitLoss += acct.position*bars[last].Close - acct.openCost
+		trade.ReturnPercent = percent(trade.ProfitLoss, trade.EntryPrice*trade.Quantity)
+		trade.Open = true
+		acct.trades = append(acct.trades, trade)
+	}
+	result.Trades = append(result.Trades, acct.trades...)
+	result.Metrics = metrics(result, cfg.InitialCapital, heldBars, acct.commissions)
+
+	return result, nil
+}
+
+// fill executes an order at the bar's open with slippage and commission
+func (a *account) fill(o order, bar models.StockHistoryEntry) {
+	open := bar.Open
+	if open <= 0 {
+		open = bar.Close
+	}
+	if open <= 0 {
+		return
+	}
+	buyPrice := open * (1 + a.cfg.SlippageBps/10000)
+	sellPrice := open * (1 - a.cfg.SlippageBps/10000)
+	rate := a.cfg.CommissionPercent / 100
+
+	quantity := o.quantity
+	if o.target != nil {
+		equity := a.cash + a.position*open
+		quantity = math.Floor(equity**o.target/open) - a.position
+	}
+
+	switch {
+	case quantity > 0:
+		// Whole shares the cash affords after commission
+		affordable := math.Floor((a.cash - a.cfg.Commission) / (buyPrice * (1 + rate)))
+		quantity = math.Min(math.Floor(quantity), affordable)
+		if quantity <= 0 {
+			return
+		}
+		commission := a.cfg.Commission + quantity*buyPrice*rate
+		a.cash -= quantity*buyPrice + commission
+		a.position += quantity
+		a.commissions += commission
+
+		if a.open == nil {
+			a.open = &models.BacktestTrade{EntryDate: bar.Date}
+			a.openCost, a.sold, a.soldValue = 0, 0, 0
+		}
+		trade := a.open
+		trade.EntryPrice = (trade.EntryPrice*trade.Quantity + quantity*buyPrice) / (trade.Quantity + quantity)
+		trade.Quantity += quantity
+		trade.Commission += commission
+		trade.ProfitLoss -= commission
+		a.openCost += quantity * buyPrice
+
+	case quantity < 0 && a.position > 0:
+		quantity = math.Min(-quantity, a.position)
+		commission := a.cfg.Commission + quantity*sellPrice*rate
+		cost := a.openCost * quantity / a.position
+		a.cash += quantity*sellPrice - commission
+		a.position -= quantity
+		a.openCost -= cost
+		a.commissions += commission
+
+		trade := a.open
+		trade.ProfitLoss += quantity*sellPrice - cost - commission
+		trade.Commission += commission
+		a.sold += quantity
+		a.soldValue += quantity * sellPrice
+
+		if a.position <= 0 {
+			exitDate, exitPrice := bar.Date, a.soldValue/a.sold
+			trade.ExitDate, trade.ExitPrice = &exitDate, &exitPrice
+			trade.ReturnPercent = percent(trade.ProfitLoss, trade.EntryPrice*trade.Quantity)
+			a.trades = append(a.trades, *trade)
+			a.open = nil
+			a.position, a.openCost = 0, 0
+		}
+	}
+}
+
+func percent(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole * 100
+}
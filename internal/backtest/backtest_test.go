@@ -0,0 +1,182 @@
+package backtest
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// script is a strategy that runs the step registered for each bar index
+type script map[int]func(ctx *Context)
+
+func (s script) OnBar(ctx *Context) {
+	if step, ok := s[len(ctx.Bars())-1]; ok {
+		step(ctx)
+	}
+}
+
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+func dayRef(d int) *time.Time {
+	t := day(d)
+	return &t
+}
+
+// testBars returns n bars that open at 10, 20, 30... and close 5 above the open
+func testBars(n int) []models.StockHistoryEntry {
+	bars := make([]models.StockHistoryEntry, n)
+	for i := range bars {
+		open := float64(10 * (i + 1))
+		bars[i] = models.StockHistoryEntry{Date: day(i + 1), Open: open, Close: open + 5}
+	}
+	return bars
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestRunFillTiming(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy script
+		cfg      Config
+		entry    time.Time // zero when nothing is bought
+		price    float64
+		quantity float64
+		exit     *time.Time
+		exitAt   float64
+		open     bool
+	}{
+		{
+			name:     "buy fills at the next open",
+			strategy: script{0: func(c *Context) { c.Buy(10) }},
+			entry:    day(2), price: 20, quantity: 10, open: true,
+		},
+		{
+			name: "round trip",
+			strategy: script{
+				0: func(c *Context) { c.Buy(10) },
+				2: func(c *Context) { c.Sell(10) },
+			},
+			entry: day(2), price: 20, quantity: 10, exit: dayRef(4), exitAt: 40,
+		},
+		{
+			name:     "order on the last bar never fills",
+			strategy: script{4: func(c *Context) { c.Buy(10) }},
+		},
+		{
+			name:     "slippage moves the fill price",
+			strategy: script{0: func(c *Context) { c.Buy(10) }},
+			cfg:      Config{SlippageBps: 100},
+			entry:    day(2), price: 20.2, quantity: 10, open: true,
+		},
+		{
+			name:     "target percent sizes at the next open",
+			strategy: script{0: func(c *Context) { c.TargetPercent(50) }},
+			entry:    day(2), price: 20, quantity: 25, open: true,
+		},
+		{
+			name:     "buys are limited to the cash",
+			strategy: script{0: func(c *Context) { c.Buy(1000) }},
+			cfg:      Config{Commission: 10},
+			entry:    day(2), price: 20, quantity: 49, open: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.InitialCapital = 1000
+			result, err := Run(tt.strategy, testBars(5), tt.cfg)
+			if err != nil {
+				t.Fatalf("run: %v", err)
+			}
+
+			if tt.entry.IsZero() {
+				if len(result.Trades) != 0 {
+					t.Fatalf("trades = %+v, want none", result.Trades)
+				}
+				if final := result.Equity[len(result.Equity)-1]; !approx(final.Equity, 1000) {
+					t.Errorf("final equity = %v, want 1000", final.Equity)
+				}
+				return
+			}
+			if len(result.Trades) != 1 {
+				t.Fatalf("trades = %+v, want one", result.Trades)
+			}
+			trade := result.Trades[0]
+			if !trade.EntryDate.Equal(tt.entry) || !approx(trade.EntryPrice, tt.price) || !approx(trade.Quantity, tt.quantity) {
+				t.Errorf("entry = %v %v x %v, want %v %v x %v", trade.EntryDate.Format(time.DateOnly), trade.EntryPrice, trade.Quantity,
+					tt.entry.Format(time.DateOnly), tt.price, tt.quantity)
+			}
+			if trade.Open != tt.open {
+				t.Errorf("open = %v, want %v", trade.Open, tt.open)
+			}
+			if tt.exit != nil {
+				if trade.ExitDate == nil || !trade.ExitDate.Equal(*tt.exit) || !approx(*trade.ExitPrice, tt.exitAt) {
+					t.Errorf("exit = %v at %v, want %v at %v", trade.ExitDate, trade.ExitPrice, tt.exit, tt.exitAt)
+				}
+			}
+
+			// Equity is marked at the close of the bar the order filled on
+			for _, point := range result.Equity {
+				if point.Date.Before(tt.entry) && point.Position != 0 {
+					t.Errorf("position %v on %s, before the fill", point.Position, point.Date.Format(time.DateOnly))
+				}
+			}
+		})
+	}
+}
+
+func TestRunStartKeepsEarlierBarsAsHistory(t *testing.T) {
+	var seen []int
+	strategy := script{}
+	for i := range 5 {
+		strategy[i] = func(c *Context) { seen = append(seen, len(c.Bars())) }
+	}
+	strategy[2] = func(c *Context) {
+		seen = append(seen, len(c.Bars()))
+		c.Buy(1)
+	}
+
+	result, err := Run(strategy, testBars(5), Config{InitialCapital: 1000, Start: day(3), End: day(4)})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(seen) != 2 || seen[0] != 3 || seen[1] != 4 {
+		t.Errorf("bars seen per call = %v, want [3 4]", seen)
+	}
+	if len(result.Equity) != 2 || !result.Equity[0].Date.Equal(day(3)) {
+		t.Errorf("equity = %+v, want days 3 and 4", result.Equity)
+	}
+	if len(result.Trades) != 1 || !result.Trades[0].EntryDate.Equal(day(4)) || !approx(result.Trades[0].EntryPrice, 40) {
+		t.Errorf("trades = %+v, want a buy at day 4's open", result.Trades)
+	}
+
+	if _, err := Run(strategy, testBars(5), Config{Start: day(9)}); !errors.Is(err, ErrNoBars) {
+		t.Errorf("range after the bars: error = %v, want %v", err, ErrNoBars)
+	}
+}
+
+func TestRunStrategyCannotSeeAhead(t *testing.T) {
+	bars := testBars(3)
+	strategy := script{0: func(c *Context) {
+		// Appending must not overwrite the bar that comes next
+		_ = append(c.Bars(), models.StockHistoryEntry{Date: day(2), Open: 1, Close: 1})
+		_ = append(c.Closes(), 1)
+		c.Buy(1)
+	}}
+
+	result, err := Run(strategy, bars, Config{InitialCapital: 1000})
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if bars[1].Open != 20 || len(result.Trades) != 1 || !approx(result.Trades[0].EntryPrice, 20) {
+		t.Errorf("next bar = %+v, trades = %+v; want the buy to fill at 20", bars[1], result.Trades)
+	}
+}
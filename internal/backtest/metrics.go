@@ -0,0 +1,78 @@
+package backtest
+
+import (
+	"math"
+
+	"go-flow/internal/models"
+	"go-flow/internal/service"
+)
+
+// metrics summarizes an equity curve and its closed trades
+func metrics(result *models.BacktestResult, initialCapital float64, heldBars int, commissions float64) models.BacktestMetrics {
+	m := models.BacktestMetrics{
+		InitialCapital: initialCapital,
+		FinalEquity:    initialCapital,
+		Commissions:    commissions,
+	}
+	equity := result.Equity
+	if len(equity) == 0 {
+		return m
+	}
+
+	m.FinalEquity = equity[len(equity)-1].Equity
+	m.TotalReturn = percent(m.FinalEquity-initialCapital, initialCapital)
+	m.Exposure = percent(float64(heldBars), float64(len(equity)))
+
+	if years := equity[len(equity)-1].Date.Sub(equity[0].Date).Hours() / 24 / 365.25; years > 0 && initialCapital > 0 && m.FinalEquity > 0 {
+		cagr := (math.Pow(m.FinalEquity/initialCapital, 1/years) - 1) * 100
+		m.CAGR = &cagr
+	}
+
+	// Sharpe of daily equity returns, with the first bar measured from the
+	// initial capital
+	returns := make([]float64, 0, len(equity))
+	previous := initialCapital
+	for _, point := range equity {
+		if previous > 0 {
+			returns = append(returns, point.Equity/previous-1)
+		}
+		previous = point.Equity
+	}
+	if sd := service.Stdev(returns); sd > 0 {
+		sharpe := service.Mean(returns) / sd * math.Sqrt(service.TradingDaysPerYear)
+		m.Sharpe = &sharpe
+	}
+
+	peak := initialCapital
+	peakIndex := -1
+	for i, point := range equity {
+		if point.Equity > peak {
+			peak, peakIndex = point.Equity, i
+		}
+		if drawdown := percent(peak-point.Equity, peak); drawdown > m.MaxDrawdown {
+			m.MaxDrawdown = drawdown
+			m.DrawdownTrough = &equity[i].Date
+			m.DrawdownPeak = nil
+			if peakIndex >= 0 {
+				m.DrawdownPeak = &equity[peakIndex].Date
+			}
+		}
+	}
+
+	wins := 0
+	for _, trade := range result.Trades {
+		if trade.Open {
+			continue
+		}
+		m.Trades++
+		if trade.ProfitLoss > 0 {
+			wins++
+		}
+	}
+	if m.Trades > 0 {
+		winRate := percent(float64(wins), float64(m.Trades))
+		m.WinRate = &winRate
+	}
+
+	return m
+}
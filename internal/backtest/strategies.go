@@ -0,0 +1,173 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"go-flow/internal/models"
+	"go-flow/internal/service"
+)
+
+// ParamError reports an invalid strategy parameter
+type ParamError struct {
+	Name    string
+	Message string
+}
+
+func (e *ParamError) Error() string {
+	return e.Name + ": " + e.Message
+}
+
+// ErrUnknownStrategy is returned by New for a name that is not registered
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// definition is a registered strategy with its parameters and constructor
+type definition struct {
+	info  models.BacktestStrategyInfo
+	build func(params map[string]float64) (Strategy, error)
+}
+
+var registry = map[string]definition{}
+
+// register adds a strategy that can be run by name
+func register(info models.BacktestStrategyInfo, build func(params map[string]float64) (Strategy, error)) {
+	registry[info.Name] = definition{info: info, build: build}
+}
+
+// Strategies describes every registered strategy, ordered by name
+func Strategies() []models.BacktestStrategyInfo {
+	infos := make([]models.BacktestStrategyInfo, 0, len(registry))
+	for _, def := range registry {
+		infos = append(infos, def.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// New builds a registered strategy. Missing parameters take their defaults;
+// the resolved parameters are returned alongside the strategy.
+func New(name string, params map[string]float64) (Strategy, map[string]float64, error) {
+	def, ok := registry[name]
+	if !ok {
+		return nil, nil, ErrUnknownStrategy
+	}
+
+	resolved := make(map[string]float64, len(def.info.Params))
+	for key := range params {
+		known := false
+		for _, p := range def.info.Params {
+			known = known || p.Name == key
+		}
+		if !known {
+			return nil, nil, &ParamError{Name: key, Message: "is not a parameter of " + name}
+		}
+	}
+	for _, p := range def.info.Params {
+		value, ok := params[p.Name]
+		if !ok {
+			value = p.Default
+		}
+		if value < p.Min || value > p.Max {
+			return nil, nil, &ParamError{Name: p.Name, Message: fmt.Sprintf("must be between %g and %g", p.Min, p.Max)}
+		}
+		if p.Integer && value != math.Trunc(value) {
+			return nil, nil, &ParamError{Name: p.Name, Message: "must be a whole number"}
+		}
+		resolved[p.Name] = value
+	}
+
+	strategy, err := def.build(resolved)
+	if err != nil {
+		return nil, nil, err
+	}
+	return strategy, resolved, nil
+}
+
+func init() {
+	register(models.BacktestStrategyInfo{
+		Name:        "sma_crossover",
+		Description: "Holds the stock while the fast simple moving average is above the slow one",
+		Params: []models.BacktestParam{
+			{Name: "fast", Description: "Fast SMA period in bars", Default: 20, Min: 2, Max: 250, Integer: true},
+			{Name: "slow", Description: "Slow SMA period in bars", Default: 50, Min: 3, Max: 500, Integer: true},
+			{Name: "allocation", Description: "Percent of equity invested while holding", Default: 100, Min: 1, Max: 100},
+		},
+	}, func(params map[string]float64) (Strategy, error) {
+		if params["fast"] >= params["slow"] {
+			return nil, &ParamError{Name: "fast", Message: "must be less than slow"}
+		}
+		return &SMACrossover{Fast: int(params["fast"]), Slow: int(params["slow"]), Allocation: params["allocation"]}, nil
+	})
+
+	register(models.BacktestStrategyInfo{
+		Name:        "rsi_mean_reversion",
+		Description: "Buys when RSI falls below the oversold level and sells when it rises above the overbought level",
+		Params: []models.BacktestParam{
+			{Name: "period", Description: "RSI period in bars", Default: 14, Min: 2, Max: 100, Integer: true},
+			{Name: "oversold", Description: "RSI level to buy below", Default: 30, Min: 1, Max: 50},
+			{Name: "overbought", Description: "RSI level to sell above", Default: 70, Min: 50, Max: 99},
+			{Name: "allocation", Description: "Percent of equity invested while holding", Default: 100, Min: 1, Max: 100},
+		},
+	}, func(params map[string]float64) (Strategy, error) {
+		return &RSIMeanReversion{
+			Period:     int(params["period"]),
+			Oversold:   params["oversold"],
+			Overbought: params["overbought"],
+			Allocation: params["allocation"],
+		}, nil
+	})
+}
+
+// SMACrossover buys when the fast SMA crosses above the slow SMA and sells
+// when it crosses back below
+type SMACrossover struct {
+	Fast, Slow int
+	Allocation float64
+}
+
+func (s *SMACrossover) OnBar(ctx *Context) {
+	closes := ctx.Closes()
+	if len(closes) < s.Slow+1 {
+		return
+	}
+
+	above := service.SMA(closes, s.Fast) > service.SMA(closes, s.Slow)
+	wasAbove := service.SMA(closes[:len(closes)-1], s.Fast) > service.SMA(closes[:len(closes)-1], s.Slow)
+	switch {
+	case above && !wasAbove && ctx.Position() == 0:
+		ctx.TargetPercent(s.Allocation)
+	case !above && wasAbove && ctx.Position() > 0:
+		ctx.TargetPercent(0)
+	}
+}
+
+// RSIMeanReversion buys an oversold stock and sells it once overbought
+type RSIMeanReversion struct {
+	Period               int
+	Oversold, Overbought float64
+	Allocation           float64
+}
+
+// rsiWindows is how many periods of closes RSI is computed over, so Wilder's
+// smoothing has settled
+const rsiWindows = 5
+
+func (s *RSIMeanReversion) OnBar(ctx *Context) {
+	closes := ctx.Closes()
+	if len(closes) <= s.Period {
+		return
+	}
+	if window := s.Period*rsiWindows + 1; len(closes) > window {
+		closes = closes[len(closes)-window:]
+	}
+
+	rsi := service.RSI(closes, s.Period)
+	switch {
+	case rsi < s.Oversold && ctx.Position() == 0:
+		ctx.TargetPercent(s.Allocation)
+	case rsi > s.Overbought && ctx.Position() > 0:
+		ctx.TargetPercent(0)
+	}
+}
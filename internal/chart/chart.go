@@ -0,0 +1,453 @@
+// Package chart renders candlestick charts of daily price history as PNG or
+// SVG images in pure Go.
+//
+// A chart is laid out once as a scene of shapes and labels measured in the
+// Go fonts, and each format draws that scene, so both look the same.
+package chart
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-flow/internal/models"
+	"go-flow/internal/service"
+)
+
+// Formats a chart can be rendered in
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+)
+
+// ContentType returns the media type of a format
+func ContentType(format string) string {
+	if format == FormatSVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Bollinger Bands overlays use the usual 20 days and two deviations
+const (
+	bollingerPeriod     = 20
+	bollingerDeviations = 2
+)
+
+// Options configures a chart
+type Options struct {
+	Width, Height int
+	Theme         string
+	// SMA lists the periods of the moving averages to overlay
+	SMA       []int
+	Bollinger bool
+	Volume    bool
+}
+
+// Warmup returns how many bars before the first plotted one the overlays
+// need to be defined from the start
+func (o Options) Warmup() int {
+	n := 0
+	for _, period := range o.SMA {
+		n = max(n, period-1)
+	}
+	if o.Bollinger {
+		n = max(n, bollingerPeriod-1)
+	}
+	return n
+}
+
+// Theme holds a chart's colors
+type Theme struct {
+	Background color.NRGBA
+	Text       color.NRGBA
+	Muted      color.NRGBA
+	Grid       color.NRGBA
+	Up         color.NRGBA
+	Down       color.NRGBA
+	Band       color.NRGBA
+	// Overlays colors the moving averages in order
+	Overlays []color.NRGBA
+}
+
+// Themes are the built-in color themes
+var Themes = map[string]Theme{
+	"light": {
+		Background: hex(0xffffff),
+		Text:       hex(0x1f2937),
+		Muted:      hex(0x6b7280),
+		Grid:       hex(0xe5e7eb),
+		Up:         hex(0x16a34a),
+		Down:       hex(0xdc2626),
+		Band:       hex(0x3b82f6),
+		Overlays:   []color.NRGBA{hex(0xf59e0b), hex(0x8b5cf6), hex(0x0ea5e9), hex(0xec4899)},
+	},
+	"dark": {
+		Background: hex(0x111827),
+		Text:       hex(0xf3f4f6),
+		Muted:      hex(0x9ca3af),
+		Grid:       hex(0x374151),
+		Up:         hex(0x22c55e),
+		Down:       hex(0xef4444),
+		Band:       hex(0x60a5fa),
+		Overlays:   []color.NRGBA{hex(0xfbbf24), hex(0xa78bfa), hex(0x38bdf8), hex(0xf472b6)},
+	},
+}
+
+func hex(rgb uint32) color.NRGBA {
+	return color.NRGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 255}
+}
+
+func alpha(c color.NRGBA, a float64) color.NRGBA {
+	c.A = uint8(math.Round(a * 255))
+	return c
+}
+
+// Render draws the bars of history from index from onwards; earlier bars
+// only feed the overlays. history is ordered oldest first.
+func Render(w io.Writer, format, symbol string, history []models.StockHistoryEntry, from int, opts Options) error {
+	if from < 0 || from >= len(history) {
+		return fmt.Errorf("no bars to chart")
+	}
+	theme, ok := Themes[opts.Theme]
+	if !ok {
+		return fmt.Errorf("unknown theme %q", opts.Theme)
+	}
+
+	s := build(symbol, history, from, opts, theme)
+	switch format {
+	case FormatPNG:
+		return writePNG(w, s)
+	case FormatSVG:
+		return writeSVG(w, s)
+	}
+	return fmt.Errorf("unsupported chart format %q", format)
+}
+
+// Layout margins in pixels; prices are labeled on the right
+const (
+	marginLeft   = 10
+	marginRight  = 64
+	marginTop    = 34
+	marginBottom = 24
+	paneGap      = 8
+	// volumeShare is the part of the plot height given to volume bars
+	volumeShare = 0.2
+)
+
+// build lays out the chart
+func build(symbol string, history []models.StockHistoryEntry, from int, opts Options, theme Theme) *scene {
+	s := &scene{width: opts.Width, height: opts.Height, background: theme.Background}
+	bars := history[from:]
+	n := len(bars)
+
+	closes := make([]float64, len(history))
+	for i, bar := range history {
+		closes[i] = bar.Close
+	}
+
+	// Overlay values for each plotted bar; NaN where undefined
+	smas := make([][]float64, len(opts.SMA))
+	for j, period := range opts.SMA {
+		smas[j] = make([]float64, n)
+		for i := range bars {
+			smas[j][i] = math.NaN()
+			if from+i+1 >= period {
+				smas[j][i] = service.SMA(closes[:from+i+1], period)
+			}
+		}
+	}
+	var upper, lower []float64
+	if opts.Bollinger {
+		upper, lower = make([]float64, n), make([]float64, n)
+		for i := range bars {
+			upper[i], lower[i] = math.NaN(), math.NaN()
+			if bands := service.BollingerBands(closes[:from+i+1], bollingerPeriod, bollingerDeviations); bands != nil {
+				upper[i], lower[i] = bands.Upper, bands.Lower
+			}
+		}
+	}
+
+	// Panes
+	left, right := float64(marginLeft), float64(opts.Width-marginRight)
+	top, bottom := float64(marginTop), float64(opts.Height-marginBottom)
+	priceBottom, volumeTop := bottom, bottom
+	if opts.Volume {
+		volumeTop = bottom - (bottom-top)*volumeShare
+		priceBottom = volumeTop - paneGap
+	}
+	slot := (right - left) / float64(n)
+	xOf := func(i int) float64 { return left + slot*(float64(i)+0.5) }
+
+	// Price scale over everything that is plotted
+	low, high := math.Inf(1), math.Inf(-1)
+	for i, bar := range bars {
+		low, high = math.Min(low, bar.Low), math.Max(high, bar.High)
+		if opts.Bollinger && !math.IsNaN(upper[i]) {
+			low, high = math.Min(low, lower[i]), math.Max(high, upper[i])
+		}
+		for _, sma := range smas {
+			if !math.IsNaN(sma[i]) {
+				low, high = math.Min(low, sma[i]), math.Max(high, sma[i])
+			}
+		}
+	}
+	if high <= low {
+		high, low = high+1, low-1
+	}
+	pad := (high - low) * 0.05
+	low, high = low-pad, high+pad
+	yOf := func(price float64) float64 {
+		return priceBottom - (price-low)/(high-low)*(priceBottom-top)
+	}
+
+	// Grid and price labels
+	step := niceStep(high-low, max(2, int((priceBottom-top)/50)))
+	decimals := stepDecimals(step)
+	for price := math.Ceil(low/step) * step; price <= high; price += step {
+		y := math.Round(yOf(price)) + 0.5
+		s.add(line{points: []point{{left, y}, {right, y}}, stroke: theme.Grid, width: 1})
+		s.add(text{x: right + 6, y: y + 4, s: strconv.FormatFloat(price, 'f', decimals, 64), size: labelSize, color: theme.Muted})
+	}
+
+	// Date labels
+	for _, t := range dateTicks(bars, int((right-left)/80)) {
+		x := math.Round(xOf(t.bar)) + 0.5
+		s.add(line{points: []point{{x, top}, {x, bottom}}, stroke: alpha(theme.Grid, 0.6), width: 1})
+		s.add(text{x: x, y: bottom + 16, s: t.label, size: labelSize, color: theme.Muted, anchor: anchorMiddle})
+	}
+
+	// Bollinger Bands under the candles
+	if opts.Bollinger {
+		for _, run := range definedRuns(upper) {
+			var band []point
+			for i := run[0]; i < run[1]; i++ {
+				band = append(band, point{xOf(i), yOf(upper[i])})
+			}
+			for i := run[1] - 1; i >= run[0]; i-- {
+				band = append(band, point{xOf(i), yOf(lower[i])})
+			}
+			s.add(polygon{points: band, fill: alpha(theme.Band, 0.1)})
+		}
+		s.addSeries(upper, xOf, yOf, alpha(theme.Band, 0.7), 1)
+		s.addSeries(lower, xOf, yOf, alpha(theme.Band, 0.7), 1)
+	}
+
+	// Volume bars
+	if opts.Volume {
+		var maxVolume int64
+		for _, bar := range bars {
+			maxVolume = max(maxVolume, bar.Volume)
+		}
+		if maxVolume > 0 {
+			width := math.Max(1, slot*0.7)
+			for i, bar := range bars {
+				h := float64(bar.Volume) / float64(maxVolume) * (bottom - volumeTop)
+				s.add(rect{x: xOf(i) - width/2, y: bottom - h, w: width, h: h, fill: alpha(candleColor(bar, theme), 0.35)})
+			}
+			s.add(text{x: right + 6, y: volumeTop + 10, s: formatVolume(maxVolume), size: labelSize, color: theme.Muted})
+		}
+	}
+
+	// Candles
+	body := math.Max(1, slot*0.7)
+	wick := math.Max(1, math.Min(slot*0.15, 2))
+	for i, bar := range bars {
+		c := candleColor(bar, theme)
+		x := xOf(i)
+		s.add(rect{x: x - wick/2, y: yOf(bar.High), w: wick, h: math.Max(1, yOf(bar.Low)-yOf(bar.High)), fill: c})
+		yTop, yBottom := yOf(math.Max(bar.Open, bar.Close)), yOf(math.Min(bar.Open, bar.Close))
+		s.add(rect{x: x - body/2, y: yTop, w: body, h: math.Max(1, yBottom-yTop), fill: c})
+	}
+
+	// Moving averages over the candles
+	for j, sma := range smas {
+		s.addSeries(sma, xOf, yOf, theme.Overlays[j%len(theme.Overlays)], 1.5)
+	}
+
+	s.addHeader(symbol, bars, opts, theme, left, right)
+	return s
+}
+
+// addSeries draws an overlay as lines through its defined values
+func (s *scene) addSeries(values []float64, xOf func(int) float64, yOf func(float64) float64, c color.NRGBA, width float64) {
+	for _, run := range definedRuns(values) {
+		points := make([]point, 0, run[1]-run[0])
+		for i := run[0]; i < run[1]; i++ {
+			points = append(points, point{xOf(i), yOf(values[i])})
+		}
+		s.add(line{points: points, stroke: c, width: width})
+	}
+}
+
+// addHeader writes the symbol, last close and change over the chart on the
+// left and the overlay legend on the right
+func (s *scene) addHeader(symbol string, bars []models.StockHistoryEntry, opts Options, theme Theme, left, right float64) {
+	const baseline = 21
+	first, last := bars[0], bars[len(bars)-1]
+
+	x := left
+	s.add(text{x: x, y: baseline, s: symbol, size: titleSize, bold: true, color: theme.Text})
+	x += textWidth(symbol, titleSize, true) + 10
+
+	closing := strconv.FormatFloat(last.Close, 'f', 2, 64)
+	s.add(text{x: x, y: baseline, s: closing, size: titleSize, color: theme.Text})
+	x += textWidth(closing, titleSize, false) + 8
+
+	reference := first.Open
+	if reference == 0 {
+		reference = first.Close
+	}
+	change := last.Close - reference
+	label := fmt.Sprintf("%+.2f", change)
+	if reference != 0 {
+		label += fmt.Sprintf(" (%+.2f%%)", change/reference*100)
+	}
+	changeColor := theme.Up
+	if change < 0 {
+		changeColor = theme.Down
+	}
+	s.add(text{x: x, y: baseline, s: label, size: labelSize, color: changeColor})
+	x += textWidth(label, labelSize, false) + 10
+
+	span := first.Date.Format("Jan 2, 2006") + " – " + last.Date.Format("Jan 2, 2006")
+	s.add(text{x: x, y: baseline, s: span, size: labelSize, color: theme.Muted})
+
+	// Legend, right-aligned so it ends with the plot
+	type entry struct {
+		label string
+		color color.NRGBA
+	}
+	var legend []entry
+	for j, period := range opts.SMA {
+		legend = append(legend, entry{fmt.Sprintf("SMA %d", period), theme.Overlays[j%len(theme.Overlays)]})
+	}
+	if opts.Bollinger {
+		legend = append(legend, entry{fmt.Sprintf("BB %d, %d", bollingerPeriod, bollingerDeviations), theme.Band})
+	}
+	x = right
+	for i := len(legend) - 1; i >= 0; i-- {
+		s.add(text{x: x, y: baseline, s: legend[i].label, size: labelSize, color: theme.Muted, anchor: anchorEnd})
+		x -= textWidth(legend[i].label, labelSize, false) + 4
+		s.add(rect{x: x - 8, y: baseline - 8, w: 8, h: 8, fill: legend[i].color})
+		x -= 20
+	}
+}
+
+func candleColor(bar models.StockHistoryEntry, theme Theme) color.NRGBA {
+	if bar.Close < bar.Open {
+		return theme.Down
+	}
+	return theme.Up
+}
+
+// definedRuns returns the [start, end) index ranges of consecutive values
+// that are not NaN
+func definedRuns(values []float64) [][2]int {
+	var runs [][2]int
+	start := -1
+	for i, v := range values {
+		switch {
+		case !math.IsNaN(v) && start < 0:
+			start = i
+		case math.IsNaN(v) && start >= 0:
+			runs = append(runs, [2]int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		runs = append(runs, [2]int{start, len(values)})
+	}
+	return runs
+}
+
+// niceStep returns a round grid step that splits span into about count parts
+func niceStep(span float64, count int) float64 {
+	raw := span / float64(count)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 2.5, 5} {
+		if raw <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// stepDecimals returns how many decimals labels at a grid step need
+func stepDecimals(step float64) int {
+	d := 0
+	for scaled := step; d < 6 && math.Abs(scaled-math.Round(scaled)) > 1e-9; d++ {
+		scaled *= 10
+	}
+	return d
+}
+
+// tick is a labeled bar on the date axis
+type tick struct {
+	bar   int
+	label string
+}
+
+// dateTicks returns the bars to label: the first bar of each year on long
+// charts, of each month on medium ones and evenly spaced bars on short ones,
+// thinned out to at most limit labels
+func dateTicks(bars []models.StockHistoryEntry, limit int) []tick {
+	limit = max(limit, 2)
+	span := bars[len(bars)-1].Date.Sub(bars[0].Date)
+
+	var ticks []tick
+	switch {
+	case span > 3*365*24*time.Hour:
+		for i := 1; i < len(bars); i++ {
+			if bars[i].Date.Year() != bars[i-1].Date.Year() {
+				ticks = append(ticks, tick{i, bars[i].Date.Format("2006")})
+			}
+		}
+	case span > 60*24*time.Hour:
+		for i := 1; i < len(bars); i++ {
+			if bars[i].Date.Month() != bars[i-1].Date.Month() {
+				label := bars[i].Date.Format("Jan")
+				if bars[i].Date.Month() == time.January {
+					label = bars[i].Date.Format("2006")
+				}
+				ticks = append(ticks, tick{i, label})
+			}
+		}
+	default:
+		every := max(1, (len(bars)+limit-1)/limit)
+		for i := 0; i < len(bars); i += every {
+			ticks = append(ticks, tick{i, bars[i].Date.Format("Jan 2")})
+		}
+	}
+
+	if len(ticks) > limit {
+		every := (len(ticks) + limit - 1) / limit
+		thinned := ticks[:0]
+		for i := 0; i < len(ticks); i += every {
+			thinned = append(thinned, ticks[i])
+		}
+		ticks = thinned
+	}
+	return ticks
+}
+
+// formatVolume abbreviates a share count
+func formatVolume(v int64) string {
+	switch {
+	case v >= 1e9:
+		return trimZero(float64(v)/1e9) + "B"
+	case v >= 1e6:
+		return trimZero(float64(v)/1e6) + "M"
+	case v >= 1e3:
+		return trimZero(float64(v)/1e3) + "K"
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+func trimZero(v float64) string {
+	return strings.TrimSuffix(strconv.FormatFloat(v, 'f', 1, 64), ".0")
+}
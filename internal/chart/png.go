@@ -0,0 +1,104 @@
+package chart
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// writePNG rasterizes the scene with anti-aliasing and encodes it as PNG
+func writePNG(w io.Writer, s *scene) error {
+	img := image.NewRGBA(image.Rect(0, 0, s.width, s.height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(s.background), image.Point{}, draw.Src)
+
+	var z vector.Rasterizer
+	for _, e := range s.elements {
+		switch e := e.(type) {
+		case rect:
+			fill(img, &z, e.fill, [][]point{{{e.x, e.y}, {e.x + e.w, e.y}, {e.x + e.w, e.y + e.h}, {e.x, e.y + e.h}}})
+		case line:
+			fill(img, &z, e.stroke, strokeQuads(e.points, e.width))
+		case polygon:
+			fill(img, &z, e.fill, [][]point{e.points})
+		case text:
+			drawText(img, e)
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// fill paints the union of the shapes. The rasterizer only covers their
+// bounding box, so small shapes stay cheap on a large image.
+func fill(img *image.RGBA, z *vector.Rasterizer, c color.NRGBA, shapes [][]point) {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, shape := range shapes {
+		for _, p := range shape {
+			minX, minY = math.Min(minX, p.x), math.Min(minY, p.y)
+			maxX, maxY = math.Max(maxX, p.x), math.Max(maxY, p.y)
+		}
+	}
+	bounds := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY))).Intersect(img.Bounds())
+	if bounds.Empty() {
+		return
+	}
+
+	z.Reset(bounds.Dx(), bounds.Dy())
+	z.DrawOp = draw.Over
+	ox, oy := float64(bounds.Min.X), float64(bounds.Min.Y)
+	for _, shape := range shapes {
+		z.MoveTo(float32(shape[0].x-ox), float32(shape[0].y-oy))
+		for _, p := range shape[1:] {
+			z.LineTo(float32(p.x-ox), float32(p.y-oy))
+		}
+		z.ClosePath()
+	}
+	z.Draw(img, bounds, image.NewUniform(c), image.Point{})
+}
+
+// strokeQuads turns a polyline into one quad per segment. The quads all wind
+// the same way, so where they overlap at the joints coverage is not doubled.
+func strokeQuads(points []point, width float64) [][]point {
+	var quads [][]point
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		dx, dy := b.x-a.x, b.y-a.y
+		length := math.Hypot(dx, dy)
+		if length == 0 {
+			continue
+		}
+		// Half-width normal, with the segment extended by it to close joints
+		nx, ny := -dy/length*width/2, dx/length*width/2
+		ex, ey := dx/length*width/2, dy/length*width/2
+		quads = append(quads, []point{
+			{a.x - ex + nx, a.y - ey + ny},
+			{b.x + ex + nx, b.y + ey + ny},
+			{b.x + ex - nx, b.y + ey - ny},
+			{a.x - ex - nx, a.y - ey - ny},
+		})
+	}
+	return quads
+}
+
+func drawText(img *image.RGBA, t text) {
+	facesMu.Lock()
+	defer facesMu.Unlock()
+
+	d := font.Drawer{Dst: img, Src: image.NewUniform(t.color), Face: fontFace(t.size, t.bold)}
+	x := t.x
+	switch t.anchor {
+	case anchorMiddle:
+		x -= fixedToFloat(d.MeasureString(t.s)) / 2
+	case anchorEnd:
+		x -= fixedToFloat(d.MeasureString(t.s))
+	}
+	d.Dot = fixed.Point26_6{X: fixed.Int26_6(math.Round(x * 64)), Y: fixed.Int26_6(math.Round(t.y * 64))}
+	d.DrawString(t.s)
+}
@@ -0,0 +1,118 @@
+package chart
+
+import (
+	"image/color"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// A scene is a chart laid out as shapes in drawing order, so the PNG and SVG
+// renderers draw exactly the same picture
+type scene struct {
+	width, height int
+	background    color.NRGBA
+	elements      []any
+}
+
+type point struct {
+	x, y float64
+}
+
+// rect is a filled rectangle
+type rect struct {
+	x, y, w, h float64
+	fill       color.NRGBA
+}
+
+// line is a stroked polyline
+type line struct {
+	points []point
+	stroke color.NRGBA
+	width  float64
+}
+
+// polygon is a filled closed shape
+type polygon struct {
+	points []point
+	fill   color.NRGBA
+}
+
+// Text anchors
+const (
+	anchorStart = iota
+	anchorMiddle
+	anchorEnd
+)
+
+// text is a label whose baseline starts, centers or ends at x, y
+type text struct {
+	x, y   float64
+	s      string
+	size   float64
+	bold   bool
+	color  color.NRGBA
+	anchor int
+}
+
+func (s *scene) add(e any) {
+	s.elements = append(s.elements, e)
+}
+
+// Label sizes in pixels
+const (
+	labelSize = 11
+	titleSize = 13
+)
+
+type faceKey struct {
+	size float64
+	bold bool
+}
+
+var (
+	fonts = sync.OnceValue(func() map[bool]*opentype.Font {
+		regular, err := opentype.Parse(goregular.TTF)
+		if err != nil {
+			panic(err)
+		}
+		bold, err := opentype.Parse(gobold.TTF)
+		if err != nil {
+			panic(err)
+		}
+		return map[bool]*opentype.Font{false: regular, true: bold}
+	})
+
+	facesMu sync.Mutex
+	faces   = make(map[faceKey]font.Face)
+)
+
+// fontFace returns the Go font face for a label. Faces are shared, so
+// callers hold facesMu while using one.
+func fontFace(size float64, bold bool) font.Face {
+	key := faceKey{size, bold}
+	if face, ok := faces[key]; ok {
+		return face
+	}
+	face, err := opentype.NewFace(fonts()[bold], &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		panic(err)
+	}
+	faces[key] = face
+	return face
+}
+
+// textWidth measures a label in the font both renderers use
+func textWidth(s string, size float64, bold bool) float64 {
+	facesMu.Lock()
+	defer facesMu.Unlock()
+	return fixedToFloat(font.MeasureString(fontFace(size, bold), s))
+}
+
+func fixedToFloat(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
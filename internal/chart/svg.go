@@ -0,0 +1,66 @@
+package chart
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// writeSVG writes the scene as a standalone SVG document
+func writeSVG(w io.Writer, s *scene) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Go, Helvetica, Arial, sans-serif">`+"\n",
+		s.width, s.height, s.width, s.height)
+	fmt.Fprintf(b, `<rect width="100%%" height="100%%"%s/>`+"\n", svgPaint("fill", s.background))
+
+	for _, e := range s.elements {
+		switch e := e.(type) {
+		case rect:
+			fmt.Fprintf(b, `<rect x="%s" y="%s" width="%s" height="%s"%s/>`+"\n",
+				num(e.x), num(e.y), num(e.w), num(e.h), svgPaint("fill", e.fill))
+		case line:
+			fmt.Fprintf(b, `<polyline points="%s" fill="none" stroke-width="%s" stroke-linejoin="round"%s/>`+"\n",
+				svgPoints(e.points), num(e.width), svgPaint("stroke", e.stroke))
+		case polygon:
+			fmt.Fprintf(b, `<polygon points="%s"%s/>`+"\n", svgPoints(e.points), svgPaint("fill", e.fill))
+		case text:
+			weight := ""
+			if e.bold {
+				weight = ` font-weight="bold"`
+			}
+			anchor := [...]string{"start", "middle", "end"}[e.anchor]
+			fmt.Fprintf(b, `<text x="%s" y="%s" font-size="%s" text-anchor="%s"%s%s>%s</text>`+"\n",
+				num(e.x), num(e.y), num(e.size), anchor, weight, svgPaint("fill", e.color), html.EscapeString(e.s))
+		}
+	}
+
+	b.WriteString("</svg>\n")
+	return b.Flush()
+}
+
+// svgPaint returns a fill or stroke attribute with its opacity
+func svgPaint(attr string, c color.NRGBA) string {
+	paint := fmt.Sprintf(` %s="#%02x%02x%02x"`, attr, c.R, c.G, c.B)
+	if c.A < 255 {
+		paint += fmt.Sprintf(` %s-opacity="%s"`, attr, num(float64(c.A)/255))
+	}
+	return paint
+}
+
+func svgPoints(points []point) string {
+	parts := make([]string, len(points))
+	for i, p := range points {
+		parts[i] = num(p.x) + "," + num(p.y)
+	}
+	return strings.Join(parts, " ")
+}
+
+// num formats a coordinate with at most two decimals
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
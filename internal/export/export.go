@@ -0,0 +1,280 @@
+// Package export writes datasets as CSV, NDJSON or Parquet.
+//
+// Rows are encoded as they arrive, so an export streams from its source to
+// its destination without ever holding the whole dataset.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Formats an export can be written in
+const (
+	FormatCSV     = "csv"
+	FormatNDJSON  = "ndjson"
+	FormatParquet = "parquet"
+)
+
+// Formats lists the supported formats, the default first
+var Formats = []string{FormatCSV, FormatNDJSON, FormatParquet}
+
+// contentTypes maps each format to the media type it is served as
+var contentTypes = map[string]string{
+	FormatCSV:     "text/csv; charset=utf-8",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatParquet: "application/vnd.apache.parquet",
+}
+
+// acceptTypes maps the media types clients ask for to a format
+var acceptTypes = map[string]string{
+	"text/csv":                       FormatCSV,
+	"application/csv":                FormatCSV,
+	"application/x-ndjson":           FormatNDJSON,
+	"application/ndjson":             FormatNDJSON,
+	"application/jsonl":              FormatNDJSON,
+	"application/json":               FormatNDJSON,
+	"application/vnd.apache.parquet": FormatParquet,
+	"application/x-parquet":          FormatParquet,
+}
+
+const (
+	// flushEvery is how many rows are written between flushes of the
+	// destination, so a client starts receiving a large export right away
+	flushEvery = 500
+
+	// rowGroupSize bounds the rows a Parquet writer buffers before it writes
+	// them out as a row group
+	rowGroupSize = 10000
+)
+
+// ContentType returns the media type of a format
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+// ParseFormat validates a format name; "jsonl" is accepted for NDJSON
+func ParseFormat(name string) (string, error) {
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case FormatCSV, FormatNDJSON, FormatParquet:
+		return name, nil
+	case "jsonl":
+		return FormatNDJSON, nil
+	}
+	return "", fmt.Errorf("must be one of %s", strings.Join(Formats, ", "))
+}
+
+// Negotiate picks the format of an export. An explicit format parameter
+// wins; otherwise the first media type in the Accept header that names a
+// supported format is used, and CSV when none does.
+func Negotiate(accept, format string) (string, error) {
+	if format != "" {
+		return ParseFormat(format)
+	}
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if f, ok := acceptTypes[mediaType]; ok {
+			return f, nil
+		}
+	}
+	return FormatCSV, nil
+}
+
+// Write encodes the rows produced by stream into w in the given format. The
+// encoder is only created once the first row arrives or stream returns
+// without error, so when stream fails before producing anything nothing has
+// been written to w. If w has a Flush method it is flushed every few hundred
+// rows.
+func Write[T any](w io.Writer, format string, stream func(emit func(T) error) error) error {
+	var enc encoder[T]
+	n := 0
+	err := stream(func(row T) error {
+		if enc == nil {
+			var err error
+			if enc, err = newEncoder[T](w, format); err != nil {
+				return err
+			}
+		}
+		if err := enc.Write(row); err != nil {
+			return err
+		}
+		if n++; n%flushEvery == 0 {
+			return flush(w, enc)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if enc == nil {
+		if enc, err = newEncoder[T](w, format); err != nil {
+			return err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	return flush[T](w, nil)
+}
+
+// flush pushes what the encoder buffered through to the client
+func flush[T any](w io.Writer, enc encoder[T]) error {
+	if enc != nil {
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+	}
+	if f, ok := w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// encoder writes rows of one format
+type encoder[T any] interface {
+	Write(row T) error
+	// Flush writes out buffered rows where the format allows it
+	Flush() error
+	// Close finishes the output, writing any trailer the format needs
+	Close() error
+}
+
+func newEncoder[T any](w io.Writer, format string) (encoder[T], error) {
+	switch format {
+	case FormatCSV:
+		return newCSVEncoder[T](w)
+	case FormatNDJSON:
+		return &ndjsonEncoder[T]{enc: json.NewEncoder(w)}, nil
+	case FormatParquet:
+		return &parquetEncoder[T]{
+			writer: parquet.NewGenericWriter[T](w, parquet.MaxRowsPerRowGroup(rowGroupSize), parquet.Compression(&parquet.Snappy)),
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// csvEncoder writes a header named after the rows' JSON fields and one
+// record per row
+type csvEncoder[T any] struct {
+	w      *csv.Writer
+	fields []int
+}
+
+func newCSVEncoder[T any](w io.Writer) (*csvEncoder[T], error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot export %s as CSV", t)
+	}
+
+	enc := &csvEncoder[T]{w: csv.NewWriter(w)}
+	var header []string
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "-" || !t.Field(i).IsExported() {
+			continue
+		}
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		header = append(header, name)
+		enc.fields = append(enc.fields, i)
+	}
+
+	return enc, enc.w.Write(header)
+}
+
+func (e *csvEncoder[T]) Write(row T) error {
+	v := reflect.ValueOf(row)
+	record := make([]string, len(e.fields))
+	for i, field := range e.fields {
+		record[i] = csvValue(v.Field(field))
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvEncoder[T]) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder[T]) Close() error {
+	return e.Flush()
+}
+
+// csvValue formats a field the way its JSON encoding reads, leaving nil
+// values empty
+func csvValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		return value.UTC().Format(time.RFC3339)
+	case fmt.Stringer:
+		return value.String()
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// ndjsonEncoder writes one JSON object per line
+type ndjsonEncoder[T any] struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder[T]) Write(row T) error {
+	return e.enc.Encode(row)
+}
+
+func (e *ndjsonEncoder[T]) Flush() error {
+	return nil
+}
+
+func (e *ndjsonEncoder[T]) Close() error {
+	return nil
+}
+
+// parquetEncoder writes a Parquet file whose schema comes from the rows'
+// parquet tags. Rows are written out a row group at a time; the file is
+// only readable once Close has written the footer.
+type parquetEncoder[T any] struct {
+	writer *parquet.GenericWriter[T]
+}
+
+func (e *parquetEncoder[T]) Write(row T) error {
+	_, err := e.writer.Write([]T{row})
+	return err
+}
+
+func (e *parquetEncoder[T]) Flush() error {
+	return nil
+}
+
+func (e *parquetEncoder[T]) Close() error {
+	return e.writer.Close()
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"io"
+	"time"
+
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+)
+
+// Datasets that can be exported
+const (
+	DatasetHistory      = "history"
+	DatasetIndicators   = "indicators"
+	DatasetTransactions = "transactions"
+	DatasetValuations   = "valuations"
+)
+
+// Exporter streams stored datasets into a writer
+type Exporter struct {
+	repo repository.ExportRepository
+}
+
+func NewExporter(repo repository.ExportRepository) *Exporter {
+	return &Exporter{
+		repo: repo,
+	}
+}
+
+// History writes a stock's daily history, optionally limited to a date range
+func (e *Exporter) History(w io.Writer, format, symbol string, start, end *time.Time) error {
+	return Write(w, format, func(emit func(HistoryRow) error) error {
+		return e.repo.StreamHistory(symbol, start, end, func(entry models.StockHistoryEntry) error {
+			return emit(historyRow(symbol, entry))
+		})
+	})
+}
+
+// Indicators writes a stock's daily indicators. They are computed over the
+// whole stored history, so the first days of a range carry the same values
+// as a longer export would, and only the days in the range are written.
+func (e *Exporter) Indicators(w io.Writer, format, symbol string, start, end *time.Time) error {
+	series := service.NewIndicatorSeries()
+	return Write(w, format, func(emit func(IndicatorRow) error) error {
+		return e.repo.StreamHistory(symbol, nil, end, func(entry models.StockHistoryEntry) error {
+			values := series.Add(entry.Close)
+			if start != nil && entry.Date.Before(*start) {
+				return nil
+			}
+			return emit(indicatorRow(symbol, entry, values))
+		})
+	})
+}
+
+// Transactions writes a portfolio's ledger in the order it is applied
+func (e *Exporter) Transactions(w io.Writer, format string, portfolioID int64) error {
+	return Write(w, format, func(emit func(TransactionRow) error) error {
+		return e.repo.StreamTransactions(portfolioID, func(t models.Transaction) error {
+			return emit(transactionRow(t))
+		})
+	})
+}
+
+// Valuations writes a user's portfolio's stored daily valuations, oldest
+// first
+func (e *Exporter) Valuations(w io.Writer, format string, portfolioID, userID int64) error {
+	return Write(w, format, func(emit func(ValuationRow) error) error {
+		return e.repo.StreamSnapshots(portfolioID, userID, func(s models.PortfolioSnapshot) error {
+			return emit(valuationRow(s))
+		})
+	})
+}
@@ -0,0 +1,151 @@
+package export
+
+import (
+	"time"
+
+	"go-flow/internal/models"
+	"go-flow/internal/service"
+)
+
+// Date is a calendar day stored as days since the Unix epoch, which is how
+// Parquet represents dates. It is written as YYYY-MM-DD everywhere else.
+type Date int32
+
+// DateOf returns the day of t
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400)
+}
+
+func (d Date) Time() time.Time {
+	return time.Unix(int64(d)*86400, 0).UTC()
+}
+
+func (d Date) String() string {
+	return d.Time().Format("2006-01-02")
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// HistoryRow is one day of a stock's price history
+type HistoryRow struct {
+	Symbol   string  `json:"symbol" parquet:"symbol,dict"`
+	Date     Date    `json:"date" parquet:"date,date"`
+	Open     float64 `json:"open" parquet:"open"`
+	High     float64 `json:"high" parquet:"high"`
+	Low      float64 `json:"low" parquet:"low"`
+	Close    float64 `json:"close" parquet:"close"`
+	AdjClose float64 `json:"adj_close" parquet:"adj_close"`
+	Volume   int64   `json:"volume" parquet:"volume"`
+}
+
+func historyRow(symbol string, e models.StockHistoryEntry) HistoryRow {
+	return HistoryRow{
+		Symbol:   symbol,
+		Date:     DateOf(e.Date),
+		Open:     e.Open,
+		High:     e.High,
+		Low:      e.Low,
+		Close:    e.Close,
+		AdjClose: e.AdjClose,
+		Volume:   e.Volume,
+	}
+}
+
+// IndicatorRow holds a stock's daily indicators as of one day. Indicators
+// that need more history than is stored up to that day are null.
+type IndicatorRow struct {
+	Symbol        string   `json:"symbol" parquet:"symbol,dict"`
+	Date          Date     `json:"date" parquet:"date,date"`
+	Close         float64  `json:"close" parquet:"close"`
+	SMA20         *float64 `json:"sma_20" parquet:"sma_20,optional"`
+	SMA50         *float64 `json:"sma_50" parquet:"sma_50,optional"`
+	SMA200        *float64 `json:"sma_200" parquet:"sma_200,optional"`
+	EMA12         *float64 `json:"ema_12" parquet:"ema_12,optional"`
+	EMA26         *float64 `json:"ema_26" parquet:"ema_26,optional"`
+	RSI14         *float64 `json:"rsi_14" parquet:"rsi_14,optional"`
+	MACD          *float64 `json:"macd" parquet:"macd,optional"`
+	MACDSignal    *float64 `json:"macd_signal" parquet:"macd_signal,optional"`
+	MACDHistogram *float64 `json:"macd_histogram" parquet:"macd_histogram,optional"`
+	BBUpper       *float64 `json:"bb_upper" parquet:"bb_upper,optional"`
+	BBMiddle      *float64 `json:"bb_middle" parquet:"bb_middle,optional"`
+	BBLower       *float64 `json:"bb_lower" parquet:"bb_lower,optional"`
+}
+
+func indicatorRow(symbol string, e models.StockHistoryEntry, v service.IndicatorValues) IndicatorRow {
+	return IndicatorRow{
+		Symbol:        symbol,
+		Date:          DateOf(e.Date),
+		Close:         e.Close,
+		SMA20:         v.SMA20,
+		SMA50:         v.SMA50,
+		SMA200:        v.SMA200,
+		EMA12:         v.EMA12,
+		EMA26:         v.EMA26,
+		RSI14:         v.RSI14,
+		MACD:          v.MACD,
+		MACDSignal:    v.Signal,
+		MACDHistogram: v.Histogram,
+		BBUpper:       v.BBUpper,
+		BBMiddle:      v.BBMiddle,
+		BBLower:       v.BBLower,
+	}
+}
+
+// TransactionRow is one ledger entry of a portfolio
+type TransactionRow struct {
+	ID          int64     `json:"id" parquet:"id"`
+	PortfolioID int64     `json:"portfolio_id" parquet:"portfolio_id"`
+	ExecutedAt  time.Time `json:"executed_at" parquet:"executed_at,timestamp(millisecond)"`
+	Type        string    `json:"type" parquet:"type,dict"`
+	Symbol      string    `json:"symbol" parquet:"symbol,dict"`
+	Quantity    float64   `json:"quantity" parquet:"quantity"`
+	Price       float64   `json:"price" parquet:"price"`
+	Fees        float64   `json:"fees" parquet:"fees"`
+	TotalAmount float64   `json:"total_amount" parquet:"total_amount"`
+	SplitRatio  *float64  `json:"split_ratio" parquet:"split_ratio,optional"`
+	Notes       string    `json:"notes" parquet:"notes"`
+	ExternalID  string    `json:"external_id" parquet:"external_id"`
+	ImportID    *int64    `json:"import_id" parquet:"import_id,optional"`
+}
+
+func transactionRow(t models.Transaction) TransactionRow {
+	return TransactionRow{
+		ID:          t.ID,
+		PortfolioID: t.PortfolioID,
+		ExecutedAt:  t.ExecutedAt.UTC(),
+		Type:        t.Type,
+		Symbol:      t.Symbol,
+		Quantity:    t.Quantity,
+		Price:       t.Price,
+		Fees:        t.Fees,
+		TotalAmount: t.TotalAmount,
+		SplitRatio:  t.SplitRatio,
+		Notes:       t.Notes,
+		ExternalID:  t.ExternalID,
+		ImportID:    t.ImportID,
+	}
+}
+
+// ValuationRow is a portfolio's value at the close of one day
+type ValuationRow struct {
+	PortfolioID int64   `json:"portfolio_id" parquet:"portfolio_id"`
+	Date        Date    `json:"date" parquet:"date,date"`
+	Cash        float64 `json:"cash" parquet:"cash"`
+	MarketValue float64 `json:"market_value" parquet:"market_value"`
+	TotalValue  float64 `json:"total_value" parquet:"total_value"`
+	NetFlow     float64 `json:"net_flow" parquet:"net_flow"`
+}
+
+func valuationRow(s models.PortfolioSnapshot) ValuationRow {
+	return ValuationRow{
+		PortfolioID: s.PortfolioID,
+		Date:        DateOf(s.Date),
+		Cash:        s.Cash,
+		MarketValue: s.MarketValue,
+		TotalValue:  s.TotalValue,
+		NetFlow:     s.NetFlow,
+	}
+}
@@ -0,0 +1,159 @@
+package graph
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"go-flow/internal/models"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// indicatorLookback is how many daily bars indicators are calculated over,
+// enough for the 200 day moving average
+const indicatorLookback = 250
+
+// Viewer is the user a request acts for; a zero UserID is an anonymous
+// request, which may only read stocks
+type Viewer struct {
+	UserID int64
+	Scopes []string
+}
+
+// historyKey identifies one history query; zero times leave the range open
+type historyKey struct {
+	Symbol     string
+	Start, End time.Time
+	Limit      int
+}
+
+// historyRange is a history query without its symbol, which batches share
+type historyRange struct {
+	Start, End time.Time
+	Limit      int
+}
+
+// loaders are created for each request so their caches never outlive it
+type loaders struct {
+	stocks         *Loader[string, *models.Stock]
+	histories      *Loader[historyKey, []models.StockHistoryEntry]
+	watchlistItems *Loader[int64, []models.StockWatchlist]
+}
+
+type contextKey struct{}
+
+type requestContext struct {
+	viewer  Viewer
+	loaders *loaders
+}
+
+// WithRequest prepares ctx for executing a request on behalf of viewer
+func (r *Resolver) WithRequest(ctx context.Context, viewer Viewer) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestContext{
+		viewer:  viewer,
+		loaders: r.newLoaders(viewer.UserID),
+	})
+}
+
+func (r *Resolver) newLoaders(userID int64) *loaders {
+	return &loaders{
+		stocks: NewLoader(func(symbols []string) (map[string]*models.Stock, error) {
+			stocks, err := r.StockRepo.GetBySymbols(symbols)
+			if err != nil {
+				return nil, err
+			}
+			bySymbol := make(map[string]*models.Stock, len(stocks))
+			for i := range stocks {
+				bySymbol[stocks[i].Symbol] = &stocks[i]
+			}
+			return bySymbol, nil
+		}),
+		histories: NewLoader(func(keys []historyKey) (map[historyKey][]models.StockHistoryEntry, error) {
+			// One query per distinct range covers every symbol asking for it
+			symbols := make(map[historyRange][]string)
+			for _, key := range keys {
+				span := historyRange{Start: key.Start, End: key.End, Limit: key.Limit}
+				symbols[span] = append(symbols[span], key.Symbol)
+			}
+
+			histories := make(map[historyKey][]models.StockHistoryEntry, len(keys))
+			for span, batch := range symbols {
+				query := models.StockHistoryQuery{Limit: span.Limit}
+				if !span.Start.IsZero() {
+					query.Start = &span.Start
+				}
+				if !span.End.IsZero() {
+					query.End = &span.End
+				}
+				found, err := r.StockRepo.QueryHistories(batch, query)
+				if err != nil {
+					return nil, err
+				}
+				for symbol, history := range found {
+					histories[historyKey{Symbol: symbol, Start: span.Start, End: span.End, Limit: span.Limit}] = history
+				}
+			}
+			return histories, nil
+		}),
+		watchlistItems: NewLoader(func(ids []int64) (map[int64][]models.StockWatchlist, error) {
+			return r.WatchlistRepo.ListItemsFor(ids, userID)
+		}),
+	}
+}
+
+// request returns what WithRequest stored in ctx. Without it the request is
+// anonymous and gets loaders of its own.
+func (r *Resolver) request(ctx context.Context) *requestContext {
+	if rc, ok := ctx.Value(contextKey{}).(*requestContext); ok {
+		return rc
+	}
+	return &requestContext{loaders: r.newLoaders(0)}
+}
+
+// loadStock loads a stored stock by symbol, or nil if it is not stored
+func (r *Resolver) loadStock(ctx context.Context, symbol string) (*models.Stock, error) {
+	stock, err := r.request(ctx).loaders.stocks.Load(ctx, strings.ToUpper(symbol))
+	if err != nil {
+		return nil, internalError(err, "Failed to retrieve stock")
+	}
+	return stock, nil
+}
+
+// viewerWith returns the authenticated viewer if their credentials were
+// granted scope
+func (r *Resolver) viewerWith(ctx context.Context, scope string) (Viewer, error) {
+	viewer := r.request(ctx).viewer
+	if viewer.UserID == 0 {
+		return viewer, codedError(codeUnauthorized, "A bearer token or API key is required")
+	}
+	if !slices.Contains(viewer.Scopes, scope) {
+		return viewer, codedError(codeForbidden, "The credentials are missing the "+scope+" scope")
+	}
+	return viewer, nil
+}
+
+// Error codes reported in the extensions of GraphQL errors, matching the
+// problem codes of the REST API
+const (
+	codeInvalidRequest = "invalid_request"
+	codeUnauthorized   = "unauthorized"
+	codeForbidden      = "forbidden"
+	codeInternalError  = "internal_error"
+)
+
+func codedError(code, message string) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message:    message,
+		Extensions: map[string]any{"code": code},
+	}
+}
+
+// internalError logs err and returns message without the details, as the
+// REST handlers do
+func internalError(err error, message string) *gqlerror.Error {
+	log.Printf("graphql: %s: %v", strings.ToLower(message[:1])+message[1:], err)
+	return codedError(codeInternalError, message)
+}
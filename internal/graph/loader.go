@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// batchWait is how long a loader collects keys before fetching them. Sibling
+// fields resolve concurrently, so a short wait gathers a whole list.
+const batchWait = 2 * time.Millisecond
+
+// Loader batches and caches lookups by key for the lifetime of a request,
+// so resolving a field on every element of a list costs one query rather
+// than one per element
+type Loader[K comparable, V any] struct {
+	fetch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	cache   map[K]*loaded[V]
+	pending map[K]*loaded[V]
+}
+
+type loaded[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewLoader returns a loader that fetches batches with fetch. Keys missing
+// from the fetched map load as the zero value.
+func NewLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch: fetch,
+		cache: make(map[K]*loaded[V]),
+	}
+}
+
+// Load returns the value for key, waiting for the batch it joins
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	result := l.enqueue(key)
+	l.mu.Unlock()
+
+	return result.wait(ctx)
+}
+
+// LoadAll returns the values for keys in order, fetched in the same batch
+func (l *Loader[K, V]) LoadAll(ctx context.Context, keys []K) ([]V, error) {
+	l.mu.Lock()
+	results := make([]*loaded[V], len(keys))
+	for i, key := range keys {
+		results[i] = l.enqueue(key)
+	}
+	l.mu.Unlock()
+
+	values := make([]V, len(keys))
+	for i, result := range results {
+		value, err := result.wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// enqueue returns the cached result for key, adding the key to the pending
+// batch on first use. The caller holds l.mu.
+func (l *Loader[K, V]) enqueue(key K) *loaded[V] {
+	if result, ok := l.cache[key]; ok {
+		return result
+	}
+
+	result := &loaded[V]{done: make(chan struct{})}
+	l.cache[key] = result
+	if l.pending == nil {
+		l.pending = make(map[K]*loaded[V])
+		time.AfterFunc(batchWait, l.dispatch)
+	}
+	l.pending[key] = result
+	return result
+}
+
+func (r *loaded[V]) wait(ctx context.Context) (V, error) {
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// dispatch fetches the pending batch and releases its waiters
+func (l *Loader[K, V]) dispatch() {
+	l.mu.Lock()
+	batch := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	keys := make([]K, 0, len(batch))
+	for key := range batch {
+		keys = append(keys, key)
+	}
+
+	values, err := l.fetch(keys)
+	for key, result := range batch {
+		result.value, result.err = values[key], err
+		close(result.done)
+	}
+}
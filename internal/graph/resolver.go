@@ -0,0 +1,56 @@
+// Package graph serves the GraphQL API.
+//
+// The schema lives in schema.graphqls; generated.go and the resolver
+// signatures are generated from it with `make graphql`. Stocks and their
+// history are public, while portfolios, watchlists and alerts are resolved
+// for the viewer, the user whose credentials came with the request.
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+)
+
+// Resolver holds the dependencies every resolver shares
+type Resolver struct {
+	StockRepo     repository.StockRepository
+	PortfolioRepo repository.PortfolioRepository
+	WatchlistRepo repository.WatchlistRepository
+	AlertRepo     repository.AlertRepository
+	PriceBus      *service.PriceBus
+}
+
+// priceBuffer is how many updates a price subscription may fall behind by
+// before it misses some
+const priceBuffer = 64
+
+// normalizeSymbols upper-cases symbols and drops repeats, enforcing the
+// limit on how many one field takes
+func normalizeSymbols(symbols []string) ([]string, error) {
+	if len(symbols) == 0 || len(symbols) > maxSymbols {
+		return nil, codedError(codeInvalidRequest, fmt.Sprintf("between 1 and %d symbols are required", maxSymbols))
+	}
+
+	normalized := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol != "" && !slices.Contains(normalized, symbol) {
+			normalized = append(normalized, symbol)
+		}
+	}
+	return normalized, nil
+}
+
+// pointers returns pointers to the elements of values, as gqlgen resolves
+// lists of objects
+func pointers[T any](values []T) []*T {
+	ptrs := make([]*T, len(values))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	return ptrs
+}
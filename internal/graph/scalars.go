@@ -0,0 +1,27 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// MarshalInt64ID and UnmarshalInt64ID bind the ID scalar to the int64 keys
+// of the database. IDs are written as strings, as the GraphQL spec
+// recommends, and read from strings or numbers.
+func MarshalInt64ID(id int64) graphql.Marshaler {
+	return graphql.MarshalID(strconv.FormatInt(id, 10))
+}
+
+func UnmarshalInt64ID(v any) (int64, error) {
+	s, err := graphql.UnmarshalID(v)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid ID", s)
+	}
+	return id, nil
+}
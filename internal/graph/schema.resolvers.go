@@ -0,0 +1,306 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+	"slices"
+	"time"
+)
+
+// Stock is the resolver for the stock field.
+func (r *alertResolver) Stock(ctx context.Context, obj *models.StockAlert) (*models.Stock, error) {
+	return r.loadStock(ctx, obj.Symbol)
+}
+
+// Operator is the resolver for the operator field.
+func (r *alertConditionResolver) Operator(ctx context.Context, obj *models.AlertCondition) (*string, error) {
+	if obj.Operator == "" {
+		return nil, nil
+	}
+	return &obj.Operator, nil
+}
+
+// Days is the resolver for the days field.
+func (r *alertConditionResolver) Days(ctx context.Context, obj *models.AlertCondition) (*int, error) {
+	if obj.Days == 0 {
+		return nil, nil
+	}
+	return &obj.Days, nil
+}
+
+// Stock is the resolver for the stock field.
+func (r *positionResolver) Stock(ctx context.Context, obj *models.PortfolioPosition) (*models.Stock, error) {
+	return r.loadStock(ctx, obj.Symbol)
+}
+
+// High is the resolver for the high field.
+func (r *priceUpdateResolver) High(ctx context.Context, obj *service.PriceUpdate) (*float64, error) {
+	if obj.BarDate.IsZero() {
+		return nil, nil
+	}
+	return &obj.High, nil
+}
+
+// Low is the resolver for the low field.
+func (r *priceUpdateResolver) Low(ctx context.Context, obj *service.PriceUpdate) (*float64, error) {
+	if obj.BarDate.IsZero() {
+		return nil, nil
+	}
+	return &obj.Low, nil
+}
+
+// BarDate is the resolver for the barDate field.
+func (r *priceUpdateResolver) BarDate(ctx context.Context, obj *service.PriceUpdate) (*time.Time, error) {
+	if obj.BarDate.IsZero() {
+		return nil, nil
+	}
+	return &obj.BarDate, nil
+}
+
+// Stock is the resolver for the stock field.
+func (r *queryResolver) Stock(ctx context.Context, symbol string) (*models.Stock, error) {
+	return r.loadStock(ctx, symbol)
+}
+
+// Stocks is the resolver for the stocks field.
+func (r *queryResolver) Stocks(ctx context.Context, symbols []string) ([]*models.Stock, error) {
+	symbols, err := normalizeSymbols(symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	stocks, err := r.request(ctx).loaders.stocks.LoadAll(ctx, symbols)
+	if err != nil {
+		return nil, internalError(err, "Failed to retrieve stocks")
+	}
+	return slices.DeleteFunc(stocks, func(stock *models.Stock) bool { return stock == nil }), nil
+}
+
+// Portfolios is the resolver for the portfolios field.
+func (r *queryResolver) Portfolios(ctx context.Context) ([]*models.Portfolio, error) {
+	viewer, err := r.viewerWith(ctx, models.ScopePortfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolios, err := r.PortfolioRepo.List(viewer.UserID)
+	if err != nil {
+		return nil, internalError(err, "Failed to retrieve portfolios")
+	}
+	for i := range portfolios {
+		service.ValuePortfolio(&portfolios[i])
+	}
+	return pointers(portfolios), nil
+}
+
+// Portfolio is the resolver for the portfolio field.
+func (r *queryResolver) Portfolio(ctx context.Context, id int64) (*models.Portfolio, error) {
+	viewer, err := r.viewerWith(ctx, models.ScopePortfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio, err := r.PortfolioRepo.Get(id, viewer.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, internalError(err, "Failed to retrieve portfolio")
+	}
+	service.ValuePortfolio(portfolio)
+	return portfolio, nil
+}
+
+// Watchlists is the resolver for the watchlists field.
+func (r *queryResolver) Watchlists(ctx context.Context) ([]*models.Watchlist, error) {
+	viewer, err := r.viewerWith(ctx, models.ScopePortfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	watchlists, err := r.WatchlistRepo.List(viewer.UserID)
+	if err != nil {
+		return nil, internalError(err, "Failed to retrieve watchlists")
+	}
+	return pointers(watchlists), nil
+}
+
+// Watchlist is the resolver for the watchlist field.
+func (r *queryResolver) Watchlist(ctx context.Context, id int64) (*models.Watchlist, error) {
+	viewer, err := r.viewerWith(ctx, models.ScopePortfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	watchlist, err := r.WatchlistRepo.Get(id, viewer.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, internalError(err, "Failed to retrieve watchlist")
+	}
+	return watchlist, nil
+}
+
+// Alerts is the resolver for the alerts field.
+func (r *queryResolver) Alerts(ctx context.Context, active *bool) ([]*models.StockAlert, error) {
+	viewer, err := r.viewerWith(ctx, models.ScopePortfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts, err := r.AlertRepo.List(viewer.UserID, active)
+	if err != nil {
+		return nil, internalError(err, "Failed to retrieve alerts")
+	}
+	return pointers(alerts), nil
+}
+
+// Alert is the resolver for the alert field.
+func (r *queryResolver) Alert(ctx context.Context, id int64) (*models.StockAlert, error) {
+	viewer, err := r.viewerWith(ctx, models.ScopePortfolio)
+	if err != nil {
+		return nil, err
+	}
+
+	alert, err := r.AlertRepo.Get(id, viewer.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, internalError(err, "Failed to retrieve alert")
+	}
+	return alert, nil
+}
+
+// History is the resolver for the history field.
+func (r *stockResolver) History(ctx context.Context, obj *models.Stock, start *time.Time, end *time.Time, limit *int) ([]*models.StockHistoryEntry, error) {
+	key := historyKey{Symbol: obj.Symbol, Limit: defaultHistoryLimit}
+	if limit != nil {
+		if *limit < 1 || *limit > maxHistoryLimit {
+			return nil, codedError(codeInvalidRequest, fmt.Sprintf("history limit must be between 1 and %d", maxHistoryLimit))
+		}
+		key.Limit = *limit
+	}
+	if start != nil {
+		key.Start = *start
+	}
+	if end != nil {
+		key.End = *end
+	}
+	if start != nil && end != nil && end.Before(*start) {
+		return nil, codedError(codeInvalidRequest, "history end must not be before start")
+	}
+
+	history, err := r.request(ctx).loaders.histories.Load(ctx, key)
+	if err != nil {
+		return nil, internalError(err, "Failed to retrieve stock history")
+	}
+	return pointers(history), nil
+}
+
+// Indicators is the resolver for the indicators field.
+func (r *stockResolver) Indicators(ctx context.Context, obj *models.Stock) (*models.TechnicalIndicators, error) {
+	history, err := r.request(ctx).loaders.histories.Load(ctx, historyKey{Symbol: obj.Symbol, Limit: indicatorLookback})
+	if err != nil {
+		return nil, internalError(err, "Failed to retrieve stock history")
+	}
+	if len(history) == 0 {
+		return nil, nil
+	}
+	return service.CalculateIndicators(obj.Symbol, history), nil
+}
+
+// Prices is the resolver for the prices field.
+func (r *subscriptionResolver) Prices(ctx context.Context, symbols []string) (<-chan *service.PriceUpdate, error) {
+	symbols, err := normalizeSymbols(symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	updates, unsubscribe := r.PriceBus.Subscribe(priceBuffer)
+	out := make(chan *service.PriceUpdate)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				if !slices.Contains(symbols, update.Symbol) {
+					continue
+				}
+				select {
+				case out <- &update:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Items is the resolver for the items field.
+func (r *watchlistResolver) Items(ctx context.Context, obj *models.Watchlist) ([]*models.StockWatchlist, error) {
+	items, err := r.request(ctx).loaders.watchlistItems.Load(ctx, obj.ID)
+	if err != nil {
+		return nil, internalError(err, "Failed to retrieve watchlist items")
+	}
+	return pointers(items), nil
+}
+
+// Stock is the resolver for the stock field.
+func (r *watchlistItemResolver) Stock(ctx context.Context, obj *models.StockWatchlist) (*models.Stock, error) {
+	return r.loadStock(ctx, obj.Symbol)
+}
+
+// Alert returns AlertResolver implementation.
+func (r *Resolver) Alert() AlertResolver { return &alertResolver{r} }
+
+// AlertCondition returns AlertConditionResolver implementation.
+func (r *Resolver) AlertCondition() AlertConditionResolver { return &alertConditionResolver{r} }
+
+// Position returns PositionResolver implementation.
+func (r *Resolver) Position() PositionResolver { return &positionResolver{r} }
+
+// PriceUpdate returns PriceUpdateResolver implementation.
+func (r *Resolver) PriceUpdate() PriceUpdateResolver { return &priceUpdateResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// Stock returns StockResolver implementation.
+func (r *Resolver) Stock() StockResolver { return &stockResolver{r} }
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+// Watchlist returns WatchlistResolver implementation.
+func (r *Resolver) Watchlist() WatchlistResolver { return &watchlistResolver{r} }
+
+// WatchlistItem returns WatchlistItemResolver implementation.
+func (r *Resolver) WatchlistItem() WatchlistItemResolver { return &watchlistItemResolver{r} }
+
+type alertResolver struct{ *Resolver }
+type alertConditionResolver struct{ *Resolver }
+type positionResolver struct{ *Resolver }
+type priceUpdateResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type stockResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type watchlistResolver struct{ *Resolver }
+type watchlistItemResolver struct{ *Resolver }
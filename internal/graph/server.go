@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	// maxSymbols caps how many symbols stocks and prices take at once
+	maxSymbols = 50
+	// maxHistoryLimit caps how many bars one history field returns
+	maxHistoryLimit = 5000
+	// defaultHistoryLimit matches the default of the history argument
+	defaultHistoryLimit = 100
+	// listEstimate is the size assumed for lists without a size argument
+	listEstimate = 10
+)
+
+// NewServer returns the GraphQL server. Queries whose estimated cost exceeds
+// complexityLimit are rejected before they run: each field costs one, and
+// list fields multiply the cost of their elements by the number of symbols
+// or bars asked for, or by listEstimate when the size is not known upfront.
+func NewServer(r *Resolver, complexityLimit int) *handler.Server {
+	cfg := Config{Resolvers: r}
+	perSymbol := func(childComplexity int, symbols []string) int {
+		return 1 + len(symbols)*childComplexity
+	}
+	perItem := func(childComplexity int) int {
+		return 1 + listEstimate*childComplexity
+	}
+	cfg.Complexity.Query.Stocks = perSymbol
+	cfg.Complexity.Subscription.Prices = perSymbol
+	cfg.Complexity.Query.Portfolios = perItem
+	cfg.Complexity.Query.Watchlists = perItem
+	cfg.Complexity.Query.Alerts = func(childComplexity int, _ *bool) int {
+		return perItem(childComplexity)
+	}
+	cfg.Complexity.Portfolio.Positions = perItem
+	cfg.Complexity.Watchlist.Items = perItem
+	cfg.Complexity.Stock.History = func(childComplexity int, _, _ *time.Time, limit *int) int {
+		bars := defaultHistoryLimit
+		if limit != nil {
+			bars = min(max(*limit, 1), maxHistoryLimit)
+		}
+		return 1 + bars*childComplexity
+	}
+
+	srv := handler.New(NewExecutableSchema(cfg))
+	srv.AddTransport(transport.Websocket{KeepAlivePingInterval: 10 * time.Second})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.FixedComplexityLimit(complexityLimit))
+	return srv
+}
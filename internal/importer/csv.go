@@ -0,0 +1,194 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// dateTokens turns a layout's date format into a Go time layout
+var dateTokens = strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02")
+
+// ParseCSV reads a CSV export with the given layout. Rows come back oldest
+// first, whichever order the broker wrote them in, so same-day trades are
+// applied in the order they happened.
+func ParseCSV(r io.Reader, layout models.CSVLayout) ([]models.ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	if layout.Delimiter != "" {
+		reader.Comma = rune(layout.Delimiter[0])
+	}
+	dateLayout := dateTokens.Replace(layout.DateFormat)
+
+	var columns map[string]int
+	var rows []models.ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		if len(record) > 0 {
+			record[0] = strings.TrimPrefix(record[0], "\ufeff")
+		}
+
+		// Exports often start with a title or account line before the header
+		if columns == nil {
+			if slices.ContainsFunc(record, func(cell string) bool { return strings.EqualFold(strings.TrimSpace(cell), layout.Columns.Date) }) {
+				if columns, err = headerColumns(record, layout.Columns); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if !slices.ContainsFunc(record, func(cell string) bool { return strings.TrimSpace(cell) != "" }) {
+			continue
+		}
+
+		row := csvRow(record, columns, layout, dateLayout)
+		row.Row = line
+		rows = append(rows, row)
+	}
+	if columns == nil {
+		return nil, fmt.Errorf("no header row with a %q column", layout.Columns.Date)
+	}
+	assignFingerprints(rows)
+
+	// Brokers usually list the newest entries first
+	var dated []time.Time
+	for _, row := range rows {
+		if row.Transaction != nil {
+			dated = append(dated, row.Transaction.ExecutedAt)
+		}
+	}
+	if len(dated) > 1 && dated[0].After(dated[len(dated)-1]) {
+		slices.Reverse(rows)
+	}
+
+	return rows, nil
+}
+
+// headerColumns maps each column the layout names to its index in the header
+func headerColumns(header []string, c models.CSVColumns) (map[string]int, error) {
+	index := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := index[name]; !ok {
+			index[name] = i
+		}
+	}
+
+	columns := make(map[string]int)
+	named := append([]string{c.Date, c.Action, c.Symbol, c.Quantity, c.Price, c.Amount, c.SplitRatio, c.ID, c.Notes}, c.Fees...)
+	for _, name := range named {
+		if name == "" {
+			continue
+		}
+		i, ok := index[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("column %q not found in the header", name)
+		}
+		columns[strings.ToLower(name)] = i
+	}
+
+	return columns, nil
+}
+
+// csvRow converts one record
+func csvRow(record []string, columns map[string]int, layout models.CSVLayout, dateLayout string) models.ImportRow {
+	cell := func(name string) string {
+		i, ok := columns[strings.ToLower(name)]
+		if name == "" || !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	var numErr error
+	num := func(name string) float64 {
+		v, err := parseNumber(cell(name))
+		if err != nil && numErr == nil {
+			numErr = fmt.Errorf("%s: %w", name, err)
+		}
+		return v
+	}
+
+	action := cell(layout.Columns.Action)
+	if action == "" {
+		return models.ImportRow{Status: models.ImportRowSkipped, Error: "no action"}
+	}
+	kind := ""
+	for _, a := range layout.Actions {
+		if strings.Contains(strings.ToLower(action), strings.ToLower(a.Match)) {
+			kind = a.Type
+			break
+		}
+	}
+	switch kind {
+	case "":
+		return models.ImportRow{Status: models.ImportRowSkipped, Error: fmt.Sprintf("unrecognized action %q", action)}
+	case "skip":
+		return models.ImportRow{Status: models.ImportRowSkipped, Error: fmt.Sprintf("action %q is skipped by the layout", action)}
+	}
+
+	// Dates such as "01/15/2024 as of 01/12/2024" use the first date
+	date, _, _ := strings.Cut(cell(layout.Columns.Date), " as of ")
+	executedAt, err := time.Parse(dateLayout, strings.TrimSpace(date))
+	if err != nil {
+		return invalidRow(fmt.Sprintf("%s: invalid date %q, expected %s", layout.Columns.Date, date, layout.DateFormat))
+	}
+
+	t := models.Transaction{
+		Symbol:     strings.ToUpper(cell(layout.Columns.Symbol)),
+		ExternalID: cell(layout.Columns.ID),
+		Notes:      cell(layout.Columns.Notes),
+		ExecutedAt: executedAt,
+	}
+	quantity, price, amount := num(layout.Columns.Quantity), num(layout.Columns.Price), num(layout.Columns.Amount)
+	for _, name := range layout.Columns.Fees {
+		t.Fees += math.Abs(num(name))
+	}
+	ratio := num(layout.Columns.SplitRatio)
+	if numErr != nil {
+		return invalidRow(numErr.Error())
+	}
+
+	switch kind {
+	case "cash":
+		kind = models.TransactionDeposit
+		if amount < 0 {
+			kind = models.TransactionWithdrawal
+		}
+	case "trade":
+		kind = models.TransactionBuy
+		if quantity < 0 {
+			kind = models.TransactionSell
+		}
+	}
+	t.Type = kind
+
+	switch kind {
+	case models.TransactionBuy, models.TransactionSell, models.TransactionTransferIn, models.TransactionTransferOut:
+		t.Quantity, t.Price = math.Abs(quantity), math.Abs(price)
+	case models.TransactionSplit:
+		if ratio <= 0 {
+			return invalidRow("a split needs a split ratio column")
+		}
+		t.SplitRatio = &ratio
+	default:
+		t.TotalAmount = math.Abs(amount)
+	}
+
+	return newRow(t)
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"go-flow/internal/models"
+)
+
+var testLayout = models.CSVLayout{
+	Name:       "test",
+	DateFormat: "MM/DD/YYYY",
+	Columns: models.CSVColumns{
+		Date:     "Date",
+		Action:   "Action",
+		Symbol:   "Symbol",
+		Quantity: "Quantity",
+		Price:    "Price",
+		Fees:     []string{"Fees & Comm"},
+		Amount:   "Amount",
+	},
+	Actions: []models.CSVAction{
+		{Match: "Buy", Type: models.TransactionBuy},
+		{Match: "Sell", Type: models.TransactionSell},
+		{Match: "Transfer", Type: "cash"},
+		{Match: "Journal", Type: "skip"},
+	},
+}
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "title lines before the header and newest first",
+			data: `"Transactions for account XXXX-1234"
+"As of 02/01/2024"
+Date,Action,Symbol,Quantity,Price,Fees & Comm,Amount
+01/20/2024 as of 01/18/2024,Sell,AAPL,5,$190.00,$1.00,$949.00
+01/15/2024,Buy,aapl,10,$185.50,,"($1,855.00)"
+01/02/2024,MoneyLink Transfer,,,,,"$5,000.00"
+`,
+			want: []string{
+				"6 new deposit  0@0 fees 0 amount 5000 id fingerprint at 2024-01-02T00:00:00Z",
+				"5 new buy AAPL 10@185.5 fees 0 amount 0 id fingerprint at 2024-01-15T00:00:00Z",
+				"4 new sell AAPL 5@190 fees 1 amount 0 id fingerprint at 2024-01-20T00:00:00Z",
+			},
+		},
+		{
+			name: "oldest first is kept",
+			data: "\ufeffDate,Action,Symbol,Quantity,Price,Fees & Comm,Amount\n" +
+				"01/02/2024,Transfer,,,,,-100\n" +
+				"\n" +
+				"01/03/2024,Journal,,,,,5\n" +
+				"01/04/2024,Dividend,AAPL,,,,2\n" +
+				"01/05/2024,Buy,MSFT,1,abc,,\n" +
+				"2024-01-06,Buy,MSFT,1,10,,\n",
+			want: []string{
+				"2 new withdrawal  0@0 fees 0 amount 100 id fingerprint at 2024-01-02T00:00:00Z",
+				`4 skipped: action "Journal" is skipped by the layout`,
+				`5 skipped: unrecognized action "Dividend"`,
+				`6 invalid: Price: invalid number "abc"`,
+				`7 invalid: Date: invalid date "2024-01-06", expected MM/DD/YYYY`,
+			},
+		},
+		{
+			name:    "no header",
+			data:    "Trade Date,Action\n01/02/2024,Buy\n",
+			wantErr: `no header row with a "Date" column`,
+		},
+		{
+			name:    "header missing a column",
+			data:    "Date,Action,Symbol\n01/02/2024,Buy,AAPL\n",
+			wantErr: `column "Quantity" not found in the header`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseCSV(strings.NewReader(tt.data), testLayout)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := summarize(rows); !slices.Equal(got, tt.want) {
+				t.Errorf("rows =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Package importer reads broker statements into ledger transactions.
+//
+// Parsers turn a statement into rows, each holding the transaction it
+// becomes or why it is left out. Whether a row is a duplicate of one already
+// imported is decided when the rows are applied to a portfolio.
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go-flow/internal/models"
+)
+
+// DetectFormat returns the statement format implied by a file name, falling
+// back to the content: OFX files carry an OFX header or element
+func DetectFormat(filename string, data []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ofx", ".qfx":
+		return models.ImportFormatOFX
+	case ".csv", ".txt":
+		return models.ImportFormatCSV
+	}
+
+	head := strings.ToUpper(string(data[:min(len(data), 1024)]))
+	if strings.Contains(head, "OFXHEADER") || strings.Contains(head, "<OFX>") {
+		return models.ImportFormatOFX
+	}
+	return models.ImportFormatCSV
+}
+
+// newRow returns a row that will be imported unless it turns out to be a
+// duplicate
+func newRow(t models.Transaction) models.ImportRow {
+	return models.ImportRow{Status: models.ImportRowNew, Transaction: &t}
+}
+
+func invalidRow(message string) models.ImportRow {
+	return models.ImportRow{Status: models.ImportRowInvalid, Error: message}
+}
+
+// parseNumber reads amounts as brokers write them: with currency signs,
+// thousands separators and negative amounts in parentheses. An empty field
+// is zero.
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	s = strings.NewReplacer("$", "", ",", "", "(", "", ")", "", " ", "", "+", "").Replace(s)
+	if s == "" || s == "-" || s == "--" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
+
+// assignFingerprints gives every new row without a broker transaction ID
+// its content fingerprint, so statements that carry no IDs can still be
+// imported again without duplicating what is already there
+func assignFingerprints(rows []models.ImportRow) {
+	occurrences := make(map[string]int)
+	for i := range rows {
+		t := rows[i].Transaction
+		if rows[i].Status != models.ImportRowNew || t.ExternalID != "" {
+			continue
+		}
+		key := fingerprint(*t, 0)
+		t.ExternalID = fingerprint(*t, occurrences[key])
+		occurrences[key]++
+	}
+}
+
+// fingerprint identifies a row that has no broker transaction ID by its
+// content. occurrence tells apart identical rows in the same statement, so
+// two equal trades on one day are both imported once.
+func fingerprint(t models.Transaction, occurrence int) string {
+	key := fmt.Sprintf("%s|%s|%s|%g|%g|%g|%g|%d",
+		t.ExecutedAt.Format("2006-01-02"), t.Type, t.Symbol, t.Quantity, t.Price, t.Fees, t.TotalAmount, occurrence)
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,91 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"1234.5", 1234.5, false},
+		{"1,234.50", 1234.5, false},
+		{"$1,234.50", 1234.5, false},
+		{"($1,234.50)", -1234.5, false},
+		{"(5)", -5, false},
+		{"-3.25", -3.25, false},
+		{"+2", 2, false},
+		{" $ 12 ", 12, false},
+		{"", 0, false},
+		{"--", 0, false},
+		{"12 USD", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseNumber(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseNumber(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseNumber(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignFingerprints(t *testing.T) {
+	date := time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)
+	buy := models.Transaction{Type: models.TransactionBuy, Symbol: "AAPL", Quantity: 10, Price: 100, ExecutedAt: date}
+	sell := models.Transaction{Type: models.TransactionSell, Symbol: "AAPL", Quantity: 10, Price: 110, ExecutedAt: date}
+	withID := buy
+	withID.ExternalID = "broker-1"
+
+	rows := []models.ImportRow{
+		newRow(buy),
+		newRow(withID),
+		{Status: models.ImportRowSkipped, Error: "not supported"},
+		newRow(buy),
+		newRow(sell),
+		{Status: models.ImportRowInvalid, Transaction: &models.Transaction{}},
+		newRow(buy),
+	}
+	assignFingerprints(rows)
+
+	want := []string{
+		fingerprint(buy, 0),
+		"broker-1",
+		"",
+		fingerprint(buy, 1),
+		fingerprint(sell, 0),
+		"",
+		fingerprint(buy, 2),
+	}
+	seen := make(map[string]bool)
+	for i, row := range rows {
+		got := ""
+		if row.Transaction != nil {
+			got = row.Transaction.ExternalID
+		}
+		if got != want[i] {
+			t.Errorf("row %d external ID = %q, want %q", i, got, want[i])
+		}
+		if got != "" && seen[got] {
+			t.Errorf("row %d external ID %q is not unique", i, got)
+		}
+		seen[got] = true
+	}
+
+	// The same statement fingerprints the same way when imported again
+	again := []models.ImportRow{newRow(buy), newRow(buy)}
+	assignFingerprints(again)
+	if again[0].Transaction.ExternalID != rows[0].Transaction.ExternalID || again[1].Transaction.ExternalID != rows[3].Transaction.ExternalID {
+		t.Errorf("fingerprints changed between imports: %q, %q", again[0].Transaction.ExternalID, again[1].Transaction.ExternalID)
+	}
+}
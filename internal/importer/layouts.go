@@ -0,0 +1,129 @@
+package importer
+
+import (
+	"slices"
+	"strings"
+
+	"go-flow/internal/models"
+)
+
+// layouts are the built-in CSV layouts of common brokers' exports
+var layouts = []models.CSVLayout{
+	{
+		Name:        "generic",
+		Description: "One row per ledger transaction with the API's own field names",
+		DateFormat:  "YYYY-MM-DD",
+		Columns: models.CSVColumns{
+			Date:       "date",
+			Action:     "type",
+			Symbol:     "symbol",
+			Quantity:   "quantity",
+			Price:      "price",
+			Fees:       []string{"fees"},
+			Amount:     "amount",
+			SplitRatio: "split_ratio",
+			ID:         "id",
+			Notes:      "notes",
+		},
+		Actions: []models.CSVAction{
+			{Match: "transfer_in", Type: models.TransactionTransferIn},
+			{Match: "transfer_out", Type: models.TransactionTransferOut},
+			{Match: "buy", Type: models.TransactionBuy},
+			{Match: "sell", Type: models.TransactionSell},
+			{Match: "dividend", Type: models.TransactionDividend},
+			{Match: "split", Type: models.TransactionSplit},
+			{Match: "fee", Type: models.TransactionFee},
+			{Match: "deposit", Type: models.TransactionDeposit},
+			{Match: "withdrawal", Type: models.TransactionWithdrawal},
+		},
+	},
+	{
+		Name:        "schwab",
+		Description: "Charles Schwab brokerage transaction history",
+		DateFormat:  "MM/DD/YYYY",
+		Columns: models.CSVColumns{
+			Date:     "Date",
+			Action:   "Action",
+			Symbol:   "Symbol",
+			Quantity: "Quantity",
+			Price:    "Price",
+			Fees:     []string{"Fees & Comm"},
+			Amount:   "Amount",
+			Notes:    "Description",
+		},
+		Actions: []models.CSVAction{
+			{Match: "Reinvest Shares", Type: models.TransactionBuy},
+			{Match: "Buy", Type: models.TransactionBuy},
+			{Match: "Sell", Type: models.TransactionSell},
+			{Match: "Div", Type: models.TransactionDividend},
+			{Match: "Fee", Type: models.TransactionFee},
+			{Match: "Foreign Tax", Type: models.TransactionFee},
+			{Match: "MoneyLink", Type: "cash"},
+			{Match: "Funds", Type: "cash"},
+			{Match: "Journal", Type: "cash"},
+			{Match: "Security Transfer", Type: "skip"},
+			{Match: "Stock Split", Type: "skip"},
+			{Match: "Interest", Type: "skip"},
+		},
+	},
+	{
+		Name:        "fidelity",
+		Description: "Fidelity account history",
+		DateFormat:  "MM/DD/YYYY",
+		Columns: models.CSVColumns{
+			Date:     "Run Date",
+			Action:   "Action",
+			Symbol:   "Symbol",
+			Quantity: "Quantity",
+			Price:    "Price ($)",
+			Fees:     []string{"Commission ($)", "Fees ($)"},
+			Amount:   "Amount ($)",
+			Notes:    "Security Description",
+		},
+		Actions: []models.CSVAction{
+			{Match: "YOU BOUGHT", Type: models.TransactionBuy},
+			{Match: "REINVESTMENT", Type: models.TransactionBuy},
+			{Match: "YOU SOLD", Type: models.TransactionSell},
+			{Match: "DIVIDEND RECEIVED", Type: models.TransactionDividend},
+			{Match: "FEE CHARGED", Type: models.TransactionFee},
+			{Match: "FOREIGN TAX", Type: models.TransactionFee},
+			{Match: "Electronic Funds Transfer", Type: "cash"},
+			{Match: "TRANSFERRED FROM", Type: "cash"},
+			{Match: "TRANSFERRED TO", Type: "cash"},
+			{Match: "CONTRIBUTION", Type: "cash"},
+			{Match: "INTEREST", Type: "skip"},
+		},
+	},
+	{
+		Name:        "ibkr",
+		Description: "Interactive Brokers Flex Query trades with TradeID, TradeDate and signed Quantity",
+		DateFormat:  "YYYYMMDD",
+		Columns: models.CSVColumns{
+			Date:     "TradeDate",
+			Action:   "Buy/Sell",
+			Symbol:   "Symbol",
+			Quantity: "Quantity",
+			Price:    "TradePrice",
+			Fees:     []string{"IBCommission"},
+			ID:       "TradeID",
+		},
+		Actions: []models.CSVAction{
+			{Match: "BUY", Type: models.TransactionBuy},
+			{Match: "SELL", Type: models.TransactionSell},
+		},
+	},
+}
+
+// Layouts returns the built-in CSV layouts
+func Layouts() []models.CSVLayout {
+	return slices.Clone(layouts)
+}
+
+// Layout returns the built-in CSV layout with the given name
+func Layout(name string) (models.CSVLayout, bool) {
+	i := slices.IndexFunc(layouts, func(l models.CSVLayout) bool { return strings.EqualFold(l.Name, name) })
+	if i < 0 {
+		return models.CSVLayout{}, false
+	}
+	return layouts[i], true
+}
@@ -0,0 +1,365 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// ErrNotOFX is returned when a file has no OFX document in it
+var ErrNotOFX = errors.New("no <OFX> element found")
+
+// node is an OFX element: an aggregate with children or a leaf with a value
+type node struct {
+	name     string
+	value    string
+	children []*node
+}
+
+// child returns the value of the first descendant at path, or ""
+func (n *node) child(path ...string) string {
+	if c := n.find(path...); c != nil {
+		return c.value
+	}
+	return ""
+}
+
+// find returns the first element at path below n
+func (n *node) find(path ...string) *node {
+	current := n
+	for _, name := range path {
+		var next *node
+		for _, c := range current.children {
+			if c.name == name {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// walk calls fn for n and every element below it, depth first
+func (n *node) walk(fn func(*node)) {
+	fn(n)
+	for _, c := range n.children {
+		c.walk(fn)
+	}
+}
+
+// ofxLeaves are leaf elements that statements are known to leave empty. In
+// SGML an empty leaf looks just like an aggregate opening.
+var ofxLeaves = map[string]bool{
+	"MEMO": true, "NAME": true, "PAYEE": true, "CHECKNUM": true, "REFNUM": true,
+	"FITID": true, "TICKER": true, "SECNAME": true, "DTSETTLE": true, "DTUSER": true,
+}
+
+// closedTags returns the names of every element the document closes. SGML
+// leaves are never closed, so an empty element whose name is not among them
+// is a leaf rather than an aggregate.
+func closedTags(data string) map[string]bool {
+	closed := make(map[string]bool)
+	for {
+		i := strings.Index(data, "</")
+		if i < 0 {
+			return closed
+		}
+		data = data[i+2:]
+		end := strings.IndexByte(data, '>')
+		if end < 0 {
+			return closed
+		}
+		closed[strings.ToUpper(strings.TrimSpace(data[:end]))] = true
+		data = data[end+1:]
+	}
+}
+
+// parseOFXTree reads both OFX 1.x SGML, where leaf elements are not
+// closed, and OFX 2.x XML. Everything before the <OFX> element, the headers
+// of either version, is ignored.
+func parseOFXTree(data string) (*node, error) {
+	start := strings.Index(strings.ToUpper(data), "<OFX>")
+	if start < 0 {
+		return nil, ErrNotOFX
+	}
+	data = data[start:]
+	closed := closedTags(data)
+
+	root := &node{}
+	stack := []*node{root}
+	var leaf *node // the last leaf opened, which an XML closing tag may close
+
+	for len(data) > 0 {
+		open := strings.IndexByte(data, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(data[open:], '>')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated tag at %q", truncate(data[open:], 20))
+		}
+		tag := strings.TrimSpace(data[open+1 : open+end])
+		data = data[open+end+1:]
+
+		next := strings.IndexByte(data, '<')
+		if next < 0 {
+			next = len(data)
+		}
+		text := strings.TrimSpace(data[:next])
+
+		switch {
+		case tag == "" || strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!"):
+			continue
+		case strings.HasPrefix(tag, "/"):
+			name := strings.ToUpper(strings.TrimPrefix(tag, "/"))
+			if leaf != nil && leaf.name == name {
+				leaf = nil
+				continue
+			}
+			leaf = nil
+			// SGML allows aggregates to close several levels at once
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		default:
+			empty := strings.HasSuffix(tag, "/")
+			name := strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(tag, "/")))
+			n := &node{name: name}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+			switch {
+			case text != "":
+				n.value = unescape(text)
+				leaf = n
+			case empty:
+				leaf = nil
+			case ofxLeaves[name] || !closed[name]:
+				// An empty leaf, which an XML closing tag may still close
+				leaf = n
+			default:
+				stack = append(stack, n)
+				leaf = nil
+			}
+		}
+	}
+
+	ofx := root.find("OFX")
+	if ofx == nil {
+		return nil, ErrNotOFX
+	}
+	return ofx, nil
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ").Replace(s)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// ParseOFX reads the transactions of an OFX or QFX investment or bank
+// statement. Securities are identified by the ticker in the statement's
+// security list, and entries without a FITID by their content. Transaction
+// kinds the ledger has no counterpart for, such as option trades or margin
+// interest, are returned as skipped rows.
+func ParseOFX(r io.Reader) ([]models.ImportRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statement: %w", err)
+	}
+	ofx, err := parseOFXTree(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	tickers := make(map[string]string)
+	ofx.walk(func(n *node) {
+		if n.name != "SECINFO" {
+			return
+		}
+		if id, ticker := n.child("SECID", "UNIQUEID"), n.child("TICKER"); id != "" && ticker != "" {
+			tickers[id] = strings.ToUpper(ticker)
+		}
+	})
+
+	var rows []models.ImportRow
+	add := func(row models.ImportRow) {
+		row.Row = len(rows) + 1
+		rows = append(rows, row)
+	}
+
+	ofx.walk(func(n *node) {
+		switch n.name {
+		case "INVTRANLIST":
+			for _, c := range n.children {
+				if c.name == "DTSTART" || c.name == "DTEND" {
+					continue
+				}
+				for _, row := range investmentRows(c, tickers) {
+					add(row)
+				}
+			}
+		case "BANKTRANLIST":
+			for _, c := range n.children {
+				if c.name == "STMTTRN" {
+					add(bankRow(c))
+				}
+			}
+		}
+	})
+	assignFingerprints(rows)
+
+	return rows, nil
+}
+
+// investmentRows converts one entry of an investment transaction list
+func investmentRows(n *node, tickers map[string]string) []models.ImportRow {
+	if n.name == "INVBANKTRAN" {
+		if stmt := n.find("STMTTRN"); stmt != nil {
+			return []models.ImportRow{bankRow(stmt)}
+		}
+		return []models.ImportRow{invalidRow("INVBANKTRAN without STMTTRN")}
+	}
+
+	// Buys and sells wrap their details in INVBUY or INVSELL
+	body := n
+	for _, wrapper := range []string{"INVBUY", "INVSELL"} {
+		if w := n.find(wrapper); w != nil {
+			body = w
+		}
+	}
+
+	t := models.Transaction{ExternalID: body.child("INVTRAN", "FITID")}
+	t.Notes = body.child("INVTRAN", "MEMO")
+	executedAt, err := parseOFXDate(body.child("INVTRAN", "DTTRADE"))
+	if err != nil {
+		return []models.ImportRow{invalidRow("DTTRADE: " + err.Error())}
+	}
+	t.ExecutedAt = executedAt
+
+	if id := body.child("SECID", "UNIQUEID"); id != "" {
+		t.Symbol = tickers[id]
+		if t.Symbol == "" {
+			return []models.ImportRow{invalidRow("security " + id + " has no ticker in the security list")}
+		}
+	}
+
+	num := func(name string) float64 {
+		v, _ := parseNumber(body.child(name))
+		return v
+	}
+	units, price, total := math.Abs(num("UNITS")), num("UNITPRICE"), math.Abs(num("TOTAL"))
+	fees := math.Abs(num("COMMISSION")) + math.Abs(num("FEES")) + math.Abs(num("TAXES"))
+
+	switch n.name {
+	case "BUYSTOCK", "BUYMF", "BUYOTHER", "BUYDEBT":
+		t.Type, t.Quantity, t.Price, t.Fees = models.TransactionBuy, units, price, fees
+	case "SELLSTOCK", "SELLMF", "SELLOTHER", "SELLDEBT":
+		t.Type, t.Quantity, t.Price, t.Fees = models.TransactionSell, units, price, fees
+	case "INCOME":
+		t.Type, t.TotalAmount = models.TransactionDividend, total
+	case "INVEXPENSE":
+		t.Type, t.TotalAmount = models.TransactionFee, total
+	case "SPLIT":
+		numerator, denominator := num("NUMERATOR"), num("DENOMINATOR")
+		if numerator <= 0 || denominator <= 0 {
+			return []models.ImportRow{invalidRow("split without a numerator and denominator")}
+		}
+		ratio := numerator / denominator
+		t.Type, t.SplitRatio = models.TransactionSplit, &ratio
+	case "TRANSFER":
+		t.Type, t.Quantity, t.Price = models.TransactionTransferIn, units, num("AVGCOSTBASIS")
+		if strings.EqualFold(body.child("TFERACTION"), "OUT") {
+			t.Type, t.Price = models.TransactionTransferOut, 0
+		}
+	case "REINVEST":
+		// A reinvested dividend is income immediately spent on shares
+		dividend := t
+		dividend.Type, dividend.TotalAmount = models.TransactionDividend, total
+		t.Type, t.Quantity, t.Price, t.Fees = models.TransactionBuy, units, price, fees
+		if t.ExternalID != "" {
+			dividend.ExternalID = t.ExternalID + ":income"
+			t.ExternalID += ":buy"
+		}
+		return []models.ImportRow{newRow(dividend), newRow(t)}
+	default:
+		return []models.ImportRow{{Status: models.ImportRowSkipped, Error: n.name + " transactions are not supported"}}
+	}
+
+	return []models.ImportRow{newRow(t)}
+}
+
+// bankRow converts a cash transaction: deposits and withdrawals by sign,
+// and fees. Interest and other cash income have no ledger type and are
+// skipped.
+func bankRow(n *node) models.ImportRow {
+	executedAt, err := parseOFXDate(n.child("DTPOSTED"))
+	if err != nil {
+		return invalidRow("DTPOSTED: " + err.Error())
+	}
+	amount, err := parseNumber(n.child("TRNAMT"))
+	if err != nil {
+		return invalidRow("TRNAMT: " + err.Error())
+	}
+
+	t := models.Transaction{
+		ExternalID:  n.child("FITID"),
+		TotalAmount: math.Abs(amount),
+		Notes:       strings.TrimSpace(n.child("NAME") + " " + n.child("MEMO")),
+		ExecutedAt:  executedAt,
+	}
+
+	switch kind := strings.ToUpper(n.child("TRNTYPE")); {
+	case kind == "FEE" || kind == "SRVCHG":
+		t.Type = models.TransactionFee
+	case kind == "INT" || kind == "DIV":
+		return models.ImportRow{Status: models.ImportRowSkipped, Transaction: &t, Error: "cash income without a security is not supported"}
+	case amount >= 0:
+		t.Type = models.TransactionDeposit
+	default:
+		t.Type = models.TransactionWithdrawal
+	}
+
+	return newRow(t)
+}
+
+// parseOFXDate reads YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]], in UTC unless the
+// offset says otherwise
+func parseOFXDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	zone := time.UTC
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		offset, _, _ := strings.Cut(strings.Trim(s[i:], "[]"), ":")
+		if hours, err := strconv.ParseFloat(offset, 64); err == nil {
+			zone = time.FixedZone("", int(hours*3600))
+		}
+		s = s[:i]
+	}
+	s, _, _ = strings.Cut(s, ".")
+
+	switch {
+	case len(s) >= 14:
+		return time.ParseInLocation("20060102150405", s[:14], zone)
+	case len(s) >= 8:
+		return time.ParseInLocation("20060102", s[:8], zone)
+	default:
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+}
@@ -0,0 +1,205 @@
+package importer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// summarize renders rows compactly for comparison; content fingerprints are
+// shown as "fingerprint"
+func summarize(rows []models.ImportRow) []string {
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		t := row.Transaction
+		if t == nil {
+			out[i] = fmt.Sprintf("%d %s: %s", row.Row, row.Status, row.Error)
+			continue
+		}
+		id := t.ExternalID
+		if strings.HasPrefix(id, "sha256:") {
+			id = "fingerprint"
+		}
+		out[i] = fmt.Sprintf("%d %s %s %s %g@%g fees %g amount %g id %s at %s",
+			row.Row, row.Status, t.Type, t.Symbol, t.Quantity, t.Price, t.Fees, t.TotalAmount, id, t.ExecutedAt.UTC().Format(time.RFC3339))
+	}
+	return out
+}
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+
+<OFX>
+<INVSTMTMSGSRSV1><INVSTMTTRNRS><INVSTMTRS>
+<INVTRANLIST>
+<DTSTART>20240101
+<DTEND>20240131
+<BUYSTOCK>
+<INVBUY>
+<INVTRAN>
+<FITID>1001
+<SRVRTID>
+<MEMO>
+<DTTRADE>20240115093000.000[-5:EST]
+</INVTRAN>
+<SECID><UNIQUEID>037833100<UNIQUEIDTYPE>CUSIP</SECID>
+<UNITS>10
+<UNITPRICE>185.50
+<COMMISSION>1.00
+<TOTAL>-1856.00
+</INVBUY>
+<BUYTYPE>BUY
+</BUYSTOCK>
+<INCOME>
+<INVTRAN><FITID>1002<DTTRADE>20240120</INVTRAN>
+<SECID><UNIQUEID>037833100<UNIQUEIDTYPE>CUSIP</SECID>
+<INCOMETYPE>DIV
+<TOTAL>24.00
+</INCOME>
+<INVBANKTRAN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240102<MEMO><TRNAMT>5000.00<NAME>Deposit</STMTTRN>
+<SUBACCTFUND>CASH
+</INVBANKTRAN>
+<BUYOPT>
+<INVBUY><INVTRAN><FITID>1003<DTTRADE>20240125</INVTRAN></INVBUY>
+</BUYOPT>
+</INVTRANLIST>
+</INVSTMTRS></INVSTMTTRNRS></INVSTMTMSGSRSV1>
+<SECLISTMSGSRSV1><SECLIST>
+<STOCKINFO><SECINFO><SECID><UNIQUEID>037833100<UNIQUEIDTYPE>CUSIP</SECID><SECNAME>Apple Inc<TICKER>aapl</SECINFO></STOCKINFO>
+</SECLIST></SECLISTMSGSRSV1>
+</OFX>
+`
+
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE"?>
+<OFX>
+  <BANKMSGSRSV1><STMTTRNRS><STMTRS>
+    <BANKTRANLIST>
+      <DTSTART>20240101</DTSTART>
+      <DTEND>20240131</DTEND>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20240105</DTPOSTED>
+        <TRNAMT>-250.00</TRNAMT>
+        <FITID>B1</FITID>
+        <NAME>Tom &amp; Jerry</NAME>
+        <MEMO></MEMO>
+      </STMTTRN>
+      <STMTTRN>
+        <TRNTYPE>SRVCHG</TRNTYPE>
+        <DTPOSTED>20240131120000[+1:CET]</DTPOSTED>
+        <MEMO/>
+        <TRNAMT>-5.00</TRNAMT>
+        <FITID>B2</FITID>
+      </STMTTRN>
+      <STMTTRN>
+        <TRNTYPE>INT</TRNTYPE>
+        <DTPOSTED>20240131</DTPOSTED>
+        <TRNAMT>0.42</TRNAMT>
+        <FITID>B3</FITID>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFX(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "sgml investment statement",
+			data: sgmlStatement,
+			want: []string{
+				"1 new buy AAPL 10@185.5 fees 1 amount 0 id 1001 at 2024-01-15T14:30:00Z",
+				"2 new dividend AAPL 0@0 fees 0 amount 24 id 1002 at 2024-01-20T00:00:00Z",
+				"3 new deposit  0@0 fees 0 amount 5000 id fingerprint at 2024-01-02T00:00:00Z",
+				"4 skipped: BUYOPT transactions are not supported",
+			},
+		},
+		{
+			name: "xml bank statement",
+			data: xmlStatement,
+			want: []string{
+				"1 new withdrawal  0@0 fees 0 amount 250 id B1 at 2024-01-05T00:00:00Z",
+				"2 new fee  0@0 fees 0 amount 5 id B2 at 2024-01-31T11:00:00Z",
+				"3 skipped   0@0 fees 0 amount 0.42 id B3 at 2024-01-31T00:00:00Z",
+			},
+		},
+		{
+			name: "sgml security without a ticker",
+			data: `<OFX><INVTRANLIST><SELLSTOCK><INVSELL><INVTRAN><FITID>9<DTTRADE>20240110</INVTRAN>
+<SECID><UNIQUEID>XYZ<UNIQUEIDTYPE>CUSIP</SECID><UNITS>-5<UNITPRICE>10</INVSELL></SELLSTOCK></INVTRANLIST></OFX>`,
+			want: []string{"1 invalid: security XYZ has no ticker in the security list"},
+		},
+		{
+			name:    "no ofx element",
+			data:    "Date,Action\n01/02/2024,Buy\n",
+			wantErr: ErrNotOFX,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseOFX(strings.NewReader(tt.data))
+			if err != tt.wantErr {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if got := summarize(rows); !slices.Equal(got, tt.want) {
+				t.Errorf("rows =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseOFXNotes(t *testing.T) {
+	rows, err := ParseOFX(strings.NewReader(xmlStatement))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if got := rows[0].Transaction.Notes; got != "Tom & Jerry" {
+		t.Errorf("notes = %q, want %q", got, "Tom & Jerry")
+	}
+}
+
+func TestParseOFXDate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"20240115", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), false},
+		{"20240115093000", time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC), false},
+		{"20240115093000.123", time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC), false},
+		{"20240115093000.000[-5:EST]", time.Date(2024, 1, 15, 14, 30, 0, 0, time.UTC), false},
+		{"20240115[-5:EST]", time.Date(2024, 1, 15, 5, 0, 0, 0, time.UTC), false},
+		{"20240115120000[+5.5:IST]", time.Date(2024, 1, 15, 6, 30, 0, 0, time.UTC), false},
+		{"20240115120000[0:GMT]", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), false},
+		{"20240115120000[EST]", time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), false},
+		{" 20240115 ", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), false},
+		{"2024", time.Time{}, true},
+		{"20241315", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseOFXDate(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOFXDate(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseOFXDate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// CompareRequest selects the benchmarks and date range of a comparison.
+// Symbol names the subject when comparing a stock rather than a portfolio;
+// benchmarks is a comma separated list.
+type CompareRequest struct {
+	Symbol       string   `json:"symbol" form:"symbol" validate:"omitempty,max=10"`
+	Benchmarks   string   `json:"benchmarks" form:"benchmarks" validate:"omitempty,max=200"`
+	Start        string   `json:"start" form:"start" validate:"omitempty,datetime=2006-01-02"`
+	End          string   `json:"end" form:"end" validate:"omitempty,datetime=2006-01-02"`
+	RiskFreeRate *float64 `json:"risk_free_rate" form:"risk_free_rate" validate:"omitempty,gte=0,lte=50"`
+}
+
+// ComparisonPoint is a series' cumulative return since the first common date,
+// in percent
+type ComparisonPoint struct {
+	Date             time.Time `json:"date"`
+	CumulativeReturn float64   `json:"cumulative_return"`
+}
+
+// ComparisonSeries is one member of a comparison, normalized to start at zero
+type ComparisonSeries struct {
+	Name        string            `json:"name"`
+	TotalReturn float64           `json:"total_return"`
+	Points      []ComparisonPoint `json:"points"`
+}
+
+// ComparisonStats measures the subject against one benchmark over the common
+// dates. Alpha (Jensen's), tracking error and excess return are annualized
+// percentages except excess return, which is over the whole range.
+type ComparisonStats struct {
+	Benchmark        string   `json:"benchmark"`
+	Observations     int      `json:"observations"`
+	Beta             *float64 `json:"beta"`
+	Alpha            *float64 `json:"alpha"`
+	TrackingError    *float64 `json:"tracking_error"`
+	InformationRatio *float64 `json:"information_ratio"`
+	ExcessReturn     *float64 `json:"excess_return"`
+}
+
+// ComparisonResponse holds the normalized series of the subject and its
+// benchmarks over the dates they all share
+type ComparisonResponse struct {
+	Subject    string             `json:"subject"`
+	Start      *time.Time         `json:"start"`
+	End        *time.Time         `json:"end"`
+	Series     []ComparisonSeries `json:"series"`
+	Statistics []ComparisonStats  `json:"statistics"`
+}
+
+// CorrelationRequest selects the symbols and how many trading days of daily
+// returns to correlate
+type CorrelationRequest struct {
+	Symbols  []string `json:"symbols" validate:"required,min=2,max=50,dive,required,max=10"`
+	Lookback int      `json:"lookback" validate:"omitempty,min=20,max=2520"`
+}
+
+// CorrelationResponse holds the correlation and covariance matrices of daily
+// returns, indexed in the order of Symbols. Correlations are nil where a
+// symbol's returns are constant. FilledDays counts the calendar days each
+// symbol had no bar and carried its previous close forward.
+type CorrelationResponse struct {
+	Symbols      []string       `json:"symbols"`
+	Lookback     int            `json:"lookback"`
+	Start        *time.Time     `json:"start"`
+	End          *time.Time     `json:"end"`
+	Observations int            `json:"observations"`
+	FilledDays   map[string]int `json:"filled_days"`
+	Pearson      [][]*float64   `json:"pearson"`
+	Spearman     [][]*float64   `json:"spearman"`
+	Covariance   [][]float64    `json:"covariance"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// BacktestRequest runs a built-in strategy over a symbol's stored history.
+// Slippage is in basis points of the fill price; commission is charged per
+// fill plus commission_percent of the traded value.
+type BacktestRequest struct {
+	Strategy          string             `json:"strategy" validate:"required,max=50"`
+	Symbol            string             `json:"symbol" validate:"required,max=10"`
+	Params            map[string]float64 `json:"params"`
+	Start             string             `json:"start" validate:"omitempty,datetime=2006-01-02"`
+	End               string             `json:"end" validate:"omitempty,datetime=2006-01-02"`
+	InitialCapital    float64            `json:"initial_capital" validate:"omitempty,gt=0"`
+	SlippageBps       float64            `json:"slippage_bps" validate:"gte=0,lte=1000"`
+	Commission        float64            `json:"commission" validate:"gte=0"`
+	CommissionPercent float64            `json:"commission_percent" validate:"gte=0,lte=10"`
+}
+
+// BacktestParam describes one parameter of a strategy
+type BacktestParam struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Default     float64 `json:"default"`
+	Min         float64 `json:"min"`
+	Max         float64 `json:"max"`
+	Integer     bool    `json:"integer"`
+}
+
+// BacktestStrategyInfo describes a strategy that can be run by name
+type BacktestStrategyInfo struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Params      []BacktestParam `json:"params"`
+}
+
+// BacktestTrade is a round trip from flat to flat. Trades still open at the
+// end are valued at the last close and have no exit.
+type BacktestTrade struct {
+	EntryDate     time.Time  `json:"entry_date"`
+	EntryPrice    float64    `json:"entry_price"`
+	ExitDate      *time.Time `json:"exit_date"`
+	ExitPrice     *float64   `json:"exit_price"`
+	Quantity      float64    `json:"quantity"`
+	Commission    float64    `json:"commission"`
+	ProfitLoss    float64    `json:"profit_loss"`
+	ReturnPercent float64    `json:"return_percent"`
+	BarsHeld      int        `json:"bars_held"`
+	Open          bool       `json:"open"`
+}
+
+// EquityPoint is the account at one bar's close
+type EquityPoint struct {
+	Date     time.Time `json:"date"`
+	Equity   float64   `json:"equity"`
+	Cash     float64   `json:"cash"`
+	Position float64   `json:"position"`
+}
+
+// BacktestMetrics summarizes a backtest. Returns, drawdown, exposure and win
+// rate are percentages; CAGR and Sharpe are annualized.
+type BacktestMetrics struct {
+	InitialCapital float64    `json:"initial_capital"`
+	FinalEquity    float64    `json:"final_equity"`
+	TotalReturn    float64    `json:"total_return"`
+	CAGR           *float64   `json:"cagr"`
+	Sharpe         *float64   `json:"sharpe"`
+	MaxDrawdown    float64    `json:"max_drawdown"`
+	DrawdownPeak   *time.Time `json:"drawdown_peak"`
+	DrawdownTrough *time.Time `json:"drawdown_trough"`
+	Exposure       float64    `json:"exposure"`
+	Trades         int        `json:"trades"`
+	WinRate        *float64   `json:"win_rate"`
+	Commissions    float64    `json:"commissions"`
+}
+
+// BacktestResult is the outcome of running a strategy over history
+type BacktestResult struct {
+	Strategy string             `json:"strategy"`
+	Symbol   string             `json:"symbol"`
+	Params   map[string]float64 `json:"params"`
+	Start    *time.Time         `json:"start"`
+	End      *time.Time         `json:"end"`
+	Metrics  BacktestMetrics    `json:"metrics"`
+	Trades   []BacktestTrade    `json:"trades"`
+	Equity   []EquityPoint      `json:"equity"`
+}
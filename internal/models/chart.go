@@ -0,0 +1,15 @@
+package models
+
+// ChartRequest configures a rendered candlestick chart. Start and End
+// override Range; SMA is a comma separated list of moving average periods.
+type ChartRequest struct {
+	Width     int    `json:"width" form:"width" validate:"omitempty,min=200,max=2000"`
+	Height    int    `json:"height" form:"height" validate:"omitempty,min=150,max=1200"`
+	Range     string `json:"range" form:"range" validate:"omitempty,oneof=1m 3m 6m ytd 1y 2y 5y"`
+	Start     string `json:"start" form:"start" validate:"omitempty,datetime=2006-01-02"`
+	End       string `json:"end" form:"end" validate:"omitempty,datetime=2006-01-02"`
+	Theme     string `json:"theme" form:"theme" validate:"omitempty,oneof=light dark"`
+	SMA       string `json:"sma" form:"sma" validate:"omitempty,max=50"`
+	Bollinger bool   `json:"bollinger" form:"bollinger"`
+	Volume    *bool  `json:"volume" form:"volume"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SelectFields returns the JSON fields of a struct limited to the requested
+// names. Requested fields are always present, even when their value is zero.
+func SelectFields(v any, fields []string) map[string]any {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	typ := value.Type()
+
+	wanted := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		wanted[field] = true
+	}
+
+	selected := make(map[string]any, len(fields))
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if wanted[name] {
+			selected[name] = value.Field(i).Interface()
+		}
+	}
+	return selected
+}
@@ -0,0 +1,107 @@
+package models
+
+import "time"
+
+// Statement formats that can be imported
+const (
+	ImportFormatOFX = "ofx"
+	ImportFormatCSV = "csv"
+)
+
+// Import statuses
+const (
+	ImportStatusCompleted  = "completed"
+	ImportStatusRolledBack = "rolled_back"
+)
+
+// Import row statuses: new rows are imported, the others are left out
+const (
+	ImportRowNew       = "new"
+	ImportRowDuplicate = "duplicate"
+	ImportRowSkipped   = "skipped"
+	ImportRowInvalid   = "invalid"
+)
+
+// TransactionImport records one statement imported into a portfolio
+type TransactionImport struct {
+	ID           int64      `json:"id" db:"id"`
+	PortfolioID  int64      `json:"portfolio_id" db:"portfolio_id"`
+	Format       string     `json:"format" db:"format"`
+	Layout       string     `json:"layout,omitempty" db:"layout"`
+	Filename     string     `json:"filename,omitempty" db:"filename"`
+	Status       string     `json:"status" db:"status"`
+	Imported     int        `json:"imported" db:"imported"`
+	Duplicates   int        `json:"duplicates" db:"duplicates"`
+	Skipped      int        `json:"skipped" db:"skipped"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	RolledBackAt *time.Time `json:"rolled_back_at,omitempty" db:"rolled_back_at"`
+}
+
+// ImportRow is one entry read from a statement: its row number in the file
+// (or its position in an OFX transaction list), what will happen to it and
+// the transaction it becomes
+type ImportRow struct {
+	Row         int          `json:"row"`
+	Status      string       `json:"status"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// ImportResult is the outcome of an import or, for a dry run, its preview
+type ImportResult struct {
+	DryRun     bool               `json:"dry_run"`
+	Import     *TransactionImport `json:"import,omitempty"`
+	New        int                `json:"new"`
+	Duplicates int                `json:"duplicates"`
+	Skipped    int                `json:"skipped"`
+	Invalid    int                `json:"invalid"`
+	Rows       []ImportRow        `json:"rows"`
+}
+
+// ImportRequest holds the form fields sent with a statement upload. format
+// is taken from the file name when omitted; CSV files need a built-in
+// layout or a layout_config holding a CSVLayout as JSON.
+type ImportRequest struct {
+	Format       string `json:"format" form:"format" validate:"omitempty,oneof=ofx qfx csv"`
+	Layout       string `json:"layout" form:"layout" validate:"max=50"`
+	LayoutConfig string `json:"layout_config" form:"layout_config" validate:"max=10000"`
+	DryRun       bool   `json:"dry_run" form:"dry_run"`
+}
+
+// CSVLayout describes how a broker's CSV export maps onto ledger
+// transactions. Columns are matched by header name, case-insensitively; the
+// header is the first row that holds the date column.
+type CSVLayout struct {
+	Name        string      `json:"name" validate:"max=50"`
+	Description string      `json:"description,omitempty" validate:"max=200"`
+	Delimiter   string      `json:"delimiter,omitempty" validate:"omitempty,len=1"`
+	DateFormat  string      `json:"date_format" validate:"required,max=30"` // e.g. MM/DD/YYYY
+	Columns     CSVColumns  `json:"columns"`
+	Actions     []CSVAction `json:"actions" validate:"required,min=1,max=100,dive"`
+}
+
+// CSVColumns names the header of each column; only date, action and the
+// columns the actions need are required. Fees may name several columns,
+// which are added up.
+type CSVColumns struct {
+	Date       string   `json:"date" validate:"required,max=100"`
+	Action     string   `json:"action" validate:"required,max=100"`
+	Symbol     string   `json:"symbol,omitempty" validate:"max=100"`
+	Quantity   string   `json:"quantity,omitempty" validate:"max=100"`
+	Price      string   `json:"price,omitempty" validate:"max=100"`
+	Fees       []string `json:"fees,omitempty" validate:"max=5,dive,max=100"`
+	Amount     string   `json:"amount,omitempty" validate:"max=100"`
+	SplitRatio string   `json:"split_ratio,omitempty" validate:"max=100"`
+	ID         string   `json:"id,omitempty" validate:"max=100"`
+	Notes      string   `json:"notes,omitempty" validate:"max=100"`
+}
+
+// CSVAction maps the action column to a transaction type. Match is a
+// case-insensitive substring and the first matching action wins. Besides the
+// ledger types, "cash" is a deposit or withdrawal by the sign of the amount,
+// "trade" a buy or sell by the sign of the quantity and "skip" leaves the row
+// out.
+type CSVAction struct {
+	Match string `json:"match" validate:"required,max=100"`
+	Type  string `json:"type" validate:"required,oneof=buy sell dividend split fee deposit withdrawal transfer_in transfer_out cash trade skip"`
+}
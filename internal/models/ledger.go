@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// Cost basis methods used to match sales against tax lots
+const (
+	CostBasisFIFO     = "fifo"
+	CostBasisLIFO     = "lifo"
+	CostBasisAverage  = "average"
+	CostBasisSpecific = "specific"
+)
+
+// LotSelection picks how much of a specific lot a sale consumes
+type LotSelection struct {
+	LotID    int64   `json:"lot_id" validate:"required"`
+	Quantity float64 `json:"quantity" validate:"gt=0"`
+}
+
+// TaxLot is a block of shares acquired by one transaction, whose ID it
+// shares. Quantities and cost are adjusted for later splits.
+type TaxLot struct {
+	ID           int64     `json:"id"`
+	Symbol       string    `json:"symbol"`
+	AcquiredAt   time.Time `json:"acquired_at"`
+	Quantity     float64   `json:"quantity"`
+	Remaining    float64   `json:"remaining"`
+	CostPerShare float64   `json:"cost_per_share"`
+	CostBasis    float64   `json:"cost_basis"`
+}
+
+// RealizedGain is the gain on the part of one lot consumed by one sale
+type RealizedGain struct {
+	LotID         int64     `json:"lot_id"`
+	TransactionID int64     `json:"transaction_id"`
+	Symbol        string    `json:"symbol"`
+	Quantity      float64   `json:"quantity"`
+	Proceeds      float64   `json:"proceeds"`
+	CostBasis     float64   `json:"cost_basis"`
+	Gain          float64   `json:"gain"`
+	Term          string    `json:"term"` // "short" or "long"
+	AcquiredAt    time.Time `json:"acquired_at"`
+	SoldAt        time.Time `json:"sold_at"`
+}
+
+// RealizedGainsResponse lists realized gains with short and long term totals
+type RealizedGainsResponse struct {
+	Gains     []RealizedGain `json:"gains"`
+	ShortTerm float64        `json:"short_term"`
+	LongTerm  float64        `json:"long_term"`
+	Total     float64        `json:"total"`
+}
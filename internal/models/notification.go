@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Notification channels
+const (
+	ChannelInApp   = "in_app"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Notification is a message sent to a user, recorded with the channels it
+// was delivered to
+type Notification struct {
+	ID        int64      `json:"id" db:"id"`
+	UserID    int64      `json:"user_id" db:"user_id"`
+	AlertID   *int64     `json:"alert_id,omitempty" db:"alert_id"`
+	Title     string     `json:"title" db:"title"`
+	Body      string     `json:"body" db:"body"`
+	Channels  []string   `json:"channels" db:"channels"`
+	ReadAt    *time.Time `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NotificationPreferences controls how a user is notified. Quiet hours
+// ("HH:MM" in Timezone) and the hourly limit only hold back email and
+// webhook deliveries; the in-app inbox always receives notifications when
+// enabled. A MaxPerHour of 0 means no limit.
+type NotificationPreferences struct {
+	UserID          int64     `json:"user_id" db:"user_id"`
+	InAppEnabled    bool      `json:"in_app_enabled" db:"in_app_enabled"`
+	EmailEnabled    bool      `json:"email_enabled" db:"email_enabled"`
+	WebhookEnabled  bool      `json:"webhook_enabled" db:"webhook_enabled"`
+	WebhookURL      string    `json:"webhook_url" db:"webhook_url"`
+	WebhookSecret   string    `json:"webhook_secret,omitempty" db:"webhook_secret"`
+	QuietHoursStart *string   `json:"quiet_hours_start,omitempty" db:"quiet_hours_start"`
+	QuietHoursEnd   *string   `json:"quiet_hours_end,omitempty" db:"quiet_hours_end"`
+	Timezone        string    `json:"timezone" db:"timezone"`
+	MaxPerHour      int       `json:"max_per_hour" db:"max_per_hour"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DefaultNotificationPreferences are used until a user saves their own
+func DefaultNotificationPreferences(userID int64) NotificationPreferences {
+	return NotificationPreferences{
+		UserID:       userID,
+		InAppEnabled: true,
+		Timezone:     "UTC",
+		MaxPerHour:   20,
+	}
+}
+
+// NotificationPreferencesRequest replaces a user's notification preferences
+type NotificationPreferencesRequest struct {
+	InAppEnabled    bool    `json:"in_app_enabled"`
+	EmailEnabled    bool    `json:"email_enabled"`
+	WebhookEnabled  bool    `json:"webhook_enabled"`
+	WebhookURL      string  `json:"webhook_url" validate:"omitempty,url,max=2048"`
+	QuietHoursStart *string `json:"quiet_hours_start" validate:"omitempty,datetime=15:04"`
+	QuietHoursEnd   *string `json:"quiet_hours_end" validate:"omitempty,datetime=15:04"`
+	Timezone        string  `json:"timezone" validate:"omitempty,timezone"`
+	MaxPerHour      *int    `json:"max_per_hour" validate:"omitempty,min=0,max=1000"`
+	RotateSecret    bool    `json:"rotate_secret"`
+}
+
+// NotificationUpdateRequest marks a notification read or unread
+type NotificationUpdateRequest struct {
+	Read *bool `json:"read" validate:"required"`
+}
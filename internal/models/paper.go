@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// Paper order types
+const (
+	OrderMarket    = "market"
+	OrderLimit     = "limit"
+	OrderStop      = "stop"
+	OrderStopLimit = "stop_limit"
+)
+
+// Paper order sides
+const (
+	OrderBuy  = "buy"
+	OrderSell = "sell"
+)
+
+// Time in force: a day order expires at the next market close, gtc stays open
+// until it fills or is cancelled and ioc is cancelled unless it fills at once
+const (
+	TimeInForceDay = "day"
+	TimeInForceGTC = "gtc"
+	TimeInForceIOC = "ioc"
+)
+
+// Paper order statuses
+const (
+	OrderStatusOpen      = "open"
+	OrderStatusFilled    = "filled"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusExpired   = "expired"
+	OrderStatusRejected  = "rejected"
+)
+
+// PaperOrder is a simulated order in a paper account. A filled order refers to
+// the transaction its fill was recorded as.
+type PaperOrder struct {
+	ID            int64      `json:"id" db:"id"`
+	PortfolioID   int64      `json:"portfolio_id" db:"portfolio_id"`
+	Symbol        string     `json:"symbol" db:"symbol"`
+	Side          string     `json:"side" db:"side"`
+	Type          string     `json:"type" db:"type"`
+	Quantity      float64    `json:"quantity" db:"quantity"`
+	LimitPrice    *float64   `json:"limit_price,omitempty" db:"limit_price"`
+	StopPrice     *float64   `json:"stop_price,omitempty" db:"stop_price"`
+	TimeInForce   string     `json:"time_in_force" db:"time_in_force"`
+	Status        string     `json:"status" db:"status"`
+	StopTriggered bool       `json:"stop_triggered" db:"stop_triggered"`
+	FilledPrice   *float64   `json:"filled_price,omitempty" db:"filled_price"`
+	FilledAt      *time.Time `json:"filled_at,omitempty" db:"filled_at"`
+	TransactionID *int64     `json:"transaction_id,omitempty" db:"transaction_id"`
+	RejectReason  string     `json:"reject_reason,omitempty" db:"reject_reason"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// PaperAccountRequest opens a paper account funded with initial_cash
+type PaperAccountRequest struct {
+	Name            string  `json:"name" validate:"required,max=100"`
+	InitialCash     float64 `json:"initial_cash" validate:"gt=0"`
+	CostBasisMethod string  `json:"cost_basis_method" validate:"omitempty,oneof=fifo lifo average"`
+}
+
+// PaperOrderRequest places an order in a paper account. limit_price applies
+// to limit and stop_limit orders, stop_price to stop and stop_limit orders;
+// time_in_force defaults to day.
+type PaperOrderRequest struct {
+	Symbol      string   `json:"symbol" validate:"required,max=10"`
+	Side        string   `json:"side" validate:"required,oneof=buy sell"`
+	Type        string   `json:"type" validate:"required,oneof=market limit stop stop_limit"`
+	Quantity    float64  `json:"quantity" validate:"gt=0"`
+	LimitPrice  *float64 `json:"limit_price" validate:"omitempty,gt=0"`
+	StopPrice   *float64 `json:"stop_price" validate:"omitempty,gt=0"`
+	TimeInForce string   `json:"time_in_force" validate:"omitempty,oneof=day gtc ioc"`
+}
+
+// PaperOrderFilter narrows a paper account's order list
+type PaperOrderFilter struct {
+	Status string `json:"status" form:"status" validate:"omitempty,oneof=open filled cancelled expired rejected"`
+}
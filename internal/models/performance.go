@@ -0,0 +1,117 @@
+package models
+
+import "time"
+
+// PortfolioSnapshot is a portfolio's value at the close of one day. NetFlow
+// is the money moved in (positive) or out (negative) since the previous
+// snapshot: deposits, withdrawals and transfers of shares at market value.
+type PortfolioSnapshot struct {
+	PortfolioID int64     `json:"-" db:"portfolio_id"`
+	Date        time.Time `json:"date" db:"date"`
+	Cash        float64   `json:"cash" db:"cash"`
+	MarketValue float64   `json:"market_value" db:"market_value"`
+	TotalValue  float64   `json:"total_value" db:"total_value"`
+	NetFlow     float64   `json:"net_flow" db:"net_flow"`
+}
+
+// PerformancePoint is one day of a performance series; CumulativeReturn is the
+// time-weighted return since the start of the series, in percent
+type PerformancePoint struct {
+	Date             time.Time `json:"date"`
+	TotalValue       float64   `json:"total_value"`
+	NetFlow          float64   `json:"net_flow"`
+	CumulativeReturn float64   `json:"cumulative_return"`
+}
+
+// PeriodReturn holds the time- and money-weighted returns over one period, in
+// percent. TWR is compounded over the period; IRR is annualized (XIRR).
+type PeriodReturn struct {
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	TWR    *float64  `json:"twr"`
+	IRR    *float64  `json:"irr"`
+}
+
+// PerformanceResponse is a portfolio's value series with its returns over the
+// requested range and the standard periods ending at the latest snapshot
+type PerformanceResponse struct {
+	PortfolioID int64              `json:"portfolio_id"`
+	Range       PeriodReturn       `json:"range"`
+	Periods     []PeriodReturn     `json:"periods"`
+	Series      []PerformancePoint `json:"series"`
+}
+
+// RiskRequest selects the benchmark and lookbacks for portfolio risk metrics.
+// Lookbacks count trading days; each metric falls back to Lookback.
+type RiskRequest struct {
+	Benchmark          string   `json:"benchmark" form:"benchmark" validate:"omitempty,max=10"`
+	Lookback           int      `json:"lookback" form:"lookback" validate:"omitempty,min=20,max=2520"`
+	VolatilityLookback int      `json:"volatility_lookback" form:"volatility_lookback" validate:"omitempty,min=20,max=2520"`
+	BetaLookback       int      `json:"beta_lookback" form:"beta_lookback" validate:"omitempty,min=20,max=2520"`
+	SharpeLookback     int      `json:"sharpe_lookback" form:"sharpe_lookback" validate:"omitempty,min=20,max=2520"`
+	DrawdownLookback   int      `json:"drawdown_lookback" form:"drawdown_lookback" validate:"omitempty,min=20,max=2520"`
+	VaRLookback        int      `json:"var_lookback" form:"var_lookback" validate:"omitempty,min=20,max=2520"`
+	RiskFreeRate       *float64 `json:"risk_free_rate" form:"risk_free_rate" validate:"omitempty,gte=0,lte=50"`
+	Confidence         float64  `json:"confidence" form:"confidence" validate:"omitempty,gt=50,lt=100"`
+}
+
+// VolatilityMetric is the annualized standard deviation of daily returns
+type VolatilityMetric struct {
+	Lookback     int      `json:"lookback"`
+	Observations int      `json:"observations"`
+	Annualized   *float64 `json:"annualized"`
+}
+
+// BetaMetric measures the portfolio's sensitivity to a benchmark over the
+// days both have returns
+type BetaMetric struct {
+	Lookback     int      `json:"lookback"`
+	Observations int      `json:"observations"`
+	Benchmark    string   `json:"benchmark"`
+	Beta         *float64 `json:"beta"`
+	Correlation  *float64 `json:"correlation"`
+}
+
+// RatioMetrics are annualized risk-adjusted returns
+type RatioMetrics struct {
+	Lookback     int      `json:"lookback"`
+	Observations int      `json:"observations"`
+	RiskFreeRate float64  `json:"risk_free_rate"`
+	Sharpe       *float64 `json:"sharpe"`
+	Sortino      *float64 `json:"sortino"`
+}
+
+// DrawdownMetric is the largest peak-to-trough fall in value, in percent,
+// and when it recovered; RecoveryDate is nil while still below the peak
+type DrawdownMetric struct {
+	Lookback     int        `json:"lookback"`
+	MaxDrawdown  *float64   `json:"max_drawdown"`
+	PeakDate     *time.Time `json:"peak_date"`
+	TroughDate   *time.Time `json:"trough_date"`
+	RecoveryDate *time.Time `json:"recovery_date"`
+}
+
+// VaRMetric is the one-day value at risk and conditional value at risk
+// (expected shortfall) at the confidence level, as percentages of value lost
+type VaRMetric struct {
+	Lookback       int      `json:"lookback"`
+	Observations   int      `json:"observations"`
+	Confidence     float64  `json:"confidence"`
+	HistoricalVaR  *float64 `json:"historical_var"`
+	HistoricalCVaR *float64 `json:"historical_cvar"`
+	ParametricVaR  *float64 `json:"parametric_var"`
+	ParametricCVaR *float64 `json:"parametric_cvar"`
+}
+
+// RiskResponse holds a portfolio's risk metrics as of its latest snapshot
+type RiskResponse struct {
+	PortfolioID int64            `json:"portfolio_id"`
+	AsOf        *time.Time       `json:"as_of"`
+	TotalValue  float64          `json:"total_value"`
+	Volatility  VolatilityMetric `json:"volatility"`
+	Beta        BetaMetric       `json:"beta"`
+	Ratios      RatioMetrics     `json:"ratios"`
+	Drawdown    DrawdownMetric   `json:"drawdown"`
+	VaR         VaRMetric        `json:"var"`
+}
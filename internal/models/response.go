@@ -24,6 +24,12 @@ type StockWatchlistResponse struct {
 	Count     int              `json:"count"`
 }
 
+// WatchlistViewResponse represents a watchlist with live prices per symbol
+type WatchlistViewResponse struct {
+	Watchlist Watchlist           `json:"watchlist"`
+	Items     []WatchlistItemView `json:"items"`
+}
+
 // StockAlertResponse represents stock alert response
 type StockAlertResponse struct {
 	Alerts []StockAlert `json:"alerts"`
@@ -40,10 +46,65 @@ type NewsResponse struct {
 	Category    string    `json:"category,omitempty"`
 }
 
-// APIResponse is a generic wrapper for API responses
+// APIResponse is the envelope for every successful API response
 type APIResponse[T any] struct {
-	Data    T      `json:"data"`
+	Data      T             `json:"data"`
+	Success   bool          `json:"success"`
+	Message   string        `json:"message,omitempty"`
+	Meta      *ResponseMeta `json:"meta,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+// ResponseMeta carries paging information for list responses
+type ResponseMeta struct {
+	Total      *int   `json:"total,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+	Offset     int    `json:"offset,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// StockPage is one page of the stock list with the total number of matches
+type StockPage struct {
+	Stocks     []Stock
+	Total      int
+	NextCursor string
+}
+
+// ProblemDetails is an RFC 7807 error response extended with a stable
+// machine-readable code and the request ID
+type ProblemDetails struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a validation failure on a single request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// BatchResult represents the outcome of a batch operation for one symbol
+type BatchResult struct {
+	Symbol  string `json:"symbol"`
 	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
+	Data    any    `json:"data,omitempty"`
 	Error   string `json:"error,omitempty"`
 }
+
+// BatchJob represents an asynchronous batch operation and its progress
+type BatchJob struct {
+	ID         string        `json:"id"`
+	Type       string        `json:"type"`   // "fetch", "quotes" or "refresh"
+	Status     string        `json:"status"` // "running" or "completed"
+	Total      int           `json:"total"`
+	Completed  int           `json:"completed"`
+	Results    []BatchResult `json:"results,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
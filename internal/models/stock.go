@@ -17,33 +17,207 @@ type StockHistoryEntry struct {
 
 // StockWatchlist represents a user's watchlist entry
 type StockWatchlist struct {
-	ID        int64     `json:"id" db:"id"`
-	UserID    int64     `json:"user_id" db:"user_id"`
-	Symbol    string    `json:"symbol" db:"symbol"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID          int64     `json:"id" db:"id"`
+	UserID      int64     `json:"user_id" db:"user_id"`
+	WatchlistID int64     `json:"watchlist_id" db:"watchlist_id"`
+	Symbol      string    `json:"symbol" db:"symbol"`
+	Position    int       `json:"position" db:"position"`
+	Notes       string    `json:"notes" db:"notes"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// Watchlist represents a named, ordered list of symbols owned by a user
+type Watchlist struct {
+	ID          int64     `json:"id" db:"id"`
+	UserID      int64     `json:"user_id" db:"user_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	Position    int       `json:"position" db:"position"`
+	ItemCount   int       `json:"item_count"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WatchlistItemView is a watchlist entry joined with its latest prices
+type WatchlistItemView struct {
+	Symbol           string    `json:"symbol"`
+	Name             string    `json:"name"`
+	Notes            string    `json:"notes"`
+	Position         int       `json:"position"`
+	LastPrice        float64   `json:"last_price"`
+	PreviousClose    float64   `json:"previous_close,omitempty"`
+	DayChange        float64   `json:"day_change"`
+	DayChangePercent float64   `json:"day_change_percent"`
+	Sparkline        []float64 `json:"sparkline"`
+	AddedAt          time.Time `json:"added_at"`
+}
+
+// WatchlistRequest is the payload for creating or updating a watchlist
+type WatchlistRequest struct {
+	Name        string  `json:"name" validate:"required,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=1000"`
+	Position    *int    `json:"position" validate:"omitempty,min=0"`
+}
+
+// WatchlistItemRequest is the payload for adding a symbol to a watchlist
+type WatchlistItemRequest struct {
+	Symbol   string `json:"symbol" validate:"required,max=10"`
+	Notes    string `json:"notes" validate:"max=1000"`
+	Position *int   `json:"position" validate:"omitempty,min=0"`
+}
+
+// WatchlistItemUpdateRequest changes the notes or position of a watchlist entry
+type WatchlistItemUpdateRequest struct {
+	Notes    *string `json:"notes" validate:"omitempty,max=1000"`
+	Position *int    `json:"position" validate:"omitempty,min=0"`
+}
+
+// WatchlistOrderRequest lists a watchlist's symbols in their new order
+type WatchlistOrderRequest struct {
+	Symbols []string `json:"symbols" validate:"required,min=1,max=500"`
 }
 
 // StockAlert represents price alerts for stocks
 type StockAlert struct {
-	ID          int64      `json:"id" db:"id"`
-	UserID      int64      `json:"user_id" db:"user_id"`
-	Symbol      string     `json:"symbol" db:"symbol"`
-	AlertType   string     `json:"alert_type" db:"alert_type"` // "above", "below"
-	TargetPrice float64    `json:"target_price" db:"target_price"`
-	IsActive    bool       `json:"is_active" db:"is_active"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	TriggeredAt *time.Time `json:"triggered_at,omitempty" db:"triggered_at"`
+	ID             int64           `json:"id" db:"id"`
+	UserID         int64           `json:"user_id" db:"user_id"`
+	Symbol         string          `json:"symbol" db:"symbol"`
+	AlertType      string          `json:"alert_type" db:"alert_type"` // "above", "below", "condition"
+	TargetPrice    *float64        `json:"target_price,omitempty" db:"target_price"`
+	Condition      *AlertCondition `json:"condition,omitempty" db:"condition"`
+	IsActive       bool            `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	TriggeredAt    *time.Time      `json:"triggered_at,omitempty" db:"triggered_at"`
+	TriggeredPrice *float64        `json:"triggered_price,omitempty" db:"triggered_price"`
+}
+
+// Alert types; "condition" alerts are described by an AlertCondition
+const (
+	AlertTypeAbove     = "above"
+	AlertTypeBelow     = "below"
+	AlertTypeCondition = "condition"
+)
+
+// Alert condition types
+const (
+	ConditionPrice         = "price"          // close above/below value
+	ConditionPercentChange = "percent_change" // change over days above/below value percent
+	ConditionVolumeSpike   = "volume_spike"   // volume at least value times the days average
+	ConditionMACross       = "ma_cross"       // close crosses above/below the days SMA
+	ConditionRSI           = "rsi"            // days RSI above/below value
+	ConditionNewHigh       = "new_high"       // highest close over days
+	ConditionNewLow        = "new_low"        // lowest close over days
+	ConditionAnd           = "and"            // every nested condition holds
+	ConditionOr            = "or"             // any nested condition holds
+)
+
+// AlertCondition is a structured alert rule, stored as JSON. Leaf conditions
+// use Operator, Value and Days as their type requires; "and" and "or"
+// combine nested Conditions.
+type AlertCondition struct {
+	Type       string           `json:"type"`
+	Operator   string           `json:"operator,omitempty"` // "above", "below"
+	Value      *float64         `json:"value,omitempty"`
+	Days       int              `json:"days,omitempty"`
+	Conditions []AlertCondition `json:"conditions,omitempty"`
+}
+
+// StockAlertRequest is the payload for creating an alert; above/below alerts
+// need a target price, condition alerts a condition
+type StockAlertRequest struct {
+	Symbol      string          `json:"symbol" validate:"required,max=10"`
+	AlertType   string          `json:"alert_type" validate:"required,oneof=above below condition"`
+	TargetPrice *float64        `json:"target_price" validate:"omitempty,gt=0"`
+	Condition   *AlertCondition `json:"condition"`
+}
+
+// AlertBacktestRequest describes an alert rule to replay over stored history.
+// Dates are YYYY-MM-DD; without a start the most recent 5000 bars up to end
+// are replayed, and longer ranges are rejected. Horizons are the trading days
+// after each firing to measure returns over.
+type AlertBacktestRequest struct {
+	Symbol      string          `json:"symbol" validate:"required,max=10"`
+	AlertType   string          `json:"alert_type" validate:"required,oneof=above below condition"`
+	TargetPrice *float64        `json:"target_price" validate:"omitempty,gt=0"`
+	Condition   *AlertCondition `json:"condition"`
+	Start       string          `json:"start" validate:"omitempty,datetime=2006-01-02"`
+	End         string          `json:"end" validate:"omitempty,datetime=2006-01-02"`
+	Horizons    []int           `json:"horizons" validate:"omitempty,max=10,dive,min=1,max=252"`
+}
+
+// AlertFiring is a date on which a backtested alert would have fired
+type AlertFiring struct {
+	Date           time.Time       `json:"date"`
+	Price          float64         `json:"price"`
+	ForwardReturns []ForwardReturn `json:"forward_returns"`
+}
+
+// ForwardReturn is the percent change in close over Days trading days; it is
+// nil when the history does not reach that far
+type ForwardReturn struct {
+	Days   int      `json:"days"`
+	Return *float64 `json:"return"`
+}
+
+// HorizonSummary aggregates the forward returns of every firing for one horizon
+type HorizonSummary struct {
+	Days          int      `json:"days"`
+	Count         int      `json:"count"`
+	AverageReturn *float64 `json:"average_return"`
+	PositiveRate  *float64 `json:"positive_rate"`
+}
+
+// AlertBacktestResponse lists when an alert rule would have fired
+type AlertBacktestResponse struct {
+	Symbol        string           `json:"symbol"`
+	Start         time.Time        `json:"start"`
+	End           time.Time        `json:"end"`
+	BarsEvaluated int              `json:"bars_evaluated"`
+	FiringCount   int              `json:"firing_count"`
+	Firings       []AlertFiring    `json:"firings"`
+	Summary       []HorizonSummary `json:"summary"`
+}
+
+// StockAlertUpdateRequest changes an alert; setting is_active re-arms a
+// triggered alert
+type StockAlertUpdateRequest struct {
+	AlertType   *string         `json:"alert_type" validate:"omitempty,oneof=above below condition"`
+	TargetPrice *float64        `json:"target_price" validate:"omitempty,gt=0"`
+	Condition   *AlertCondition `json:"condition"`
+	IsActive    *bool           `json:"is_active"`
 }
 
 // Stock request structure for fetching stock data
 type StockRequest struct {
 	Symbol string `json:"symbol" validate:"required,min=1,max=10"`
-	Fields string `json:"fields,omitempty" validate:"omitempty,min=1,max=100"`
+	Fields string `json:"fields,omitempty" form:"fields" validate:"omitempty,min=1,max=100"`
 }
 
 type StockListRequest struct {
 	Symbols []string `json:"symbols" validate:"required,min=1,max=100"`
-	Fields  string   `json:"fields,omitempty" validate:"omitempty,min=1,max=100"`
+	Fields  string   `json:"fields,omitempty" form:"fields" validate:"omitempty,min=1,max=100"`
+}
+
+// StockListQuery filters, sorts and pages the stock list
+type StockListQuery struct {
+	Sector    string   `json:"sector" form:"sector" validate:"omitempty,max=100"`
+	Industry  string   `json:"industry" form:"industry" validate:"omitempty,max=255"`
+	MinPrice  *float64 `json:"min_price" form:"min_price" validate:"omitempty,min=0"`
+	MaxPrice  *float64 `json:"max_price" form:"max_price" validate:"omitempty,min=0"`
+	Search    string   `json:"q" form:"q" validate:"omitempty,max=100"`
+	SortBy    string   `json:"sort" form:"sort" validate:"omitempty,oneof=symbol name price market_cap pe_ratio last_updated"`
+	SortOrder string   `json:"order" form:"order" validate:"omitempty,oneof=asc desc"`
+	Limit     int      `json:"limit" form:"limit" validate:"omitempty,min=1,max=500"`
+	Cursor    string   `json:"cursor" form:"cursor"`
+	Fields    []string `json:"-" form:"-"`
+}
+
+// StockHistoryQuery selects a range of history entries and the fields to return
+type StockHistoryQuery struct {
+	Fields []string
+	Start  *time.Time
+	End    *time.Time
+	Limit  int
 }
 
 // Stock represents the main stock entity with current data
@@ -56,13 +230,13 @@ type Stock struct {
 	DayHigh          float64   `json:"day_high,omitempty"`
 	DayLow           float64   `json:"day_low,omitempty"`
 	Volume           int64     `json:"volume,omitempty"`
-	MarketCap        int64     `json:"market_cap,omitempty"`
-	PeRatio          float64   `json:"pe_ratio,omitempty"`
-	DividendYield    float64   `json:"dividend_yield,omitempty"`
+	MarketCap        int64     `json:"market_cap,omitempty" db:"market_cap"`
+	PeRatio          float64   `json:"pe_ratio,omitempty" db:"pe_ratio"`
+	DividendYield    float64   `json:"dividend_yield,omitempty" db:"dividend_yield"`
 	FiftyTwoWeekHigh float64   `json:"fifty_two_week_high,omitempty"`
 	FiftyTwoWeekLow  float64   `json:"fifty_two_week_low,omitempty"`
-	Sector           string    `json:"sector,omitempty"`
-	Industry         string    `json:"industry,omitempty"`
+	Sector           string    `json:"sector,omitempty" db:"sector"`
+	Industry         string    `json:"industry,omitempty" db:"industry"`
 	LastUpdated      time.Time `json:"last_updated" db:"created_at"`
 }
 
@@ -89,6 +263,8 @@ type TechnicalIndicators struct {
 	MACD           *MACDData              `json:"macd,omitempty"`
 	BollingerBands *BollingerBandsData    `json:"bollinger_bands,omitempty"`
 	CalculatedAt   time.Time              `json:"calculated_at"`
+	// Bars is how many closes the indicators were calculated from
+	Bars int `json:"-"`
 }
 
 // MACDData represents MACD indicator values
@@ -107,45 +283,98 @@ type BollingerBandsData struct {
 
 // Portfolio represents a user's portfolio
 type Portfolio struct {
-	ID           int64                `json:"id" db:"id"`
-	UserID       int64                `json:"user_id" db:"user_id"`
-	Name         string               `json:"name" db:"name"`
-	TotalValue   float64              `json:"total_value"`
-	CashBalance  float64              `json:"cash_balance" db:"cash_balance"`
-	DayChange    float64              `json:"day_change"`
-	TotalReturn  float64              `json:"total_return"`
-	Positions    []PortfolioPosition  `json:"positions,omitempty"`
-	CreatedAt    time.Time            `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time            `json:"updated_at" db:"updated_at"`
+	ID                 int64               `json:"id" db:"id"`
+	UserID             int64               `json:"user_id" db:"user_id"`
+	Name               string              `json:"name" db:"name"`
+	TotalValue         float64             `json:"total_value"`
+	CashBalance        float64             `json:"cash_balance" db:"cash_balance"`
+	CostBasisMethod    string              `json:"cost_basis_method" db:"cost_basis_method"`
+	IsPaper            bool                `json:"is_paper" db:"is_paper"`
+	CostBasis          float64             `json:"cost_basis"`
+	DayChange          float64             `json:"day_change"`
+	DayChangePercent   float64             `json:"day_change_percent"`
+	TotalReturn        float64             `json:"total_return"`
+	TotalReturnPercent float64             `json:"total_return_percent"`
+	Positions          []PortfolioPosition `json:"positions,omitempty"`
+	CreatedAt          time.Time           `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time           `json:"updated_at" db:"updated_at"`
 }
 
 // PortfolioPosition represents a stock position in a portfolio
 type PortfolioPosition struct {
-	ID              int64     `json:"id" db:"id"`
-	PortfolioID     int64     `json:"portfolio_id" db:"portfolio_id"`
-	Symbol          string    `json:"symbol" db:"symbol"`
-	Quantity        int64     `json:"quantity" db:"quantity"`
-	AverageCost     float64   `json:"average_cost" db:"average_cost"`
-	CurrentPrice    float64   `json:"current_price,omitempty"`
-	MarketValue     float64   `json:"market_value,omitempty"`
-	UnrealizedGain  float64   `json:"unrealized_gain,omitempty"`
-	UnrealizedGainPercent float64 `json:"unrealized_gain_percent,omitempty"`
-	CreatedAt       time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
-}
-
-// Transaction represents buy/sell transactions
+	ID                    int64     `json:"id" db:"id"`
+	PortfolioID           int64     `json:"portfolio_id" db:"portfolio_id"`
+	Symbol                string    `json:"symbol" db:"symbol"`
+	Quantity              float64   `json:"quantity" db:"quantity"`
+	AverageCost           float64   `json:"average_cost" db:"average_cost"`
+	CurrentPrice          float64   `json:"current_price,omitempty"`
+	PreviousClose         float64   `json:"previous_close,omitempty"`
+	MarketValue           float64   `json:"market_value,omitempty"`
+	CostBasis             float64   `json:"cost_basis,omitempty"`
+	DayChange             float64   `json:"day_change,omitempty"`
+	UnrealizedGain        float64   `json:"unrealized_gain,omitempty"`
+	UnrealizedGainPercent float64   `json:"unrealized_gain_percent,omitempty"`
+	CreatedAt             time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Transaction is an entry in a portfolio's ledger. TotalAmount is the cash
+// moved: cost plus fees for a buy, proceeds less fees for a sell and the
+// amount of a dividend, fee, deposit or withdrawal.
 type Transaction struct {
-	ID          int64     `json:"id" db:"id"`
-	PortfolioID int64     `json:"portfolio_id" db:"portfolio_id"`
-	Symbol      string    `json:"symbol" db:"symbol"`
-	Type        string    `json:"type" db:"type"` // "buy" or "sell"
-	Quantity    int64     `json:"quantity" db:"quantity"`
-	Price       float64   `json:"price" db:"price"`
-	Fees        float64   `json:"fees" db:"fees"`
-	TotalAmount float64   `json:"total_amount" db:"total_amount"`
-	ExecutedAt  time.Time `json:"executed_at" db:"executed_at"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ID            int64          `json:"id" db:"id"`
+	PortfolioID   int64          `json:"portfolio_id" db:"portfolio_id"`
+	Symbol        string         `json:"symbol,omitempty" db:"symbol"`
+	Type          string         `json:"type" db:"type"`
+	Quantity      float64        `json:"quantity" db:"quantity"`
+	Price         float64        `json:"price" db:"price"`
+	Fees          float64        `json:"fees" db:"fees"`
+	TotalAmount   float64        `json:"total_amount" db:"total_amount"`
+	SplitRatio    *float64       `json:"split_ratio,omitempty" db:"split_ratio"`
+	LotSelections []LotSelection `json:"lots,omitempty" db:"lot_selections"`
+	Notes         string         `json:"notes,omitempty" db:"notes"`
+	ExternalID    string         `json:"external_id,omitempty" db:"external_id"`
+	ImportID      *int64         `json:"import_id,omitempty" db:"import_id"`
+	ExecutedAt    time.Time      `json:"executed_at" db:"executed_at"`
+	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
+}
+
+// Transaction types
+const (
+	TransactionBuy         = "buy"
+	TransactionSell        = "sell"
+	TransactionDividend    = "dividend"
+	TransactionSplit       = "split"
+	TransactionFee         = "fee"
+	TransactionDeposit     = "deposit"
+	TransactionWithdrawal  = "withdrawal"
+	TransactionTransferIn  = "transfer_in"  // shares received at a cost basis, no cash
+	TransactionTransferOut = "transfer_out" // shares removed, no cash or realized gain
+)
+
+// PortfolioRequest is the payload for creating or updating a portfolio;
+// initial_deposit is only used on create
+type PortfolioRequest struct {
+	Name            string   `json:"name" validate:"required,max=100"`
+	CostBasisMethod string   `json:"cost_basis_method" validate:"omitempty,oneof=fifo lifo average specific"`
+	InitialDeposit  *float64 `json:"initial_deposit" validate:"omitempty,gt=0"`
+}
+
+// TransactionRequest records a ledger entry; executed_at defaults to now.
+// Which fields apply depends on the type: quantity and price for trades and
+// transfers, amount for dividends, fees, deposits and withdrawals,
+// split_ratio for splits and lots to pick specific lots on a sale.
+type TransactionRequest struct {
+	Symbol     string         `json:"symbol" validate:"max=10"`
+	Type       string         `json:"type" validate:"required,oneof=buy sell dividend split fee deposit withdrawal transfer_in transfer_out"`
+	Quantity   float64        `json:"quantity" validate:"gte=0"`
+	Price      float64        `json:"price" validate:"gte=0"`
+	Fees       float64        `json:"fees" validate:"gte=0"`
+	Amount     float64        `json:"amount" validate:"gte=0"`
+	SplitRatio *float64       `json:"split_ratio" validate:"omitempty,gt=0"`
+	Lots       []LotSelection `json:"lots" validate:"omitempty,max=100,dive"`
+	Notes      string         `json:"notes" validate:"max=500"`
+	ExecutedAt *time.Time     `json:"executed_at"`
 }
 
 // StockScreenerRequest for filtering stocks
@@ -163,4 +392,17 @@ type StockScreenerRequest struct {
 	SortOrder     string   `json:"sort_order,omitempty"` // "asc" or "desc"
 	Limit         int      `json:"limit,omitempty"`
 	Offset        int      `json:"offset,omitempty"`
+
+	// Indicators are evaluated against each candidate's price history
+	Indicators []IndicatorFilter `json:"indicators,omitempty"`
+}
+
+// IndicatorFilter compares a technical indicator against a fixed value or
+// another indicator, e.g. {"indicator": "rsi", "operator": "<", "value": 30}
+// or {"indicator": "close", "operator": "above", "compare_to": "sma_200"}
+type IndicatorFilter struct {
+	Indicator string   `json:"indicator"`            // "close", "rsi", "sma_20", "sma_50", "sma_200", "ema_12", "ema_26", "macd"
+	Operator  string   `json:"operator"`             // "<", "<=", ">", ">=", "above", "below"
+	Value     *float64 `json:"value,omitempty"`      // fixed threshold
+	CompareTo string   `json:"compare_to,omitempty"` // other indicator name
 }
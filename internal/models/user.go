@@ -0,0 +1,127 @@
+package models
+
+import "time"
+
+// User represents a registered account
+type User struct {
+	ID           int64     `json:"id" db:"id"`
+	Email        string    `json:"email" db:"email"`
+	Username     string    `json:"username" db:"username"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	Role         string    `json:"role" db:"role"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RefreshToken represents an issued refresh token; the token itself is a JWT
+// and only its ID is stored
+type RefreshToken struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	FamilyID   string     `json:"family_id" db:"family_id"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	ReplacedBy *string    `json:"replaced_by,omitempty" db:"replaced_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// RegisterRequest is the payload for creating an account
+type RegisterRequest struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Username string `json:"username" validate:"required,alphanum,min=3,max=50"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// LoginRequest accepts either the email or the username as login
+type LoginRequest struct {
+	Login    string `json:"login" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,max=72"`
+}
+
+// RefreshRequest exchanges a refresh token for a new token pair
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenPair is returned after a successful login, registration or refresh
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// AuthResponse combines the authenticated user with their tokens
+type AuthResponse struct {
+	User   *User      `json:"user"`
+	Tokens *TokenPair `json:"tokens"`
+}
+
+// User roles, from most to least privileged
+const (
+	RoleAdmin   = "admin"
+	RoleAnalyst = "analyst"
+	RoleViewer  = "viewer"
+)
+
+// Permissions checked by the router
+const (
+	PermFetchStocks = "stocks:fetch"
+	PermAdmin       = "admin"
+)
+
+// RolePermissions lists what each role may do beyond reading stock data,
+// which is public
+var RolePermissions = map[string][]string{
+	RoleAdmin:   {PermFetchStocks, PermAdmin},
+	RoleAnalyst: {PermFetchStocks},
+	RoleViewer:  {},
+}
+
+// UpdateUserRequest is the admin payload for changing a user's role
+type UpdateUserRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin analyst viewer"`
+}
+
+// AdminRefreshRequest lists the symbols to refresh; empty means every stored symbol
+type AdminRefreshRequest struct {
+	Symbols []string `json:"symbols" validate:"omitempty,max=500"`
+}
+
+// API key scopes
+const (
+	ScopeReadStocks = "read:stocks"
+	ScopeWriteFetch = "write:fetch"
+	ScopePortfolio  = "portfolio"
+)
+
+// AllScopes lists every scope; interactive sessions are granted all of them
+var AllScopes = []string{ScopeReadStocks, ScopeWriteFetch, ScopePortfolio}
+
+// APIKey represents a user's API key; the key itself is only shown once
+type APIKey struct {
+	ID         int64      `json:"id" db:"id"`
+	UserID     int64      `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	KeyHash    string     `json:"-" db:"key_hash"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreatedAPIKey is returned once, when the key is created
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyRequest is the payload for creating or updating an API key
+type APIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=read:stocks write:fetch portfolio"`
+	ExpiresAt *time.Time `json:"expires_at" validate:"omitempty"`
+}
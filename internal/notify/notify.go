@@ -0,0 +1,263 @@
+// Package notify delivers notifications to users over the in-app inbox,
+// email and signed webhooks
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+)
+
+// deliveryTimeout bounds a single external delivery, retries included
+const deliveryTimeout = 2 * time.Minute
+
+// Message is the content of a notification
+type Message struct {
+	Title   string
+	Body    string
+	AlertID *int64
+	Data    any // included in webhook payloads
+}
+
+// Recipient is who an external channel delivers to
+type Recipient struct {
+	UserID      int64
+	Email       string
+	Preferences models.NotificationPreferences
+}
+
+// Channel delivers messages outside the application
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, to Recipient, msg Message) error
+}
+
+// Dispatcher records notifications and delivers them on each user's enabled
+// channels, skipping external deliveries during quiet hours and beyond the
+// user's hourly limit. A notification lists only the channels it actually
+// reached: external ones are added once their delivery succeeds.
+type Dispatcher struct {
+	notifications repository.NotificationRepository
+	users         repository.UserRepository
+	channels      map[string]Channel
+	now           func() time.Time
+
+	// inFlight counts each user's external deliveries still being sent, so
+	// a burst of notifications cannot overrun the hourly limit before the
+	// first deliveries are recorded
+	mu       sync.Mutex
+	inFlight map[int64]int
+}
+
+func NewDispatcher(notifications repository.NotificationRepository, users repository.UserRepository, channels ...Channel) *Dispatcher {
+	d := &Dispatcher{
+		notifications: notifications,
+		users:         users,
+		channels:      make(map[string]Channel),
+		now:           time.Now,
+		inFlight:      make(map[int64]int),
+	}
+	for _, ch := range channels {
+		d.channels[ch.Name()] = ch
+	}
+	return d
+}
+
+// Notify records a message for the user and sends it on their external
+// channels in the background
+func (d *Dispatcher) Notify(userID int64, msg Message) (*models.Notification, error) {
+	prefs, err := d.notifications.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient := Recipient{UserID: userID, Preferences: *prefs}
+	if prefs.EmailEnabled {
+		user, err := d.users.GetByID(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load recipient: %w", err)
+		}
+		recipient.Email = user.Email
+	}
+
+	// Routing and reserving the deliveries happen together so concurrent
+	// notifications see each other against the hourly limit
+	d.mu.Lock()
+	channels, external, err := d.route(recipient)
+	if err == nil {
+		d.inFlight[userID] += len(external)
+	}
+	d.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	notification := models.Notification{
+		UserID:   userID,
+		AlertID:  msg.AlertID,
+		Title:    msg.Title,
+		Body:     msg.Body,
+		Channels: channels,
+	}
+	if err := d.notifications.Create(&notification); err != nil {
+		d.release(userID, len(external))
+		return nil, err
+	}
+
+	for _, name := range external {
+		go d.deliver(d.channels[name], notification.ID, recipient, msg)
+	}
+
+	return &notification, nil
+}
+
+// NotifyAlert tells the alert's owner that it fired
+func (d *Dispatcher) NotifyAlert(alert models.StockAlert) {
+	if _, err := d.Notify(alert.UserID, AlertMessage(alert)); err != nil {
+		log.Printf("notify: failed to notify user %d of alert %d: %v", alert.UserID, alert.ID, err)
+	}
+}
+
+// route picks the channels a notification goes out on: the inbox, recorded
+// right away, and the external channels to attempt. The caller holds d.mu.
+func (d *Dispatcher) route(to Recipient) ([]string, []string, error) {
+	prefs := to.Preferences
+
+	channels := []string{}
+	if prefs.InAppEnabled {
+		channels = append(channels, models.ChannelInApp)
+	}
+
+	var external []string
+	if prefs.EmailEnabled && to.Email != "" && d.channels[models.ChannelEmail] != nil {
+		external = append(external, models.ChannelEmail)
+	}
+	if prefs.WebhookEnabled && prefs.WebhookURL != "" && d.channels[models.ChannelWebhook] != nil {
+		external = append(external, models.ChannelWebhook)
+	}
+	if len(external) == 0 || InQuietHours(prefs, d.now()) {
+		return channels, nil, nil
+	}
+
+	if prefs.MaxPerHour > 0 {
+		sent, err := d.notifications.CountExternalSince(to.UserID, d.now().Add(-time.Hour))
+		if err != nil {
+			return nil, nil, err
+		}
+		if sent+d.inFlight[to.UserID] >= prefs.MaxPerHour {
+			return channels, nil, nil
+		}
+	}
+
+	return channels, external, nil
+}
+
+// deliver sends the message on an external channel and records the channel
+// on the notification once it has been delivered
+func (d *Dispatcher) deliver(ch Channel, notificationID int64, to Recipient, msg Message) {
+	defer d.release(to.UserID, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	if err := ch.Send(ctx, to, msg); err != nil {
+		log.Printf("notify: %s delivery to user %d failed: %v", ch.Name(), to.UserID, err)
+		return
+	}
+	if err := d.notifications.AddChannel(notificationID, ch.Name()); err != nil {
+		log.Printf("notify: failed to record %s delivery of notification %d: %v", ch.Name(), notificationID, err)
+	}
+}
+
+// release ends count in-flight deliveries for the user
+func (d *Dispatcher) release(userID int64, count int) {
+	if count == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inFlight[userID] -= count
+	if d.inFlight[userID] <= 0 {
+		delete(d.inFlight, userID)
+	}
+}
+
+// InQuietHours reports whether now falls within the user's quiet hours. The
+// window may wrap past midnight; equal start and end means no quiet hours.
+func InQuietHours(prefs models.NotificationPreferences, now time.Time) bool {
+	if prefs.QuietHoursStart == nil || prefs.QuietHoursEnd == nil {
+		return false
+	}
+
+	start, okStart := minuteOfDay(*prefs.QuietHoursStart)
+	end, okEnd := minuteOfDay(*prefs.QuietHoursEnd)
+	if !okStart || !okEnd || start == end {
+		return false
+	}
+
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	if start < end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// minuteOfDay parses "HH:MM"
+func minuteOfDay(value string) (int, bool) {
+	hours, minutes, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, false
+	}
+	h, errH := strconv.Atoi(hours)
+	m, errM := strconv.Atoi(minutes)
+	if errH != nil || errM != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// AlertMessage describes a fired alert
+func AlertMessage(alert models.StockAlert) Message {
+	price := 0.0
+	if alert.TriggeredPrice != nil {
+		price = *alert.TriggeredPrice
+	}
+
+	var body string
+	switch alert.AlertType {
+	case models.AlertTypeAbove, models.AlertTypeBelow:
+		target := 0.0
+		if alert.TargetPrice != nil {
+			target = *alert.TargetPrice
+		}
+		body = fmt.Sprintf("%s is at %.4f, %s your target of %.4f.", alert.Symbol, price, alert.AlertType, target)
+	default:
+		condition := "alert"
+		if alert.Condition != nil {
+			condition = alert.Condition.Type
+		}
+		body = fmt.Sprintf("%s matched your %s condition at %.4f.", alert.Symbol, condition, price)
+	}
+
+	id := alert.ID
+	return Message{
+		Title:   alert.Symbol + " alert triggered",
+		Body:    body,
+		AlertID: &id,
+		Data:    alert,
+	}
+}
@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"go-flow/internal/models"
+)
+
+func TestInQuietHours(t *testing.T) {
+	str := func(s string) *string { return &s }
+	// 2024-01-15 is in winter: New York is UTC-5, Tokyo UTC+9
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, time.January, 15, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		start, end *string
+		timezone   string
+		now        time.Time
+		want       bool
+	}{
+		{"no quiet hours", nil, nil, "UTC", at(3, 0), false},
+		{"only a start", str("22:00"), nil, "UTC", at(23, 0), false},
+		{"equal start and end", str("08:00"), str("08:00"), "UTC", at(8, 0), false},
+		{"malformed start", str("8pm"), str("07:00"), "UTC", at(3, 0), false},
+		{"same day inside", str("12:00"), str("14:00"), "UTC", at(13, 0), true},
+		{"same day at start", str("12:00"), str("14:00"), "UTC", at(12, 0), true},
+		{"same day at end", str("12:00"), str("14:00"), "UTC", at(14, 0), false},
+		{"same day before", str("12:00"), str("14:00"), "UTC", at(11, 59), false},
+		{"overnight before midnight", str("22:00"), str("07:00"), "UTC", at(23, 30), true},
+		{"overnight after midnight", str("22:00"), str("07:00"), "UTC", at(2, 0), true},
+		{"overnight at end", str("22:00"), str("07:00"), "UTC", at(7, 0), false},
+		{"overnight during the day", str("22:00"), str("07:00"), "UTC", at(12, 0), false},
+		{"overnight in new york after midnight", str("22:00"), str("07:00"), "America/New_York", at(6, 0), true},
+		{"overnight in new york morning", str("22:00"), str("07:00"), "America/New_York", at(12, 30), false},
+		{"overnight in new york evening", str("22:00"), str("07:00"), "America/New_York", at(3, 30), true},
+		{"overnight in new york summer time", str("22:00"), str("07:00"), "America/New_York", time.Date(2024, time.July, 15, 11, 30, 0, 0, time.UTC), false},
+		{"overnight in tokyo", str("22:00"), str("07:00"), "Asia/Tokyo", at(14, 0), true},
+		{"overnight in tokyo daytime", str("22:00"), str("07:00"), "Asia/Tokyo", at(3, 0), false},
+		{"unknown zone falls back to utc", str("22:00"), str("07:00"), "Mars/Olympus", at(23, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := models.NotificationPreferences{QuietHoursStart: tt.start, QuietHoursEnd: tt.end, Timezone: tt.timezone}
+			if got := InQuietHours(prefs, tt.now); got != tt.want {
+				t.Errorf("InQuietHours(%v-%v %s, %v) = %v, want %v", deref(tt.start), deref(tt.end), tt.timezone, tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
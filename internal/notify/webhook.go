@@ -0,0 +1,214 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// Webhook request headers; the signature is "sha256=" followed by the hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the user's webhook secret
+const (
+	SignatureHeader = "X-GoFlow-Signature"
+	TimestampHeader = "X-GoFlow-Timestamp"
+)
+
+const (
+	webhookAttempts = 4
+	webhookBackoff  = time.Second
+)
+
+// webhookPayload is the JSON body posted to webhooks
+type webhookPayload struct {
+	Title  string    `json:"title"`
+	Body   string    `json:"body"`
+	Data   any       `json:"data,omitempty"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+// ErrWebhookURL is returned for webhook URLs the server will not call
+var ErrWebhookURL = errors.New("webhook URL must be https and reach a public address")
+
+// WebhookChannel posts signed JSON to the user's webhook URL, retrying
+// network errors, 429s and 5xx responses with exponential backoff
+type WebhookChannel struct {
+	client *http.Client
+}
+
+// NewWebhookChannel returns a channel that posts with client, or by default
+// with a client that only connects to public addresses over https
+func NewWebhookChannel(client *http.Client) *WebhookChannel {
+	if client == nil {
+		client = newPublicClient()
+	}
+	return &WebhookChannel{
+		client: client,
+	}
+}
+
+// newPublicClient returns a client that refuses to connect to loopback,
+// private, link-local and other non-public addresses. The address is checked
+// as the connection is made, after DNS resolution, so a host that resolves
+// to a public address when saved and a private one later is still refused.
+func newPublicClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !isPublic(addrPort.Addr()) {
+				return ErrWebhookURL
+			}
+			return nil
+		},
+	}
+
+	// No proxy is configured, as it would connect on our behalf unchecked
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return ErrWebhookURL
+			}
+			if len(via) >= 5 {
+				return errors.New("webhook redirected too many times")
+			}
+			return nil
+		},
+	}
+}
+
+// ValidateWebhookURL rejects webhook URLs that are not https or that name a
+// host which is obviously not public. Hosts that resolve to non-public
+// addresses are refused when the webhook is called.
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+		return ErrWebhookURL
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrWebhookURL
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !isPublic(addr) {
+		return ErrWebhookURL
+	}
+	return nil
+}
+
+// isPublic reports whether addr is a globally routable unicast address
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() &&
+		!sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func (w *WebhookChannel) Name() string {
+	return models.ChannelWebhook
+}
+
+func (w *WebhookChannel) Send(ctx context.Context, to Recipient, msg Message) error {
+	body, err := json.Marshal(webhookPayload{
+		Title:  msg.Title,
+		Body:   msg.Body,
+		Data:   msg.Data,
+		SentAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := w.post(ctx, to.Preferences, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == webhookAttempts {
+			return fmt.Errorf("webhook failed after %d attempt(s): %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth retrying
+func (w *WebhookChannel) post(ctx context.Context, prefs models.NotificationPreferences, body []byte) (bool, error) {
+	if err := ValidateWebhookURL(prefs.WebhookURL); err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, prefs.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-flow-webhooks")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(prefs.WebhookSecret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, ErrWebhookURL), err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return false, fmt.Errorf("webhook returned %s", resp.Status)
+}
+
+// Sign returns the signature header value for a webhook body
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewWebhookSecret returns a random secret for signing a user's webhooks
+func NewWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,158 @@
+// Package paper matches simulated orders in paper trading accounts against
+// the prices the ingestion pipeline stores
+package paper
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go-flow/internal/models"
+	"go-flow/internal/repository"
+	"go-flow/internal/service"
+)
+
+// updateBuffer is how many price updates may queue before new ones are
+// dropped; the periodic sweep picks up anything that was missed
+const updateBuffer = 256
+
+// Engine matches open orders against every price published on the bus and
+// periodically expires day orders and rematches open orders against the
+// stored prices. Fills lock the order row and check it is still open, so any
+// number of engines can run against the same orders without double filling.
+type Engine struct {
+	orders   repository.PaperRepository
+	prices   *service.PriceBus
+	interval time.Duration
+}
+
+func NewEngine(orders repository.PaperRepository, prices *service.PriceBus, interval time.Duration) *Engine {
+	return &Engine{
+		orders:   orders,
+		prices:   prices,
+		interval: interval,
+	}
+}
+
+// Run matches orders until ctx is cancelled; a non-positive interval
+// disables the sweep
+func (e *Engine) Run(ctx context.Context) {
+	updates, unsubscribe := e.prices.Subscribe(updateBuffer)
+	defer unsubscribe()
+
+	var sweep <-chan time.Time
+	if e.interval > 0 {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		sweep = ticker.C
+
+		e.Sweep()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update := <-updates:
+			e.Match(update)
+		case <-sweep:
+			e.Sweep()
+		}
+	}
+}
+
+// Submit matches a newly placed order against the symbol's stored price, so
+// a marketable order fills at once, and cancels an immediate-or-cancel
+// order that did not. It returns the order in its current state.
+func (e *Engine) Submit(order *models.PaperOrder) (*models.PaperOrder, error) {
+	quotes, err := e.orders.Quotes([]string{order.Symbol})
+	if err != nil {
+		return nil, err
+	}
+
+	current := order
+	for _, quote := range quotes {
+		updated, err := e.match(*order, quote)
+		if err != nil {
+			return nil, err
+		}
+		if updated != nil {
+			current = updated
+		}
+	}
+
+	if current.Status == models.OrderStatusOpen && current.TimeInForce == models.TimeInForceIOC {
+		cancelled, err := e.orders.CloseOrder(current.ID, models.OrderStatusCancelled, "not filled immediately")
+		if err != nil {
+			return nil, err
+		}
+		if cancelled != nil {
+			current = cancelled
+		}
+	}
+
+	return current, nil
+}
+
+// Match fills or triggers the open orders for the symbol of a price update
+func (e *Engine) Match(update service.PriceUpdate) {
+	orders, err := e.orders.OpenOrders(update.Symbol)
+	if err != nil {
+		log.Printf("paper: failed to load orders for %s: %v", update.Symbol, err)
+		return
+	}
+
+	for _, order := range orders {
+		if _, err := e.match(order, update); err != nil {
+			log.Printf("paper: failed to match order %d: %v", order.ID, err)
+		}
+	}
+}
+
+// Sweep expires day orders past the close and matches the remaining open
+// orders against the stored prices
+func (e *Engine) Sweep() {
+	if _, err := e.orders.ExpireOrders(time.Now()); err != nil {
+		log.Printf("paper: sweep failed: %v", err)
+	}
+
+	symbols, err := e.orders.OpenSymbols()
+	if err != nil {
+		log.Printf("paper: sweep failed: %v", err)
+		return
+	}
+	if len(symbols) == 0 {
+		return
+	}
+
+	quotes, err := e.orders.Quotes(symbols)
+	if err != nil {
+		log.Printf("paper: sweep failed: %v", err)
+		return
+	}
+	for _, quote := range quotes {
+		e.Match(quote)
+	}
+}
+
+// match applies one price update to one order, returning the order if its
+// state changed
+func (e *Engine) match(order models.PaperOrder, update service.PriceUpdate) (*models.PaperOrder, error) {
+	if order.ExpiresAt != nil && !update.Time.Before(*order.ExpiresAt) {
+		return nil, nil
+	}
+
+	m := service.MatchOrder(order, update)
+	if m.Triggered && !m.Filled {
+		if err := e.orders.MarkTriggered(order.ID); err != nil {
+			return nil, err
+		}
+		order.StopTriggered = true
+		return &order, nil
+	}
+	if !m.Filled {
+		return nil, nil
+	}
+
+	return e.orders.FillOrder(order.ID, m.Price)
+}
@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-flow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type AlertRepository interface {
+	List(userID int64, active *bool) ([]models.StockAlert, error)
+	Get(id, userID int64) (*models.StockAlert, error)
+	Create(alert *models.StockAlert) error
+	Update(alert *models.StockAlert) error
+	Delete(id, userID int64) error
+	TriggerForPrice(symbol string, price float64) ([]models.StockAlert, error)
+	TriggerAll() ([]models.StockAlert, error)
+	ListActiveConditions(symbol string) ([]models.StockAlert, error)
+	ConditionSymbols() ([]string, error)
+	Trigger(ids []int64, price float64) ([]models.StockAlert, error)
+}
+
+type PostgresAlertRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewAlertRepository(conn *pgxpool.Pool) AlertRepository {
+	return &PostgresAlertRepository{
+		conn: conn,
+	}
+}
+
+const alertColumns = `id, user_id, symbol, alert_type, target_price, condition, is_active, created_at, triggered_at, triggered_price`
+
+// alertCrossed matches active alerts whose condition holds at the price
+// expression substituted for %[1]s
+const alertCrossed = `
+        is_active
+        AND ((alert_type = 'above' AND %[1]s >= target_price)
+          OR (alert_type = 'below' AND %[1]s <= target_price))
+    `
+
+func scanAlert(row pgx.Row) (*models.StockAlert, error) {
+	var alert models.StockAlert
+	err := row.Scan(
+		&alert.ID,
+		&alert.UserID,
+		&alert.Symbol,
+		&alert.AlertType,
+		&alert.TargetPrice,
+		&alert.Condition,
+		&alert.IsActive,
+		&alert.CreatedAt,
+		&alert.TriggeredAt,
+		&alert.TriggeredPrice,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func collectAlerts(rows pgx.Rows) ([]models.StockAlert, error) {
+	defer rows.Close()
+
+	alerts := []models.StockAlert{}
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		alerts = append(alerts, *alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+// List returns the user's alerts, newest first, optionally filtered by state
+func (r *PostgresAlertRepository) List(userID int64, active *bool) ([]models.StockAlert, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT `+alertColumns+`
+        FROM stock_alerts
+        WHERE user_id = $1 AND ($2::boolean IS NULL OR is_active = $2)
+        ORDER BY created_at DESC, id DESC
+    `, userID, active)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+
+	return collectAlerts(rows)
+}
+
+func (r *PostgresAlertRepository) Get(id, userID int64) (*models.StockAlert, error) {
+	ctx := context.Background()
+
+	alert, err := scanAlert(r.conn.QueryRow(ctx, `
+        SELECT `+alertColumns+`
+        FROM stock_alerts
+        WHERE id = $1 AND user_id = $2
+    `, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get alert: %w", err)
+	}
+
+	return alert, nil
+}
+
+func (r *PostgresAlertRepository) Create(alert *models.StockAlert) error {
+	ctx := context.Background()
+
+	query := `
+        INSERT INTO stock_alerts (user_id, symbol, alert_type, target_price, condition)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING ` + alertColumns
+
+	created, err := scanAlert(r.conn.QueryRow(ctx, query,
+		alert.UserID,
+		alert.Symbol,
+		alert.AlertType,
+		alert.TargetPrice,
+		alert.Condition,
+	))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create alert: %w", err)
+	}
+
+	*alert = *created
+	return nil
+}
+
+// Update changes an alert's condition and state; re-activating an alert
+// clears its trigger so it can fire again
+func (r *PostgresAlertRepository) Update(alert *models.StockAlert) error {
+	ctx := context.Background()
+
+	updated, err := scanAlert(r.conn.QueryRow(ctx, `
+        UPDATE stock_alerts
+        SET alert_type = $3,
+            target_price = $4,
+            condition = $5,
+            is_active = $6,
+            triggered_at = CASE WHEN $6 THEN NULL ELSE triggered_at END,
+            triggered_price = CASE WHEN $6 THEN NULL ELSE triggered_price END
+        WHERE id = $1 AND user_id = $2
+        RETURNING `+alertColumns,
+		alert.ID,
+		alert.UserID,
+		alert.AlertType,
+		alert.TargetPrice,
+		alert.Condition,
+		alert.IsActive,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update alert: %w", err)
+	}
+
+	*alert = *updated
+	return nil
+}
+
+func (r *PostgresAlertRepository) Delete(id, userID int64) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, "DELETE FROM stock_alerts WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// TriggerForPrice deactivates and returns the symbol's alerts that the price
+// satisfies. The check and the state change are a single UPDATE, so when
+// several evaluators race for the same alert only one of them gets it back.
+func (r *PostgresAlertRepository) TriggerForPrice(symbol string, price float64) ([]models.StockAlert, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        UPDATE stock_alerts
+        SET is_active = FALSE, triggered_at = NOW(), triggered_price = $2
+        WHERE symbol = $1 AND `+fmt.Sprintf(alertCrossed, "$2::numeric")+`
+        RETURNING `+alertColumns,
+		symbol, price)
+	if err != nil {
+		return nil, fmt.Errorf("failed to trigger alerts: %w", err)
+	}
+
+	return collectAlerts(rows)
+}
+
+// TriggerAll fires every active alert satisfied by its stock's stored last
+// price; it catches prices that were stored without being published
+func (r *PostgresAlertRepository) TriggerAll() ([]models.StockAlert, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        UPDATE stock_alerts a
+        SET is_active = FALSE, triggered_at = NOW(), triggered_price = s.last_price
+        FROM stocks s
+        WHERE s.symbol = a.symbol AND s.last_price IS NOT NULL AND `+fmt.Sprintf(alertCrossed, "s.last_price")+`
+        RETURNING a.id, a.user_id, a.symbol, a.alert_type, a.target_price, a.condition,
+                  a.is_active, a.created_at, a.triggered_at, a.triggered_price`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to trigger alerts: %w", err)
+	}
+
+	return collectAlerts(rows)
+}
+
+// ListActiveConditions returns the symbol's active condition alerts
+func (r *PostgresAlertRepository) ListActiveConditions(symbol string) ([]models.StockAlert, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT `+alertColumns+`
+        FROM stock_alerts
+        WHERE symbol = $1 AND is_active AND alert_type = 'condition'
+    `, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query condition alerts: %w", err)
+	}
+
+	return collectAlerts(rows)
+}
+
+// ConditionSymbols returns every symbol with an active condition alert
+func (r *PostgresAlertRepository) ConditionSymbols() ([]string, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT DISTINCT symbol
+        FROM stock_alerts
+        WHERE is_active AND alert_type = 'condition'
+        ORDER BY symbol
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query condition symbols: %w", err)
+	}
+
+	symbols, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan symbols: %w", err)
+	}
+
+	return symbols, nil
+}
+
+// Trigger deactivates and returns the given alerts that are still active;
+// alerts another evaluator fired first are left out
+func (r *PostgresAlertRepository) Trigger(ids []int64, price float64) ([]models.StockAlert, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        UPDATE stock_alerts
+        SET is_active = FALSE, triggered_at = NOW(), triggered_price = $2
+        WHERE id = ANY($1) AND is_active
+        RETURNING `+alertColumns,
+		ids, price)
+	if err != nil {
+		return nil, fmt.Errorf("failed to trigger alerts: %w", err)
+	}
+
+	return collectAlerts(rows)
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-flow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	ListByUser(userID int64) ([]models.APIKey, error)
+	GetForUser(id, userID int64) (*models.APIKey, error)
+	Update(key *models.APIKey) error
+	Revoke(id, userID int64) error
+	Authenticate(keyHash string) (*models.APIKey, error)
+	TouchLastUsed(id int64) error
+}
+
+type PostgresAPIKeyRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(conn *pgxpool.Pool) APIKeyRepository {
+	return &PostgresAPIKeyRepository{
+		conn: conn,
+	}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at`
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *PostgresAPIKeyRepository) Create(key *models.APIKey) error {
+	ctx := context.Background()
+
+	query := `
+        INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id, created_at
+    `
+
+	err := r.conn.QueryRow(ctx, query,
+		key.UserID,
+		key.Name,
+		key.Prefix,
+		key.KeyHash,
+		key.Scopes,
+		key.ExpiresAt,
+	).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser returns the user's keys that have not been revoked
+func (r *PostgresAPIKeyRepository) ListByUser(userID int64) ([]models.APIKey, error) {
+	ctx := context.Background()
+
+	query := `SELECT ` + apiKeyColumns + `
+        FROM api_keys
+        WHERE user_id = $1 AND revoked_at IS NULL
+        ORDER BY created_at DESC
+    `
+
+	rows, err := r.conn.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	return keys, rows.Err()
+}
+
+func (r *PostgresAPIKeyRepository) GetForUser(id, userID int64) (*models.APIKey, error) {
+	ctx := context.Background()
+
+	query := `SELECT ` + apiKeyColumns + `
+        FROM api_keys
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `
+
+	key, err := scanAPIKey(r.conn.QueryRow(ctx, query, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// Update changes the name, scopes and expiry of one of the user's keys
+func (r *PostgresAPIKeyRepository) Update(key *models.APIKey) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, `
+        UPDATE api_keys
+        SET name = $3, scopes = $4, expires_at = $5
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `, key.ID, key.UserID, key.Name, key.Scopes, key.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to update api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Revoke disables a key; revoked keys are kept for auditing
+func (r *PostgresAPIKeyRepository) Revoke(id, userID int64) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, `
+        UPDATE api_keys
+        SET revoked_at = NOW()
+        WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+    `, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Authenticate returns the active, unexpired key with the given hash
+func (r *PostgresAPIKeyRepository) Authenticate(keyHash string) (*models.APIKey, error) {
+	ctx := context.Background()
+
+	query := `SELECT ` + apiKeyColumns + `
+        FROM api_keys
+        WHERE key_hash = $1
+          AND revoked_at IS NULL
+          AND (expires_at IS NULL OR expires_at > NOW())
+    `
+
+	key, err := scanAPIKey(r.conn.QueryRow(ctx, query, keyHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to authenticate api key: %w", err)
+	}
+
+	return key, nil
+}
+
+// TouchLastUsed records key usage, writing at most once a minute per key
+func (r *PostgresAPIKeyRepository) TouchLastUsed(id int64) error {
+	ctx := context.Background()
+
+	_, err := r.conn.Exec(ctx, `
+        UPDATE api_keys
+        SET last_used_at = NOW()
+        WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+    `, id)
+	if err != nil {
+		return fmt.Errorf("failed to update api key usage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded or
+// belongs to a different sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// listSort describes a sortable stock list column for keyset pagination
+type listSort struct {
+	expr string // non-null SQL expression to order by
+	cast string // type the cursor value is cast back to
+}
+
+var stockListSorts = map[string]listSort{
+	"symbol":       {expr: "symbol", cast: "text"},
+	"name":         {expr: "name", cast: "text"},
+	"price":        {expr: "COALESCE(last_price, 0)", cast: "numeric"},
+	"market_cap":   {expr: "COALESCE(market_cap, 0)", cast: "bigint"},
+	"pe_ratio":     {expr: "COALESCE(pe_ratio, 0)", cast: "numeric"},
+	"last_updated": {expr: "created_at", cast: "timestamptz"},
+}
+
+// stockCursor is the position after the last row of a page. The sort value
+// is kept as the database's text form so it round-trips exactly.
+type stockCursor struct {
+	Sort   string `json:"s"`
+	Order  string `json:"o"`
+	Value  string `json:"v"`
+	Symbol string `json:"k"`
+}
+
+func encodeCursor(cursor stockCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw, sortBy, order string) (*stockCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor stockCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sortBy || cursor.Order != order {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
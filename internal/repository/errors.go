@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrNotFound is returned when a requested row does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrDuplicate is returned when an insert violates a unique constraint
+	ErrDuplicate = errors.New("duplicate")
+
+	// ErrInvalidReference is returned when a row refers to one that does not exist
+	ErrInvalidReference = errors.New("invalid reference")
+
+	// ErrOrderNotOpen is returned when a paper order has already been filled,
+	// cancelled, expired or rejected
+	ErrOrderNotOpen = errors.New("order is not open")
+
+	// ErrPaperPortfolio is returned when transactions are entered, deleted or
+	// imported by hand in a paper account, whose ledger only order fills write
+	ErrPaperPortfolio = errors.New("paper portfolio")
+
+	// ErrAlreadyRolledBack is returned when an import has already been rolled back
+	ErrAlreadyRolledBack = errors.New("import already rolled back")
+)
+
+// isForeignKeyViolation reports whether err is a PostgreSQL foreign_key_violation
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique_violation
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
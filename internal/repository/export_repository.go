@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-flow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// exportBatchSize is how many rows each FETCH from an export cursor returns
+const exportBatchSize = 1000
+
+// ExportRepository streams whole datasets row by row. Each method reads
+// through a server-side cursor and hands rows to fn one at a time, so an
+// export never holds more than one batch in memory. An error returned by fn
+// stops the export and is returned as is.
+type ExportRepository interface {
+	StreamHistory(symbol string, start, end *time.Time, fn func(models.StockHistoryEntry) error) error
+	StreamTransactions(portfolioID int64, fn func(models.Transaction) error) error
+	StreamSnapshots(portfolioID, userID int64, fn func(models.PortfolioSnapshot) error) error
+}
+
+type PostgresExportRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewExportRepository(conn *pgxpool.Pool) ExportRepository {
+	return &PostgresExportRepository{
+		conn: conn,
+	}
+}
+
+// StreamHistory streams a stock's daily history, oldest first, optionally
+// limited to a date range. It fails with ErrNotFound if the stock is not
+// stored.
+func (r *PostgresExportRepository) StreamHistory(symbol string, start, end *time.Time, fn func(models.StockHistoryEntry) error) error {
+	conditions := []string{"symbol = $1"}
+	args := []any{symbol}
+	if start != nil {
+		args = append(args, *start)
+		conditions = append(conditions, fmt.Sprintf("date >= $%d", len(args)))
+	}
+	if end != nil {
+		args = append(args, *end)
+		conditions = append(conditions, fmt.Sprintf("date <= $%d", len(args)))
+	}
+
+	query := `
+        SELECT date, open, high, low, close, volume, adj_close
+        FROM stock_history
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY date`
+
+	return r.stream(query, args,
+		func(ctx context.Context, tx pgx.Tx) error {
+			return requireRow(ctx, tx, "SELECT EXISTS (SELECT 1 FROM stocks WHERE symbol = $1)", symbol)
+		},
+		func(rows pgx.Rows) error {
+			var entry models.StockHistoryEntry
+			err := rows.Scan(&entry.Date, &entry.Open, &entry.High, &entry.Low, &entry.Close, &entry.Volume, &entry.AdjClose)
+			if err != nil {
+				return fmt.Errorf("failed to scan stock history entry: %w", err)
+			}
+			return fn(entry)
+		})
+}
+
+// StreamTransactions streams a portfolio's ledger in the order it is applied
+func (r *PostgresExportRepository) StreamTransactions(portfolioID int64, fn func(models.Transaction) error) error {
+	query := `
+        SELECT ` + transactionColumns + `
+        FROM transactions
+        WHERE portfolio_id = $1
+        ORDER BY executed_at, id`
+
+	return r.stream(query, []any{portfolioID},
+		func(ctx context.Context, tx pgx.Tx) error {
+			return requireRow(ctx, tx, "SELECT EXISTS (SELECT 1 FROM portfolios WHERE id = $1)", portfolioID)
+		},
+		func(rows pgx.Rows) error {
+			t, err := scanTransaction(rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan transaction: %w", err)
+			}
+			return fn(*t)
+		})
+}
+
+// StreamSnapshots streams a user's portfolio's stored daily valuations,
+// oldest first. It fails with ErrNotFound if the portfolio does not belong
+// to the user. Stored snapshots are streamed as they are, so after a ledger
+// or price change they trail until the refresher has rebuilt them.
+func (r *PostgresExportRepository) StreamSnapshots(portfolioID, userID int64, fn func(models.PortfolioSnapshot) error) error {
+	query := `
+        SELECT portfolio_id, date, cash, market_value, total_value, net_flow
+        FROM portfolio_snapshots
+        WHERE portfolio_id = $1
+        ORDER BY date`
+
+	return r.stream(query, []any{portfolioID},
+		func(ctx context.Context, tx pgx.Tx) error {
+			return requireRow(ctx, tx, "SELECT EXISTS (SELECT 1 FROM portfolios WHERE id = $1 AND user_id = $2)", portfolioID, userID)
+		},
+		func(rows pgx.Rows) error {
+			var s models.PortfolioSnapshot
+			if err := rows.Scan(&s.PortfolioID, &s.Date, &s.Cash, &s.MarketValue, &s.TotalValue, &s.NetFlow); err != nil {
+				return fmt.Errorf("failed to scan snapshot: %w", err)
+			}
+			return fn(s)
+		})
+}
+
+// stream runs check and then query in a read-only transaction, declaring a
+// cursor for the query and fetching it in batches. scan is called for every
+// row; nothing is passed on before check succeeds, so a caller that writes
+// rows as they come can still report a missing resource as an error.
+func (r *PostgresExportRepository) stream(query string, args []any, check func(context.Context, pgx.Tx) error, scan func(pgx.Rows) error) error {
+	ctx := context.Background()
+
+	tx, err := r.conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := check(ctx, tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "DECLARE export_cursor NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		return fmt.Errorf("failed to open export cursor: %w", err)
+	}
+
+	fetch := fmt.Sprintf("FETCH %d FROM export_cursor", exportBatchSize)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch export rows: %w", err)
+		}
+		n := 0
+		for rows.Next() {
+			n++
+			if err := scan(rows); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to fetch export rows: %w", err)
+		}
+		if n < exportBatchSize {
+			return nil
+		}
+	}
+}
+
+// requireRow runs an EXISTS query and returns ErrNotFound if it is false
+func requireRow(ctx context.Context, tx pgx.Tx, sql string, args ...any) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check export source: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"go-flow/internal/models"
+)
+
+// stockFieldColumns maps selectable Stock JSON fields to their SQL expressions
+var stockFieldColumns = map[string]string{
+	"symbol":         "symbol",
+	"name":           "name",
+	"current_price":  "COALESCE(last_price, 0)",
+	"sector":         "COALESCE(sector, '')",
+	"industry":       "COALESCE(industry, '')",
+	"market_cap":     "COALESCE(market_cap, 0)",
+	"pe_ratio":       "COALESCE(pe_ratio, 0)",
+	"dividend_yield": "COALESCE(dividend_yield, 0)",
+	"last_updated":   "created_at",
+}
+
+// defaultStockFields is used when a request does not ask for specific fields
+var defaultStockFields = []string{
+	"symbol", "name", "current_price", "sector", "industry",
+	"market_cap", "pe_ratio", "dividend_yield", "last_updated",
+}
+
+// historyFieldColumns maps selectable StockHistoryEntry JSON fields to columns
+var historyFieldColumns = map[string]string{
+	"date":      "date",
+	"open":      "open",
+	"high":      "high",
+	"low":       "low",
+	"close":     "close",
+	"volume":    "volume",
+	"adj_close": "adj_close",
+}
+
+var defaultHistoryFields = []string{"date", "open", "high", "low", "close", "volume", "adj_close"}
+
+// ParseStockFields validates a comma separated list of Stock fields
+func ParseStockFields(raw string) ([]string, error) {
+	return parseFields(raw, stockFieldColumns)
+}
+
+// ParseHistoryFields validates a comma separated list of history fields
+func ParseHistoryFields(raw string) ([]string, error) {
+	return parseFields(raw, historyFieldColumns)
+}
+
+func parseFields(raw string, columns map[string]string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	var fields []string
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[field] {
+			continue
+		}
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		seen[field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// selectColumns returns the SQL select list for the requested fields
+func selectColumns(fields []string, columns map[string]string) string {
+	exprs := make([]string, len(fields))
+	for i, field := range fields {
+		exprs[i] = columns[field]
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// stockScanTargets returns the scan destinations for the requested fields
+func stockScanTargets(stock *models.Stock, fields []string) []any {
+	targets := make([]any, len(fields))
+	for i, field := range fields {
+		switch field {
+		case "symbol":
+			targets[i] = &stock.Symbol
+		case "name":
+			targets[i] = &stock.Name
+		case "current_price":
+			targets[i] = &stock.CurrentPrice
+		case "sector":
+			targets[i] = &stock.Sector
+		case "industry":
+			targets[i] = &stock.Industry
+		case "market_cap":
+			targets[i] = &stock.MarketCap
+		case "pe_ratio":
+			targets[i] = &stock.PeRatio
+		case "dividend_yield":
+			targets[i] = &stock.DividendYield
+		case "last_updated":
+			targets[i] = &stock.LastUpdated
+		}
+	}
+	return targets
+}
+
+// historyScanTargets returns the scan destinations for the requested fields
+func historyScanTargets(entry *models.StockHistoryEntry, fields []string) []any {
+	targets := make([]any, len(fields))
+	for i, field := range fields {
+		switch field {
+		case "date":
+			targets[i] = &entry.Date
+		case "open":
+			targets[i] = &entry.Open
+		case "high":
+			targets[i] = &entry.High
+		case "low":
+			targets[i] = &entry.Low
+		case "close":
+			targets[i] = &entry.Close
+		case "volume":
+			targets[i] = &entry.Volume
+		case "adj_close":
+			targets[i] = &entry.AdjClose
+		}
+	}
+	return targets
+}
@@ -0,0 +1,367 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-flow/internal/models"
+	"go-flow/internal/service"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ImportError reports the statement row an import failed on because the
+// ledger would become inconsistent, such as a sale of shares the statement
+// never bought
+type ImportError struct {
+	Row int
+	Err error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+type ImportRepository interface {
+	Import(userID int64, imp *models.TransactionImport, rows []models.ImportRow, dryRun bool) (*models.ImportResult, error)
+	List(portfolioID, userID int64) ([]models.TransactionImport, error)
+	Get(portfolioID, userID, importID int64) (*models.TransactionImport, error)
+	Rollback(portfolioID, userID, importID int64) (*models.TransactionImport, error)
+}
+
+type PostgresImportRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewImportRepository(conn *pgxpool.Pool) ImportRepository {
+	return &PostgresImportRepository{
+		conn: conn,
+	}
+}
+
+const importColumns = `id, portfolio_id, format, layout, filename, status, imported, duplicates, skipped, created_at, rolled_back_at`
+
+func scanImport(row pgx.Row) (*models.TransactionImport, error) {
+	var imp models.TransactionImport
+	err := row.Scan(
+		&imp.ID,
+		&imp.PortfolioID,
+		&imp.Format,
+		&imp.Layout,
+		&imp.Filename,
+		&imp.Status,
+		&imp.Imported,
+		&imp.Duplicates,
+		&imp.Skipped,
+		&imp.CreatedAt,
+		&imp.RolledBackAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// Import applies parsed statement rows to one of the user's portfolios.
+// Rows whose broker ID the portfolio already has, or that appear twice in
+// the statement, are duplicates; rows that fail validation or name a stock
+// that is not stored are invalid. Only new rows are recorded, together with
+// the import they came from, and the portfolio is re-derived from its
+// ledger. A dry run does the same work, ledger replay included, and then
+// rolls it back, so its result previews exactly what an import would do.
+// Paper accounts cannot import statements.
+func (r *PostgresImportRepository) Import(userID int64, imp *models.TransactionImport, rows []models.ImportRow, dryRun bool) (*models.ImportResult, error) {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var method string
+	var paper bool
+	err = tx.QueryRow(ctx, "SELECT cost_basis_method, is_paper FROM portfolios WHERE id = $1 AND user_id = $2 FOR UPDATE", imp.PortfolioID, userID).Scan(&method, &paper)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock portfolio: %w", err)
+	}
+	if paper {
+		return nil, ErrPaperPortfolio
+	}
+
+	var externalIDs, symbols []string
+	for _, row := range rows {
+		if row.Status == models.ImportRowNew {
+			externalIDs = append(externalIDs, row.Transaction.ExternalID)
+			if row.Transaction.Symbol != "" {
+				symbols = append(symbols, row.Transaction.Symbol)
+			}
+		}
+	}
+	existing, err := queryStrings(ctx, tx, `
+        SELECT external_id FROM transactions WHERE portfolio_id = $1 AND external_id = ANY($2)
+    `, imp.PortfolioID, externalIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query imported transactions: %w", err)
+	}
+	stored, err := queryStrings(ctx, tx, "SELECT symbol FROM stocks WHERE symbol = ANY($1)", symbols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stocks: %w", err)
+	}
+
+	seen := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		seen[id] = true
+	}
+	known := make(map[string]bool, len(stored))
+	for _, symbol := range stored {
+		known[symbol] = true
+	}
+
+	rows = append([]models.ImportRow(nil), rows...)
+	for i := range rows {
+		row := &rows[i]
+		if row.Status != models.ImportRowNew {
+			continue
+		}
+		t := *row.Transaction
+		row.Transaction = &t
+		t.PortfolioID = imp.PortfolioID
+
+		switch {
+		case t.ExternalID != "" && seen[t.ExternalID]:
+			row.Status = models.ImportRowDuplicate
+		case t.Symbol != "" && !known[t.Symbol]:
+			row.Status, row.Error = models.ImportRowInvalid, "no stored stock for "+t.Symbol+"; fetch it first"
+		default:
+			if err := service.PrepareTransaction(&t, method); err != nil {
+				row.Status, row.Error = models.ImportRowInvalid, err.Error()
+			}
+		}
+		if t.ExternalID != "" {
+			seen[t.ExternalID] = true
+		}
+	}
+
+	imp.Status = models.ImportStatusCompleted
+	err = tx.QueryRow(ctx, `
+        INSERT INTO transaction_imports (portfolio_id, format, layout, filename)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, status, created_at
+    `, imp.PortfolioID, imp.Format, imp.Layout, imp.Filename).Scan(&imp.ID, &imp.Status, &imp.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import: %w", err)
+	}
+
+	// Remember which row each transaction came from to report ledger errors
+	rowOf := make(map[int64]int)
+	changed := time.Now()
+	for i := range rows {
+		if rows[i].Status != models.ImportRowNew {
+			continue
+		}
+		t := rows[i].Transaction
+		t.ImportID = &imp.ID
+		if err = insertTransaction(ctx, tx, t); err != nil {
+			return nil, err
+		}
+		rowOf[t.ID] = i
+		if t.ExecutedAt.Before(changed) {
+			changed = t.ExecutedAt
+		}
+	}
+
+	if _, err = rebuildPortfolio(ctx, tx, imp.PortfolioID, changed); err != nil {
+		var ledgerErr *service.LedgerError
+		if !errors.As(err, &ledgerErr) {
+			return nil, err
+		}
+		i, ok := rowOf[ledgerErr.TransactionID]
+		if !ok {
+			return nil, err
+		}
+		if !dryRun {
+			return nil, &ImportError{Row: rows[i].Row, Err: ledgerErr.Err}
+		}
+		rows[i].Status, rows[i].Error = models.ImportRowInvalid, ledgerErr.Err.Error()
+	}
+
+	result := &models.ImportResult{DryRun: dryRun, Rows: rows}
+	for i := range rows {
+		switch rows[i].Status {
+		case models.ImportRowNew:
+			result.New++
+		case models.ImportRowDuplicate:
+			result.Duplicates++
+		case models.ImportRowSkipped:
+			result.Skipped++
+		case models.ImportRowInvalid:
+			result.Invalid++
+		}
+		// Transaction IDs of a dry run were never committed
+		if dryRun && rows[i].Transaction != nil {
+			rows[i].Transaction.ID, rows[i].Transaction.ImportID = 0, nil
+		}
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	imp.Imported, imp.Duplicates, imp.Skipped = result.New, result.Duplicates, result.Skipped+result.Invalid
+	_, err = tx.Exec(ctx, `
+        UPDATE transaction_imports SET imported = $2, duplicates = $3, skipped = $4 WHERE id = $1
+    `, imp.ID, imp.Imported, imp.Duplicates, imp.Skipped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update import: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Import = imp
+	return result, nil
+}
+
+// List returns the import history of one of the user's portfolios, newest first
+func (r *PostgresImportRepository) List(portfolioID, userID int64) ([]models.TransactionImport, error) {
+	ctx := context.Background()
+
+	var exists bool
+	err := r.conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM portfolios WHERE id = $1 AND user_id = $2)", portfolioID, userID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT `+importColumns+`
+        FROM transaction_imports
+        WHERE portfolio_id = $1
+        ORDER BY created_at DESC, id DESC
+    `, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query imports: %w", err)
+	}
+	defer rows.Close()
+
+	imports := []models.TransactionImport{}
+	for rows.Next() {
+		imp, err := scanImport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan import: %w", err)
+		}
+		imports = append(imports, *imp)
+	}
+
+	return imports, rows.Err()
+}
+
+// Get returns one import of one of the user's portfolios
+func (r *PostgresImportRepository) Get(portfolioID, userID, importID int64) (*models.TransactionImport, error) {
+	ctx := context.Background()
+
+	imp, err := scanImport(r.conn.QueryRow(ctx, `
+        SELECT `+importColumns+`
+        FROM transaction_imports
+        WHERE id = $1 AND portfolio_id = $2
+          AND portfolio_id IN (SELECT id FROM portfolios WHERE user_id = $3)
+    `, importID, portfolioID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get import: %w", err)
+	}
+
+	return imp, nil
+}
+
+// Rollback deletes every transaction an import recorded and re-derives the
+// portfolio. The import stays in the history marked as rolled back. It fails
+// with a *service.LedgerError if later entries depend on the imported ones.
+func (r *PostgresImportRepository) Rollback(portfolioID, userID, importID int64) (*models.TransactionImport, error) {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked int64
+	err = tx.QueryRow(ctx, "SELECT id FROM portfolios WHERE id = $1 AND user_id = $2 FOR UPDATE", portfolioID, userID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to lock portfolio: %w", err)
+	}
+
+	var status string
+	err = tx.QueryRow(ctx, "SELECT status FROM transaction_imports WHERE id = $1 AND portfolio_id = $2", importID, portfolioID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get import: %w", err)
+	}
+	if status == models.ImportStatusRolledBack {
+		return nil, ErrAlreadyRolledBack
+	}
+
+	var earliest *time.Time
+	err = tx.QueryRow(ctx, `
+        WITH deleted AS (
+            DELETE FROM transactions WHERE import_id = $1 RETURNING executed_at
+        )
+        SELECT MIN(executed_at) FROM deleted
+    `, importID).Scan(&earliest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete imported transactions: %w", err)
+	}
+	changed := time.Now()
+	if earliest != nil {
+		changed = *earliest
+	}
+	if _, err = rebuildPortfolio(ctx, tx, portfolioID, changed); err != nil {
+		return nil, err
+	}
+
+	imp, err := scanImport(tx.QueryRow(ctx, `
+        UPDATE transaction_imports
+        SET status = $2, rolled_back_at = NOW()
+        WHERE id = $1
+        RETURNING `+importColumns,
+		importID, models.ImportStatusRolledBack))
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back import: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return imp, nil
+}
+
+// queryStrings runs a query returning a single text column
+func queryStrings(ctx context.Context, tx pgx.Tx, sql string, args ...any) ([]string, error) {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
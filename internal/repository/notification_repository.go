@@ -0,0 +1,288 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-flow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type NotificationRepository interface {
+	Create(notification *models.Notification) error
+	AddChannel(id int64, channel string) error
+	List(userID int64, unreadOnly bool, limit, offset int) ([]models.Notification, int, error)
+	UnreadCount(userID int64) (int, error)
+	SetRead(id, userID int64, read bool) (*models.Notification, error)
+	MarkAllRead(userID int64) (int64, error)
+	Delete(id, userID int64) error
+	CountExternalSince(userID int64, since time.Time) (int, error)
+	GetPreferences(userID int64) (*models.NotificationPreferences, error)
+	SavePreferences(prefs *models.NotificationPreferences) error
+}
+
+type PostgresNotificationRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewNotificationRepository(conn *pgxpool.Pool) NotificationRepository {
+	return &PostgresNotificationRepository{
+		conn: conn,
+	}
+}
+
+const notificationColumns = `id, user_id, alert_id, title, body, channels, read_at, created_at`
+
+// inboxFilter limits notifications to those delivered to the in-app inbox
+const inboxFilter = `user_id = $1 AND 'in_app' = ANY(channels)`
+
+func scanNotification(row pgx.Row) (*models.Notification, error) {
+	var n models.Notification
+	err := row.Scan(
+		&n.ID,
+		&n.UserID,
+		&n.AlertID,
+		&n.Title,
+		&n.Body,
+		&n.Channels,
+		&n.ReadAt,
+		&n.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *PostgresNotificationRepository) Create(n *models.Notification) error {
+	ctx := context.Background()
+
+	query := `
+        INSERT INTO notifications (user_id, alert_id, title, body, channels)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at
+    `
+
+	err := r.conn.QueryRow(ctx, query,
+		n.UserID,
+		n.AlertID,
+		n.Title,
+		n.Body,
+		n.Channels,
+	).Scan(&n.ID, &n.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
+
+// AddChannel records that a notification was delivered on a channel
+func (r *PostgresNotificationRepository) AddChannel(id int64, channel string) error {
+	ctx := context.Background()
+
+	_, err := r.conn.Exec(ctx, `
+        UPDATE notifications
+        SET channels = array_append(channels, $2)
+        WHERE id = $1 AND NOT ($2 = ANY(channels))
+    `, id, channel)
+	if err != nil {
+		return fmt.Errorf("failed to record notification channel: %w", err)
+	}
+
+	return nil
+}
+
+// List returns a page of the user's inbox, newest first, with the total count
+func (r *PostgresNotificationRepository) List(userID int64, unreadOnly bool, limit, offset int) ([]models.Notification, int, error) {
+	ctx := context.Background()
+
+	where := inboxFilter
+	if unreadOnly {
+		where += " AND read_at IS NULL"
+	}
+
+	var total int
+	if err := r.conn.QueryRow(ctx, "SELECT COUNT(*) FROM notifications WHERE "+where, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT `+notificationColumns+`
+        FROM notifications
+        WHERE `+where+`
+        ORDER BY created_at DESC, id DESC
+        LIMIT $2 OFFSET $3
+    `, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, *n)
+	}
+
+	return notifications, total, rows.Err()
+}
+
+func (r *PostgresNotificationRepository) UnreadCount(userID int64) (int, error) {
+	ctx := context.Background()
+
+	var count int
+	err := r.conn.QueryRow(ctx, "SELECT COUNT(*) FROM notifications WHERE "+inboxFilter+" AND read_at IS NULL", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	return count, nil
+}
+
+// SetRead marks an inbox notification read or unread
+func (r *PostgresNotificationRepository) SetRead(id, userID int64, read bool) (*models.Notification, error) {
+	ctx := context.Background()
+
+	n, err := scanNotification(r.conn.QueryRow(ctx, `
+        UPDATE notifications
+        SET read_at = CASE WHEN $3 THEN COALESCE(read_at, NOW()) END
+        WHERE id = $2 AND `+inboxFilter+`
+        RETURNING `+notificationColumns,
+		userID, id, read))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update notification: %w", err)
+	}
+
+	return n, nil
+}
+
+// MarkAllRead marks every unread inbox notification read and returns how many changed
+func (r *PostgresNotificationRepository) MarkAllRead(userID int64) (int64, error) {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, "UPDATE notifications SET read_at = NOW() WHERE "+inboxFilter+" AND read_at IS NULL", userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (r *PostgresNotificationRepository) Delete(id, userID int64) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, "DELETE FROM notifications WHERE id = $2 AND "+inboxFilter, userID, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// CountExternalSince counts the user's notifications sent by email or
+// webhook since the given time
+func (r *PostgresNotificationRepository) CountExternalSince(userID int64, since time.Time) (int, error) {
+	ctx := context.Background()
+
+	var count int
+	err := r.conn.QueryRow(ctx, `
+        SELECT COUNT(*)
+        FROM notifications
+        WHERE user_id = $1 AND created_at >= $2 AND channels && ARRAY['email', 'webhook']
+    `, userID, since).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetPreferences returns the user's saved preferences, or the defaults
+func (r *PostgresNotificationRepository) GetPreferences(userID int64) (*models.NotificationPreferences, error) {
+	ctx := context.Background()
+
+	var p models.NotificationPreferences
+	err := r.conn.QueryRow(ctx, `
+        SELECT user_id, in_app_enabled, email_enabled, webhook_enabled, webhook_url, webhook_secret,
+               quiet_hours_start, quiet_hours_end, timezone, max_per_hour, updated_at
+        FROM notification_preferences
+        WHERE user_id = $1
+    `, userID).Scan(
+		&p.UserID,
+		&p.InAppEnabled,
+		&p.EmailEnabled,
+		&p.WebhookEnabled,
+		&p.WebhookURL,
+		&p.WebhookSecret,
+		&p.QuietHoursStart,
+		&p.QuietHoursEnd,
+		&p.Timezone,
+		&p.MaxPerHour,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			defaults := models.DefaultNotificationPreferences(userID)
+			return &defaults, nil
+		}
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+
+	return &p, nil
+}
+
+// SavePreferences inserts or replaces the user's preferences
+func (r *PostgresNotificationRepository) SavePreferences(p *models.NotificationPreferences) error {
+	ctx := context.Background()
+
+	query := `
+        INSERT INTO notification_preferences (user_id, in_app_enabled, email_enabled, webhook_enabled,
+            webhook_url, webhook_secret, quiet_hours_start, quiet_hours_end, timezone, max_per_hour)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        ON CONFLICT (user_id)
+        DO UPDATE SET
+            in_app_enabled = EXCLUDED.in_app_enabled,
+            email_enabled = EXCLUDED.email_enabled,
+            webhook_enabled = EXCLUDED.webhook_enabled,
+            webhook_url = EXCLUDED.webhook_url,
+            webhook_secret = EXCLUDED.webhook_secret,
+            quiet_hours_start = EXCLUDED.quiet_hours_start,
+            quiet_hours_end = EXCLUDED.quiet_hours_end,
+            timezone = EXCLUDED.timezone,
+            max_per_hour = EXCLUDED.max_per_hour,
+            updated_at = NOW()
+        RETURNING updated_at
+    `
+
+	err := r.conn.QueryRow(ctx, query,
+		p.UserID,
+		p.InAppEnabled,
+		p.EmailEnabled,
+		p.WebhookEnabled,
+		p.WebhookURL,
+		p.WebhookSecret,
+		p.QuietHoursStart,
+		p.QuietHoursEnd,
+		p.Timezone,
+		p.MaxPerHour,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save notification preferences: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,417 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-flow/internal/models"
+	"go-flow/internal/service"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PaperRepository interface {
+	CreateOrder(userID int64, order *models.PaperOrder) error
+	ListOrders(portfolioID, userID int64, status string, limit, offset int) ([]models.PaperOrder, int, error)
+	GetOrder(portfolioID, userID, orderID int64) (*models.PaperOrder, error)
+	CancelOrder(portfolioID, userID, orderID int64) (*models.PaperOrder, error)
+	OpenOrders(symbol string) ([]models.PaperOrder, error)
+	OpenSymbols() ([]string, error)
+	Quotes(symbols []string) ([]service.PriceUpdate, error)
+	MarkTriggered(orderID int64) error
+	FillOrder(orderID int64, price float64) (*models.PaperOrder, error)
+	CloseOrder(orderID int64, status, reason string) (*models.PaperOrder, error)
+	ExpireOrders(now time.Time) (int64, error)
+}
+
+type PostgresPaperRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewPaperRepository(conn *pgxpool.Pool) PaperRepository {
+	return &PostgresPaperRepository{
+		conn: conn,
+	}
+}
+
+const paperOrderColumns = `id, portfolio_id, symbol, side, type, quantity, limit_price, stop_price, time_in_force, status,
+    stop_triggered, filled_price, filled_at, transaction_id, reject_reason, expires_at, created_at, updated_at`
+
+func scanPaperOrder(row pgx.Row) (*models.PaperOrder, error) {
+	var o models.PaperOrder
+	err := row.Scan(
+		&o.ID,
+		&o.PortfolioID,
+		&o.Symbol,
+		&o.Side,
+		&o.Type,
+		&o.Quantity,
+		&o.LimitPrice,
+		&o.StopPrice,
+		&o.TimeInForce,
+		&o.Status,
+		&o.StopTriggered,
+		&o.FilledPrice,
+		&o.FilledAt,
+		&o.TransactionID,
+		&o.RejectReason,
+		&o.ExpiresAt,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func collectPaperOrders(rows pgx.Rows) ([]models.PaperOrder, error) {
+	defer rows.Close()
+
+	orders := []models.PaperOrder{}
+	for rows.Next() {
+		o, err := scanPaperOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan paper order: %w", err)
+		}
+		orders = append(orders, *o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read paper orders: %w", err)
+	}
+
+	return orders, nil
+}
+
+// CreateOrder stores an open order in one of the user's paper accounts
+func (r *PostgresPaperRepository) CreateOrder(userID int64, o *models.PaperOrder) error {
+	ctx := context.Background()
+
+	created, err := scanPaperOrder(r.conn.QueryRow(ctx, `
+        INSERT INTO paper_orders (portfolio_id, symbol, side, type, quantity, limit_price, stop_price, time_in_force, expires_at)
+        SELECT id, $3, $4, $5, $6, $7, $8, $9, $10
+        FROM portfolios
+        WHERE id = $1 AND user_id = $2 AND is_paper
+        RETURNING `+paperOrderColumns,
+		o.PortfolioID, userID, o.Symbol, o.Side, o.Type, o.Quantity, o.LimitPrice, o.StopPrice, o.TimeInForce, o.ExpiresAt))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if isForeignKeyViolation(err) {
+			return ErrInvalidReference
+		}
+		return fmt.Errorf("failed to create paper order: %w", err)
+	}
+	*o = *created
+
+	return nil
+}
+
+// ListOrders returns a page of a paper account's orders, newest first,
+// optionally only those with the given status
+func (r *PostgresPaperRepository) ListOrders(portfolioID, userID int64, status string, limit, offset int) ([]models.PaperOrder, int, error) {
+	ctx := context.Background()
+
+	var total int
+	err := r.conn.QueryRow(ctx, `
+        SELECT COUNT(o.id)
+        FROM portfolios p
+        LEFT JOIN paper_orders o ON o.portfolio_id = p.id AND ($3 = '' OR o.status = $3)
+        WHERE p.id = $1 AND p.user_id = $2 AND p.is_paper
+        GROUP BY p.id
+    `, portfolioID, userID, status).Scan(&total)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to count paper orders: %w", err)
+	}
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT `+paperOrderColumns+`
+        FROM paper_orders
+        WHERE portfolio_id = $1 AND ($2 = '' OR status = $2)
+        ORDER BY created_at DESC, id DESC
+        LIMIT $3 OFFSET $4
+    `, portfolioID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query paper orders: %w", err)
+	}
+
+	orders, err := collectPaperOrders(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return orders, total, nil
+}
+
+// GetOrder returns an order from one of the user's paper accounts
+func (r *PostgresPaperRepository) GetOrder(portfolioID, userID, orderID int64) (*models.PaperOrder, error) {
+	ctx := context.Background()
+
+	o, err := scanPaperOrder(r.conn.QueryRow(ctx, `
+        SELECT `+paperOrderColumns+`
+        FROM paper_orders
+        WHERE id = $1 AND portfolio_id = $2
+          AND portfolio_id IN (SELECT id FROM portfolios WHERE user_id = $3)
+    `, orderID, portfolioID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get paper order: %w", err)
+	}
+
+	return o, nil
+}
+
+// CancelOrder cancels an open order in one of the user's paper accounts. It
+// fails with ErrOrderNotOpen once the order is no longer open.
+func (r *PostgresPaperRepository) CancelOrder(portfolioID, userID, orderID int64) (*models.PaperOrder, error) {
+	ctx := context.Background()
+
+	o, err := scanPaperOrder(r.conn.QueryRow(ctx, `
+        UPDATE paper_orders
+        SET status = $4, updated_at = NOW()
+        WHERE id = $1 AND portfolio_id = $2 AND status = $5
+          AND portfolio_id IN (SELECT id FROM portfolios WHERE user_id = $3)
+        RETURNING `+paperOrderColumns,
+		orderID, portfolioID, userID, models.OrderStatusCancelled, models.OrderStatusOpen))
+	if err == nil {
+		return o, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to cancel paper order: %w", err)
+	}
+
+	if _, err := r.GetOrder(portfolioID, userID, orderID); err != nil {
+		return nil, err
+	}
+	return nil, ErrOrderNotOpen
+}
+
+// OpenOrders returns every open order for a symbol, oldest first, so earlier
+// orders are matched first
+func (r *PostgresPaperRepository) OpenOrders(symbol string) ([]models.PaperOrder, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT `+paperOrderColumns+`
+        FROM paper_orders
+        WHERE symbol = $1 AND status = $2
+        ORDER BY created_at, id
+    `, symbol, models.OrderStatusOpen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open paper orders: %w", err)
+	}
+
+	return collectPaperOrders(rows)
+}
+
+// OpenSymbols returns the symbols that have open orders
+func (r *PostgresPaperRepository) OpenSymbols() ([]string, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, "SELECT DISTINCT symbol FROM paper_orders WHERE status = $1 ORDER BY symbol", models.OrderStatusOpen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query open paper order symbols: %w", err)
+	}
+
+	symbols, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read open paper order symbols: %w", err)
+	}
+	return symbols, nil
+}
+
+// Quotes returns the stored last price of each symbol that has one
+func (r *PostgresPaperRepository) Quotes(symbols []string) ([]service.PriceUpdate, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT symbol, last_price
+        FROM stocks
+        WHERE symbol = ANY($1) AND last_price IS NOT NULL
+        ORDER BY symbol
+    `, symbols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quotes: %w", err)
+	}
+	defer rows.Close()
+
+	var quotes []service.PriceUpdate
+	for rows.Next() {
+		q := service.PriceUpdate{Time: time.Now()}
+		if err := rows.Scan(&q.Symbol, &q.Price); err != nil {
+			return nil, fmt.Errorf("failed to scan quote: %w", err)
+		}
+		quotes = append(quotes, q)
+	}
+
+	return quotes, rows.Err()
+}
+
+// MarkTriggered records that an open stop-limit order's stop was reached,
+// turning it into a limit order
+func (r *PostgresPaperRepository) MarkTriggered(orderID int64) error {
+	ctx := context.Background()
+
+	_, err := r.conn.Exec(ctx, `
+        UPDATE paper_orders
+        SET stop_triggered = TRUE, updated_at = NOW()
+        WHERE id = $1 AND status = $2
+    `, orderID, models.OrderStatusOpen)
+	if err != nil {
+		return fmt.Errorf("failed to trigger paper order: %w", err)
+	}
+
+	return nil
+}
+
+// FillOrder fills an open order at price, recording the fill as a buy or
+// sell transaction of its paper account in the same database transaction.
+// An order the account cannot afford, or that sells more than is held, is
+// rejected instead. The order is returned in its final state, or nil if it
+// was no longer open.
+func (r *PostgresPaperRepository) FillOrder(orderID int64, price float64) (*models.PaperOrder, error) {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the account before the order, in the same order as ledger writes
+	var portfolioID int64
+	err = tx.QueryRow(ctx, "SELECT portfolio_id FROM paper_orders WHERE id = $1", orderID).Scan(&portfolioID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get paper order: %w", err)
+	}
+
+	var method string
+	var cash float64
+	err = tx.QueryRow(ctx, "SELECT cost_basis_method, cash_balance FROM portfolios WHERE id = $1 FOR UPDATE", portfolioID).Scan(&method, &cash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock portfolio: %w", err)
+	}
+
+	o, err := scanPaperOrder(tx.QueryRow(ctx, "SELECT "+paperOrderColumns+" FROM paper_orders WHERE id = $1 FOR UPDATE", orderID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock paper order: %w", err)
+	}
+	if o.Status != models.OrderStatusOpen {
+		return nil, nil
+	}
+
+	t := models.Transaction{
+		PortfolioID: portfolioID,
+		Symbol:      o.Symbol,
+		Type:        o.Side,
+		Quantity:    o.Quantity,
+		Price:       price,
+		Notes:       "Paper order " + strconv.FormatInt(o.ID, 10),
+		ExecutedAt:  time.Now(),
+	}
+
+	reason := ""
+	switch {
+	case o.Side == models.OrderBuy && o.Quantity*price > cash:
+		reason = "insufficient cash"
+	case o.Side == models.OrderSell:
+		var held float64
+		err = tx.QueryRow(ctx, "SELECT COALESCE(SUM(quantity), 0) FROM portfolio_positions WHERE portfolio_id = $1 AND symbol = $2", portfolioID, o.Symbol).Scan(&held)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get position: %w", err)
+		}
+		if o.Quantity > held {
+			reason = "insufficient shares"
+		}
+	}
+	if reason == "" {
+		if err := service.PrepareTransaction(&t, method); err != nil {
+			reason = err.Error()
+		}
+	}
+
+	if reason != "" {
+		o, err = scanPaperOrder(tx.QueryRow(ctx, `
+            UPDATE paper_orders
+            SET status = $2, reject_reason = $3, updated_at = NOW()
+            WHERE id = $1
+            RETURNING `+paperOrderColumns,
+			orderID, models.OrderStatusRejected, reason))
+		if err != nil {
+			return nil, fmt.Errorf("failed to reject paper order: %w", err)
+		}
+	} else {
+		if err = insertTransaction(ctx, tx, &t); err != nil {
+			return nil, err
+		}
+		if _, err = rebuildPortfolio(ctx, tx, portfolioID, t.ExecutedAt); err != nil {
+			return nil, err
+		}
+
+		o, err = scanPaperOrder(tx.QueryRow(ctx, `
+            UPDATE paper_orders
+            SET status = $2, filled_price = $3, filled_at = $4, transaction_id = $5, updated_at = NOW()
+            WHERE id = $1
+            RETURNING `+paperOrderColumns,
+			orderID, models.OrderStatusFilled, price, t.ExecutedAt, t.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fill paper order: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return o, nil
+}
+
+// CloseOrder moves an open order to a final status without filling it. The
+// order is returned in its final state, or nil if it was no longer open.
+func (r *PostgresPaperRepository) CloseOrder(orderID int64, status, reason string) (*models.PaperOrder, error) {
+	ctx := context.Background()
+
+	o, err := scanPaperOrder(r.conn.QueryRow(ctx, `
+        UPDATE paper_orders
+        SET status = $2, reject_reason = $3, updated_at = NOW()
+        WHERE id = $1 AND status = $4
+        RETURNING `+paperOrderColumns,
+		orderID, status, reason, models.OrderStatusOpen))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to close paper order: %w", err)
+	}
+
+	return o, nil
+}
+
+// ExpireOrders expires every open order whose time in force ended before now
+func (r *PostgresPaperRepository) ExpireOrders(now time.Time) (int64, error) {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, `
+        UPDATE paper_orders
+        SET status = $1, updated_at = NOW()
+        WHERE status = $2 AND expires_at <= $3
+    `, models.OrderStatusExpired, models.OrderStatusOpen, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire paper orders: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,802 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go-flow/internal/models"
+	"go-flow/internal/service"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PortfolioRepository interface {
+	List(userID int64) ([]models.Portfolio, error)
+	Get(id, userID int64) (*models.Portfolio, error)
+	Create(portfolio *models.Portfolio, initialDeposit float64) error
+	Update(portfolio *models.Portfolio) error
+	Delete(id, userID int64) error
+	ListTransactions(portfolioID, userID int64, limit, offset int) ([]models.Transaction, int, error)
+	RecordTransaction(userID int64, tx *models.Transaction) error
+	DeleteTransaction(portfolioID, userID, transactionID int64) error
+	Ledger(portfolioID, userID int64) (*service.Ledger, error)
+	Snapshots(portfolioID, userID int64) ([]models.PortfolioSnapshot, error)
+	RefreshStaleSnapshots() (int, error)
+}
+
+type PostgresPortfolioRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewPortfolioRepository(conn *pgxpool.Pool) PortfolioRepository {
+	return &PostgresPortfolioRepository{
+		conn: conn,
+	}
+}
+
+const portfolioColumns = `id, user_id, name, cash_balance, cost_basis_method, is_paper, created_at, updated_at`
+
+const transactionColumns = `id, portfolio_id, COALESCE(symbol, ''), type, quantity, price, fees, total_amount, split_ratio, lot_selections, notes, COALESCE(external_id, ''), import_id, executed_at, created_at`
+
+func scanPortfolio(row pgx.Row) (*models.Portfolio, error) {
+	var p models.Portfolio
+	err := row.Scan(
+		&p.ID,
+		&p.UserID,
+		&p.Name,
+		&p.CashBalance,
+		&p.CostBasisMethod,
+		&p.IsPaper,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func scanTransaction(row pgx.Row) (*models.Transaction, error) {
+	var tx models.Transaction
+	err := row.Scan(
+		&tx.ID,
+		&tx.PortfolioID,
+		&tx.Symbol,
+		&tx.Type,
+		&tx.Quantity,
+		&tx.Price,
+		&tx.Fees,
+		&tx.TotalAmount,
+		&tx.SplitRatio,
+		&tx.LotSelections,
+		&tx.Notes,
+		&tx.ExternalID,
+		&tx.ImportID,
+		&tx.ExecutedAt,
+		&tx.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// List returns the user's portfolios with their positions priced
+func (r *PostgresPortfolioRepository) List(userID int64) ([]models.Portfolio, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, "SELECT "+portfolioColumns+" FROM portfolios WHERE user_id = $1 ORDER BY name", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query portfolios: %w", err)
+	}
+	defer rows.Close()
+
+	portfolios := []models.Portfolio{}
+	var ids []int64
+	for rows.Next() {
+		p, err := scanPortfolio(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan portfolio: %w", err)
+		}
+		portfolios = append(portfolios, *p)
+		ids = append(ids, p.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read portfolios: %w", err)
+	}
+
+	positions, err := r.positions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range portfolios {
+		portfolios[i].Positions = positions[portfolios[i].ID]
+	}
+
+	return portfolios, nil
+}
+
+// Get returns one of the user's portfolios with its positions priced
+func (r *PostgresPortfolioRepository) Get(id, userID int64) (*models.Portfolio, error) {
+	ctx := context.Background()
+
+	p, err := scanPortfolio(r.conn.QueryRow(ctx, "SELECT "+portfolioColumns+" FROM portfolios WHERE id = $1 AND user_id = $2", id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+
+	positions, err := r.positions(ctx, []int64{id})
+	if err != nil {
+		return nil, err
+	}
+	p.Positions = positions[id]
+
+	return p, nil
+}
+
+// positions loads the positions of the given portfolios with each symbol's
+// latest price and previous close
+func (r *PostgresPortfolioRepository) positions(ctx context.Context, portfolioIDs []int64) (map[int64][]models.PortfolioPosition, error) {
+	positions := make(map[int64][]models.PortfolioPosition)
+	if len(portfolioIDs) == 0 {
+		return positions, nil
+	}
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT p.id, p.portfolio_id, p.symbol, p.quantity, p.average_cost, p.created_at, p.updated_at,
+               s.last_price, COALESCE(h.closes, '{}')
+        FROM portfolio_positions p
+        JOIN stocks s ON s.symbol = p.symbol
+        LEFT JOIN LATERAL (
+            SELECT array_agg(close ORDER BY date) AS closes
+            FROM (
+                SELECT date, close
+                FROM stock_history
+                WHERE symbol = p.symbol
+                ORDER BY date DESC
+                LIMIT 2
+            ) recent
+        ) h ON TRUE
+        WHERE p.portfolio_id = ANY($1)
+        ORDER BY p.symbol
+    `, portfolioIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query positions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pos models.PortfolioPosition
+		var lastPrice *float64
+		var closes []float64
+		err := rows.Scan(
+			&pos.ID,
+			&pos.PortfolioID,
+			&pos.Symbol,
+			&pos.Quantity,
+			&pos.AverageCost,
+			&pos.CreatedAt,
+			&pos.UpdatedAt,
+			&lastPrice,
+			&closes,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan position: %w", err)
+		}
+
+		if n := len(closes); n > 0 {
+			pos.CurrentPrice = closes[n-1]
+			if n > 1 {
+				pos.PreviousClose = closes[n-2]
+			}
+		}
+		if lastPrice != nil {
+			pos.CurrentPrice = *lastPrice
+		}
+
+		positions[pos.PortfolioID] = append(positions[pos.PortfolioID], pos)
+	}
+
+	return positions, rows.Err()
+}
+
+// Create stores a new portfolio, recording initialDeposit as its first
+// transaction when it is positive
+func (r *PostgresPortfolioRepository) Create(p *models.Portfolio, initialDeposit float64) error {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+        INSERT INTO portfolios (user_id, name, cost_basis_method, is_paper)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at, updated_at
+    `, p.UserID, p.Name, p.CostBasisMethod, p.IsPaper).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("failed to create portfolio: %w", err)
+	}
+
+	if initialDeposit > 0 {
+		_, err = tx.Exec(ctx, `
+            INSERT INTO transactions (portfolio_id, type, total_amount, notes)
+            VALUES ($1, $2, $3, 'Initial deposit')
+        `, p.ID, models.TransactionDeposit, initialDeposit)
+		if err != nil {
+			return fmt.Errorf("failed to record initial deposit: %w", err)
+		}
+		if _, err = rebuildPortfolio(ctx, tx, p.ID, p.CreatedAt); err != nil {
+			return err
+		}
+		p.CashBalance = initialDeposit
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Update changes a portfolio's name and cost basis method, re-deriving its
+// positions when the method changes
+func (r *PostgresPortfolioRepository) Update(p *models.Portfolio) error {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previousMethod string
+	err = tx.QueryRow(ctx, "SELECT cost_basis_method FROM portfolios WHERE id = $1 AND user_id = $2 FOR UPDATE", p.ID, p.UserID).Scan(&previousMethod)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to lock portfolio: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+        UPDATE portfolios
+        SET name = $2, cost_basis_method = $3, updated_at = NOW()
+        WHERE id = $1
+        RETURNING updated_at
+    `, p.ID, p.Name, p.CostBasisMethod).Scan(&p.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("failed to update portfolio: %w", err)
+	}
+
+	if p.CostBasisMethod != previousMethod {
+		if _, err = rebuildPortfolio(ctx, tx, p.ID, time.Time{}); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a portfolio with its positions and transactions
+func (r *PostgresPortfolioRepository) Delete(id, userID int64) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, "DELETE FROM portfolios WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete portfolio: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ListTransactions returns a page of a portfolio's transactions, newest first
+func (r *PostgresPortfolioRepository) ListTransactions(portfolioID, userID int64, limit, offset int) ([]models.Transaction, int, error) {
+	ctx := context.Background()
+
+	var total int
+	err := r.conn.QueryRow(ctx, `
+        SELECT COUNT(t.id)
+        FROM portfolios p
+        LEFT JOIN transactions t ON t.portfolio_id = p.id
+        WHERE p.id = $1 AND p.user_id = $2
+        GROUP BY p.id
+    `, portfolioID, userID).Scan(&total)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, 0, ErrNotFound
+		}
+		return nil, 0, fmt.Errorf("failed to count transactions: %w", err)
+	}
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT `+transactionColumns+`
+        FROM transactions
+        WHERE portfolio_id = $1
+        ORDER BY executed_at DESC, id DESC
+        LIMIT $2 OFFSET $3
+    `, portfolioID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		tx, err := scanTransaction(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, *tx)
+	}
+
+	return transactions, total, rows.Err()
+}
+
+// RecordTransaction validates and stores a ledger entry, then re-derives the
+// portfolio's positions and cash from the whole ledger in the same database
+// transaction. The portfolio row is locked first so concurrent entries apply
+// one after another; an entry that leaves the ledger inconsistent, such as a
+// backdated sale of shares not yet held, is rolled back. Paper accounts
+// only take fills, so entries in them fail with ErrPaperPortfolio.
+func (r *PostgresPortfolioRepository) RecordTransaction(userID int64, t *models.Transaction) error {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var method string
+	var paper bool
+	err = tx.QueryRow(ctx, "SELECT cost_basis_method, is_paper FROM portfolios WHERE id = $1 AND user_id = $2 FOR UPDATE", t.PortfolioID, userID).Scan(&method, &paper)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to lock portfolio: %w", err)
+	}
+	if paper {
+		return ErrPaperPortfolio
+	}
+
+	if err = service.PrepareTransaction(t, method); err != nil {
+		return err
+	}
+	if t.ExecutedAt.IsZero() {
+		t.ExecutedAt = time.Now()
+	}
+
+	if err = insertTransaction(ctx, tx, t); err != nil {
+		return err
+	}
+
+	if _, err = rebuildPortfolio(ctx, tx, t.PortfolioID, t.ExecutedAt); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTransaction removes a ledger entry and re-derives the portfolio. It
+// fails with a *service.LedgerError if later entries depend on it, and with
+// ErrPaperPortfolio in a paper account.
+func (r *PostgresPortfolioRepository) DeleteTransaction(portfolioID, userID, transactionID int64) error {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var paper bool
+	err = tx.QueryRow(ctx, "SELECT is_paper FROM portfolios WHERE id = $1 AND user_id = $2 FOR UPDATE", portfolioID, userID).Scan(&paper)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to lock portfolio: %w", err)
+	}
+	if paper {
+		return ErrPaperPortfolio
+	}
+
+	var executedAt time.Time
+	err = tx.QueryRow(ctx, "DELETE FROM transactions WHERE id = $1 AND portfolio_id = $2 RETURNING executed_at", transactionID, portfolioID).Scan(&executedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to delete transaction: %w", err)
+	}
+
+	if _, err = rebuildPortfolio(ctx, tx, portfolioID, executedAt); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Ledger replays one of the user's portfolios from its transactions
+func (r *PostgresPortfolioRepository) Ledger(portfolioID, userID int64) (*service.Ledger, error) {
+	ctx := context.Background()
+
+	tx, err := r.conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var method string
+	err = tx.QueryRow(ctx, "SELECT cost_basis_method FROM portfolios WHERE id = $1 AND user_id = $2", portfolioID, userID).Scan(&method)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+
+	return replayPortfolio(ctx, tx, portfolioID, method)
+}
+
+// insertTransaction stores a prepared transaction and replaces t with the
+// stored row
+func insertTransaction(ctx context.Context, tx pgx.Tx, t *models.Transaction) error {
+	var lots []byte
+	if len(t.LotSelections) > 0 {
+		var err error
+		if lots, err = json.Marshal(t.LotSelections); err != nil {
+			return fmt.Errorf("failed to encode lot selections: %w", err)
+		}
+	}
+
+	created, err := scanTransaction(tx.QueryRow(ctx, `
+        INSERT INTO transactions (portfolio_id, symbol, type, quantity, price, fees, total_amount, split_ratio, lot_selections, notes, external_id, import_id, executed_at)
+        VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, $13)
+        RETURNING `+transactionColumns,
+		t.PortfolioID, t.Symbol, t.Type, t.Quantity, t.Price, t.Fees, t.TotalAmount, t.SplitRatio, lots, t.Notes, t.ExternalID, t.ImportID, t.ExecutedAt))
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrInvalidReference
+		}
+		return fmt.Errorf("failed to record transaction: %w", err)
+	}
+	*t = *created
+
+	return nil
+}
+
+// replayPortfolio loads every transaction of a portfolio and replays them
+func replayPortfolio(ctx context.Context, tx pgx.Tx, portfolioID int64, method string) (*service.Ledger, error) {
+	transactions, err := ledgerTransactions(ctx, tx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	return service.ReplayLedger(transactions, method)
+}
+
+// ledgerTransactions loads every transaction of a portfolio in replay order
+func ledgerTransactions(ctx context.Context, tx pgx.Tx, portfolioID int64) ([]models.Transaction, error) {
+	rows, err := tx.Query(ctx, `
+        SELECT `+transactionColumns+`
+        FROM transactions
+        WHERE portfolio_id = $1
+        ORDER BY executed_at, id
+    `, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan transaction: %w", err)
+		}
+		transactions = append(transactions, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transactions: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// rebuildPortfolio replays a locked portfolio's ledger and writes the derived
+// positions and cash balance back. changed is when the earliest entry that
+// was added, removed or revalued took effect; snapshots from then on are
+// marked stale.
+func rebuildPortfolio(ctx context.Context, tx pgx.Tx, portfolioID int64, changed time.Time) (*service.Ledger, error) {
+	var method string
+	if err := tx.QueryRow(ctx, "SELECT cost_basis_method FROM portfolios WHERE id = $1", portfolioID).Scan(&method); err != nil {
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+
+	ledger, err := replayPortfolio(ctx, tx, portfolioID, method)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := ledger.Positions()
+	symbols := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		_, err := tx.Exec(ctx, `
+            INSERT INTO portfolio_positions (portfolio_id, symbol, quantity, average_cost)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (portfolio_id, symbol)
+            DO UPDATE SET
+                quantity = EXCLUDED.quantity,
+                average_cost = EXCLUDED.average_cost,
+                updated_at = NOW()
+            WHERE portfolio_positions.quantity <> EXCLUDED.quantity
+               OR portfolio_positions.average_cost <> EXCLUDED.average_cost
+        `, portfolioID, pos.Symbol, pos.Quantity, pos.AverageCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update position: %w", err)
+		}
+		symbols = append(symbols, pos.Symbol)
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM portfolio_positions WHERE portfolio_id = $1 AND symbol <> ALL($2)", portfolioID, symbols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove closed positions: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE portfolios SET cash_balance = $2, updated_at = NOW() WHERE id = $1", portfolioID, ledger.Cash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update cash balance: %w", err)
+	}
+
+	if err = markSnapshotsStale(ctx, tx, portfolioID, changed); err != nil {
+		return nil, err
+	}
+
+	return ledger, nil
+}
+
+// Snapshots returns a portfolio's daily valuations, oldest first, without
+// writing anything. Stored snapshots are returned as they are unless a
+// ledger or price change has marked them stale, in which case the series is
+// built from the ledger in memory until the refresher catches up.
+func (r *PostgresPortfolioRepository) Snapshots(portfolioID, userID int64) ([]models.PortfolioSnapshot, error) {
+	ctx := context.Background()
+
+	tx, err := r.conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly, IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var method string
+	var staleFrom *time.Time
+	err = tx.QueryRow(ctx, "SELECT cost_basis_method, snapshots_stale_from FROM portfolios WHERE id = $1 AND user_id = $2", portfolioID, userID).Scan(&method, &staleFrom)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get portfolio: %w", err)
+	}
+
+	if staleFrom != nil {
+		return buildSnapshots(ctx, tx, portfolioID, method)
+	}
+
+	rows, err := tx.Query(ctx, `
+        SELECT portfolio_id, date, cash, market_value, total_value, net_flow
+        FROM portfolio_snapshots
+        WHERE portfolio_id = $1
+        ORDER BY date
+    `, portfolioID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []models.PortfolioSnapshot{}
+	for rows.Next() {
+		var s models.PortfolioSnapshot
+		if err := rows.Scan(&s.PortfolioID, &s.Date, &s.Cash, &s.MarketValue, &s.TotalValue, &s.NetFlow); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+
+	return snapshots, nil
+}
+
+// RefreshStaleSnapshots rebuilds the stored snapshots of every portfolio
+// marked stale, replacing only the days from the earliest change on, and
+// returns how many portfolios it refreshed
+func (r *PostgresPortfolioRepository) RefreshStaleSnapshots() (int, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, "SELECT id FROM portfolios WHERE snapshots_stale_from IS NOT NULL ORDER BY id")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query stale portfolios: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stale portfolios: %w", err)
+	}
+
+	// One portfolio failing to rebuild does not hold up the others
+	refreshed := 0
+	var errs []error
+	for _, id := range ids {
+		ok, err := r.refreshSnapshots(ctx, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("portfolio %d: %w", id, err))
+			continue
+		}
+		if ok {
+			refreshed++
+		}
+	}
+
+	return refreshed, errors.Join(errs...)
+}
+
+// refreshSnapshots rebuilds one stale portfolio's snapshots. The portfolio
+// row is locked so ledger writes wait, and another refresher that got there
+// first leaves nothing to do.
+func (r *PostgresPortfolioRepository) refreshSnapshots(ctx context.Context, portfolioID int64) (bool, error) {
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var method string
+	var staleFrom *time.Time
+	err = tx.QueryRow(ctx, "SELECT cost_basis_method, snapshots_stale_from FROM portfolios WHERE id = $1 FOR UPDATE", portfolioID).Scan(&method, &staleFrom)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to lock portfolio: %w", err)
+	}
+	if staleFrom == nil {
+		return false, nil
+	}
+
+	snapshots, err := buildSnapshots(ctx, tx, portfolioID, method)
+	if err != nil {
+		return false, err
+	}
+	changed := slices.IndexFunc(snapshots, func(s models.PortfolioSnapshot) bool {
+		return !s.Date.Before(*staleFrom)
+	})
+	if changed < 0 {
+		changed = len(snapshots)
+	}
+	snapshots = snapshots[changed:]
+
+	if _, err = tx.Exec(ctx, "DELETE FROM portfolio_snapshots WHERE portfolio_id = $1 AND date >= $2", portfolioID, *staleFrom); err != nil {
+		return false, fmt.Errorf("failed to clear snapshots: %w", err)
+	}
+	_, err = tx.CopyFrom(ctx,
+		pgx.Identifier{"portfolio_snapshots"},
+		[]string{"portfolio_id", "date", "cash", "market_value", "total_value", "net_flow"},
+		pgx.CopyFromSlice(len(snapshots), func(i int) ([]any, error) {
+			s := snapshots[i]
+			return []any{portfolioID, s.Date, s.Cash, s.MarketValue, s.TotalValue, s.NetFlow}, nil
+		}),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to store snapshots: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, "UPDATE portfolios SET snapshots_stale_from = NULL WHERE id = $1", portfolioID); err != nil {
+		return false, fmt.Errorf("failed to clear stale marker: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// buildSnapshots derives a portfolio's daily valuations from its ledger and
+// the stored closes of every symbol it has held
+func buildSnapshots(ctx context.Context, tx pgx.Tx, portfolioID int64, method string) ([]models.PortfolioSnapshot, error) {
+	transactions, err := ledgerTransactions(ctx, tx, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	closes := make(map[string][]models.StockHistoryEntry)
+	if len(transactions) > 0 {
+		var symbols []string
+		for _, t := range transactions {
+			if t.Symbol != "" && !slices.Contains(symbols, t.Symbol) {
+				symbols = append(symbols, t.Symbol)
+			}
+		}
+
+		// A week of earlier closes prices holdings opened on a non-trading day
+		since := transactions[0].ExecutedAt.AddDate(0, 0, -7)
+		rows, err := tx.Query(ctx, `
+            SELECT symbol, date, close
+            FROM stock_history
+            WHERE symbol = ANY($1) AND date >= $2
+            ORDER BY symbol, date
+        `, symbols, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query closes: %w", err)
+		}
+		for rows.Next() {
+			var symbol string
+			var entry models.StockHistoryEntry
+			if err := rows.Scan(&symbol, &entry.Date, &entry.Close); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan close: %w", err)
+			}
+			closes[symbol] = append(closes[symbol], entry)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read closes: %w", err)
+		}
+	}
+
+	snapshots, err := service.BuildSnapshots(transactions, method, closes, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		snapshots[i].PortfolioID = portfolioID
+	}
+	return snapshots, nil
+}
+
+// markSnapshotsStale records that a portfolio's valuations from the trading
+// day of from on may have changed; the zero time marks the whole series
+func markSnapshotsStale(ctx context.Context, tx pgx.Tx, portfolioID int64, from time.Time) error {
+	_, err := tx.Exec(ctx, `
+        UPDATE portfolios
+        SET snapshots_stale_from = LEAST(COALESCE(snapshots_stale_from, $2::date), $2::date)
+        WHERE id = $1
+    `, portfolioID, from.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to mark snapshots stale: %w", err)
+	}
+	return nil
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 )
 
-func NewDBConnection(ctx context.Context) (*pgx.Conn, error) {
+// NewDBConnection returns a connection pool, which is safe to share between
+// concurrent requests and background workers.
+func NewDBConnection(ctx context.Context) (*pgxpool.Pool, error) {
 	// 1. Load environment variables from .env file.
 	err := godotenv.Load()
 	if err != nil {
@@ -22,18 +24,19 @@ func NewDBConnection(ctx context.Context) (*pgx.Conn, error) {
 		return nil, fmt.Errorf("DATABASE_URL environment variable is not set")
 	}
 
-	// 3. Connect to the database using the pgx driver.
-	conn, err := pgx.Connect(ctx, dbURL)
+	// 3. Connect to the database using the pgx connection pool.
+	pool, err := pgxpool.New(ctx, dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("unable to connect to database: %w", err)
 	}
 
 	// 4. Ping the database to ensure the connection is active.
-	err = conn.Ping(ctx)
+	err = pool.Ping(ctx)
 	if err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("ping failed: %w", err)
 	}
 
 	fmt.Println("Successfully connected to the database!")
-	return conn, nil
+	return pool, nil
 }
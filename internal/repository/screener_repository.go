@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"go-flow/internal/models"
+)
+
+// screenerSortColumns whitelists the columns a screener can sort by
+var screenerSortColumns = map[string]string{
+	"symbol":         "s.symbol",
+	"name":           "s.name",
+	"price":          "s.last_price",
+	"volume":         "h.volume",
+	"market_cap":     "s.market_cap",
+	"pe_ratio":       "s.pe_ratio",
+	"dividend_yield": "s.dividend_yield",
+	"sector":         "s.sector",
+	"industry":       "s.industry",
+	"last_updated":   "s.created_at",
+}
+
+// IsValidScreenerSort reports whether sortBy can be used to order screener results
+func IsValidScreenerSort(sortBy string) bool {
+	_, ok := screenerSortColumns[sortBy]
+	return ok
+}
+
+// Screen returns the stocks matching the request filters and the total number
+// of matches. Results are only paged when req.Limit is positive.
+func (r *PostgresStockRepository) Screen(req *models.StockScreenerRequest) ([]models.Stock, int, error) {
+	ctx := context.Background()
+
+	// Build the WHERE clause from the optional filters
+	var conditions []string
+	var args []any
+	addCondition := func(format string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if req.MinPrice != nil {
+		addCondition("s.last_price >= $%d", *req.MinPrice)
+	}
+	if req.MaxPrice != nil {
+		addCondition("s.last_price <= $%d", *req.MaxPrice)
+	}
+	if req.MinVolume != nil {
+		addCondition("h.volume >= $%d", *req.MinVolume)
+	}
+	if req.MinMarketCap != nil {
+		addCondition("s.market_cap >= $%d", *req.MinMarketCap)
+	}
+	if req.MaxMarketCap != nil {
+		addCondition("s.market_cap <= $%d", *req.MaxMarketCap)
+	}
+	if req.Sector != "" {
+		addCondition("s.sector ILIKE $%d", escapeLike(req.Sector))
+	}
+	if req.Industry != "" {
+		addCondition("s.industry ILIKE $%d", escapeLike(req.Industry))
+	}
+	if req.MinPeRatio != nil {
+		addCondition("s.pe_ratio >= $%d", *req.MinPeRatio)
+	}
+	if req.MaxPeRatio != nil {
+		addCondition("s.pe_ratio <= $%d", *req.MaxPeRatio)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Sort columns come from the whitelist, never from the request directly
+	sortColumn, ok := screenerSortColumns[req.SortBy]
+	if !ok {
+		sortColumn = screenerSortColumns["symbol"]
+	}
+	sortOrder := "ASC"
+	if strings.EqualFold(req.SortOrder, "desc") {
+		sortOrder = "DESC"
+	}
+
+	query := fmt.Sprintf(`
+        SELECT s.symbol, s.name, s.last_price, s.sector, s.industry, s.market_cap,
+               s.pe_ratio, s.dividend_yield, h.volume, s.created_at,
+               COUNT(*) OVER () AS total
+        FROM stocks s
+        LEFT JOIN LATERAL (
+            SELECT volume FROM stock_history
+            WHERE symbol = s.symbol
+            ORDER BY date DESC
+            LIMIT 1
+        ) h ON TRUE
+        %s
+        ORDER BY %s %s NULLS LAST, s.symbol ASC
+    `, where, sortColumn, sortOrder)
+
+	if req.Limit > 0 {
+		args = append(args, req.Limit, req.Offset)
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to screen stocks: %w", err)
+	}
+	defer rows.Close()
+
+	stocks := []models.Stock{}
+	total := 0
+	for rows.Next() {
+		var stock models.Stock
+		var lastPrice, peRatio, dividendYield sql.NullFloat64
+		var sector, industry sql.NullString
+		var marketCap, volume sql.NullInt64
+
+		err := rows.Scan(
+			&stock.Symbol,
+			&stock.Name,
+			&lastPrice,
+			&sector,
+			&industry,
+			&marketCap,
+			&peRatio,
+			&dividendYield,
+			&volume,
+			&stock.LastUpdated,
+			&total,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan stock: %w", err)
+		}
+
+		stock.CurrentPrice = lastPrice.Float64
+		stock.Sector = sector.String
+		stock.Industry = industry.String
+		stock.MarketCap = marketCap.Int64
+		stock.PeRatio = peRatio.Float64
+		stock.DividendYield = dividendYield.Float64
+		stock.Volume = volume.Int64
+
+		stocks = append(stocks, stock)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read screener results: %w", err)
+	}
+
+	// COUNT(*) OVER () is unavailable when the page is past the last row
+	if len(stocks) == 0 && req.Limit > 0 && req.Offset > 0 {
+		countQuery := fmt.Sprintf(`
+            SELECT COUNT(*)
+            FROM stocks s
+            LEFT JOIN LATERAL (
+                SELECT volume FROM stock_history
+                WHERE symbol = s.symbol
+                ORDER BY date DESC
+                LIMIT 1
+            ) h ON TRUE
+            %s
+        `, where)
+		if err := r.conn.QueryRow(ctx, countQuery, args[:len(args)-2]...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count stocks: %w", err)
+		}
+	}
+
+	return stocks, total, nil
+}
+
+// GetHistory returns up to limit of the most recent history entries for a
+// symbol, ordered from oldest to newest
+func (r *PostgresStockRepository) GetHistory(symbol string, limit int) ([]models.StockHistoryEntry, error) {
+	ctx := context.Background()
+
+	query := `
+        SELECT date, open, high, low, close, volume, adj_close
+        FROM (
+            SELECT date, open, high, low, close, volume, adj_close
+            FROM stock_history
+            WHERE symbol = $1
+            ORDER BY date DESC
+            LIMIT $2
+        ) recent
+        ORDER BY date ASC
+    `
+
+	rows, err := r.conn.Query(ctx, query, symbol, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.StockHistoryEntry
+	for rows.Next() {
+		var entry models.StockHistoryEntry
+		err := rows.Scan(
+			&entry.Date,
+			&entry.Open,
+			&entry.High,
+			&entry.Low,
+			&entry.Close,
+			&entry.Volume,
+			&entry.AdjClose,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan stock history entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
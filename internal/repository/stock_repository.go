@@ -2,98 +2,182 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"go-flow/internal/models"
 	"go-flow/internal/service"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type StockRepository interface {
-	GetAll() ([]models.Stock, error)
-	GetByID(id string) (*models.Stock, error)
-	GetBySymbol(symbol string) (*models.Stock, error)
+	GetAll(q models.StockListQuery) (*models.StockPage, error)
+	GetByID(id string, fields []string) (*models.Stock, error)
+	GetBySymbol(symbol string, fields []string) (*models.Stock, error)
+	GetBySymbols(symbols []string) ([]models.Stock, error)
+	ListSymbols() ([]string, error)
 	SaveStock(stock *models.Stock) error
 	SaveStockData(data []service.StockData) error
 	SaveStockHistory(entries []models.StockHistoryEntry) error
+	GetHistory(symbol string, limit int) ([]models.StockHistoryEntry, error)
+	QueryHistory(symbol string, q models.StockHistoryQuery) ([]models.StockHistoryEntry, error)
+	QueryHistories(symbols []string, q models.StockHistoryQuery) (map[string][]models.StockHistoryEntry, error)
+	Screen(req *models.StockScreenerRequest) ([]models.Stock, int, error)
 }
 
 type PostgresStockRepository struct {
-	conn *pgx.Conn
+	conn *pgxpool.Pool
 }
 
-func NewStockRepository(conn *pgx.Conn) StockRepository {
+func NewStockRepository(conn *pgxpool.Pool) StockRepository {
 	return &PostgresStockRepository{
 		conn: conn,
 	}
 }
 
-func (r *PostgresStockRepository) GetAll() ([]models.Stock, error) {
+// GetAll returns one page of stocks matching the query. Pages are keyed on the
+// sort column and symbol, so the cursor stays stable while rows are added.
+func (r *PostgresStockRepository) GetAll(q models.StockListQuery) (*models.StockPage, error) {
 	ctx := context.Background()
 
-	query := `
-        SELECT symbol, name, last_price, created_at 
+	fields := q.Fields
+	if len(fields) == 0 {
+		fields = defaultStockFields
+	}
+	if q.SortBy == "" {
+		q.SortBy = "last_updated"
+		if q.SortOrder == "" {
+			q.SortOrder = "desc"
+		}
+	}
+	if q.SortOrder == "" {
+		q.SortOrder = "asc"
+	}
+	sort := stockListSorts[q.SortBy]
+
+	// Build the filters shared by the page and the total count
+	var conditions []string
+	var args []any
+	addCondition := func(format string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if q.Sector != "" {
+		addCondition(`sector ILIKE $%d ESCAPE '\'`, escapeLike(q.Sector))
+	}
+	if q.Industry != "" {
+		addCondition(`industry ILIKE $%d ESCAPE '\'`, escapeLike(q.Industry))
+	}
+	if q.MinPrice != nil {
+		addCondition("last_price >= $%d", *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		addCondition("last_price <= $%d", *q.MaxPrice)
+	}
+	if q.Search != "" {
+		args = append(args, "%"+escapeLike(q.Search)+"%")
+		conditions = append(conditions, fmt.Sprintf(`(name ILIKE $%d ESCAPE '\' OR symbol ILIKE $%d ESCAPE '\')`, len(args), len(args)))
+	}
+
+	filterWhere := ""
+	if len(conditions) > 0 {
+		filterWhere = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM stocks " + filterWhere
+	if err := r.conn.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count stocks: %w", err)
+	}
+
+	// Continue after the cursor position in the requested direction
+	direction, comparison := "ASC", ">"
+	if q.SortOrder == "desc" {
+		direction, comparison = "DESC", "<"
+	}
+	if q.Cursor != "" {
+		cursor, err := decodeCursor(q.Cursor, q.SortBy, q.SortOrder)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, cursor.Value, cursor.Symbol)
+		conditions = append(conditions, fmt.Sprintf("(%s, symbol) %s ($%d::%s, $%d)",
+			sort.expr, comparison, len(args)-1, sort.cast, len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Fetch one extra row to find out whether there is a next page
+	args = append(args, q.Limit+1)
+	query := fmt.Sprintf(`
+        SELECT %s, (%s)::text, symbol
         FROM stocks 
-        ORDER BY created_at DESC
-    `
+        %s
+        ORDER BY %s %s, symbol %s
+        LIMIT $%d
+    `, selectColumns(fields, stockFieldColumns), sort.expr, where, sort.expr, direction, direction, len(args))
 
-	rows, err := r.conn.Query(ctx, query)
+	rows, err := r.conn.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query stocks: %w", err)
 	}
 	defer rows.Close()
 
-	var stocks []models.Stock
+	page := &models.StockPage{Stocks: []models.Stock{}, Total: total}
+	var last stockCursor
 	for rows.Next() {
 		var stock models.Stock
-		var lastPrice sql.NullFloat64
+		var sortValue, symbol string
 
-		err := rows.Scan(
-			&stock.Symbol,
-			&stock.Name,
-			&lastPrice,
-			&stock.LastUpdated,
-		)
-		if err != nil {
+		targets := append(stockScanTargets(&stock, fields), &sortValue, &symbol)
+		if err := rows.Scan(targets...); err != nil {
 			return nil, fmt.Errorf("failed to scan stock: %w", err)
 		}
 
-		if lastPrice.Valid {
-			stock.CurrentPrice = lastPrice.Float64
+		if len(page.Stocks) == q.Limit {
+			page.NextCursor = encodeCursor(last)
+			break
 		}
 
-		stocks = append(stocks, stock)
+		page.Stocks = append(page.Stocks, stock)
+		last = stockCursor{Sort: q.SortBy, Order: q.SortOrder, Value: sortValue, Symbol: symbol}
 	}
 
-	return stocks, rows.Err()
+	return page, rows.Err()
 }
 
-func (r *PostgresStockRepository) GetByID(id string) (*models.Stock, error) {
+// escapeLike escapes the LIKE wildcards in user supplied search text
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *PostgresStockRepository) GetByID(id string, fields []string) (*models.Stock, error) {
 	// For now, treat ID as symbol since your primary key is symbol
-	return r.GetBySymbol(id)
+	return r.GetBySymbol(id, fields)
 }
 
-func (r *PostgresStockRepository) GetBySymbol(symbol string) (*models.Stock, error) {
+func (r *PostgresStockRepository) GetBySymbol(symbol string, fields []string) (*models.Stock, error) {
 	ctx := context.Background()
 
-	query := `
-        SELECT symbol, name, last_price, created_at 
+	if len(fields) == 0 {
+		fields = defaultStockFields
+	}
+
+	query := fmt.Sprintf(`
+        SELECT %s 
         FROM stocks 
         WHERE symbol = $1
-    `
+    `, selectColumns(fields, stockFieldColumns))
 
 	var stock models.Stock
-	var lastPrice sql.NullFloat64
-
-	err := r.conn.QueryRow(ctx, query, symbol).Scan(
-		&stock.Symbol,
-		&stock.Name,
-		&lastPrice,
-		&stock.LastUpdated,
-	)
+	err := r.conn.QueryRow(ctx, query, symbol).Scan(stockScanTargets(&stock, fields)...)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -102,11 +186,151 @@ func (r *PostgresStockRepository) GetBySymbol(symbol string) (*models.Stock, err
 		return nil, fmt.Errorf("failed to get stock: %w", err)
 	}
 
-	if lastPrice.Valid {
-		stock.CurrentPrice = lastPrice.Float64
+	return &stock, nil
+}
+
+// GetBySymbols returns the stored stocks among symbols in one query
+func (r *PostgresStockRepository) GetBySymbols(symbols []string) ([]models.Stock, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT `+selectColumns(defaultStockFields, stockFieldColumns)+`
+        FROM stocks
+        WHERE symbol = ANY($1)
+    `, symbols)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stocks: %w", err)
 	}
+	defer rows.Close()
 
-	return &stock, nil
+	stocks := []models.Stock{}
+	for rows.Next() {
+		var stock models.Stock
+		if err := rows.Scan(stockScanTargets(&stock, defaultStockFields)...); err != nil {
+			return nil, fmt.Errorf("failed to scan stock: %w", err)
+		}
+		stocks = append(stocks, stock)
+	}
+
+	return stocks, rows.Err()
+}
+
+// QueryHistory returns the requested history fields for a symbol, limited to
+// the most recent entries in the range and ordered from oldest to newest
+func (r *PostgresStockRepository) QueryHistory(symbol string, q models.StockHistoryQuery) ([]models.StockHistoryEntry, error) {
+	ctx := context.Background()
+
+	fields := q.Fields
+	if len(fields) == 0 {
+		fields = defaultHistoryFields
+	}
+
+	conditions := []string{"symbol = $1"}
+	args := []any{symbol}
+	if q.Start != nil {
+		args = append(args, *q.Start)
+		conditions = append(conditions, fmt.Sprintf("date >= $%d", len(args)))
+	}
+	if q.End != nil {
+		args = append(args, *q.End)
+		conditions = append(conditions, fmt.Sprintf("date <= $%d", len(args)))
+	}
+	args = append(args, q.Limit)
+
+	// The inner query keeps every column so the outer one can order by date
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM (
+            SELECT *
+            FROM stock_history
+            WHERE %s
+            ORDER BY date DESC
+            LIMIT $%d
+        ) recent
+        ORDER BY date ASC
+    `, selectColumns(fields, historyFieldColumns), strings.Join(conditions, " AND "), len(args))
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.StockHistoryEntry{}
+	for rows.Next() {
+		var entry models.StockHistoryEntry
+		if err := rows.Scan(historyScanTargets(&entry, fields)...); err != nil {
+			return nil, fmt.Errorf("failed to scan stock history entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// QueryHistories is QueryHistory for several symbols in one query: each
+// symbol gets its own most recent q.Limit entries in the range, oldest first.
+// Every field is returned.
+func (r *PostgresStockRepository) QueryHistories(symbols []string, q models.StockHistoryQuery) (map[string][]models.StockHistoryEntry, error) {
+	ctx := context.Background()
+
+	conditions := []string{"symbol = ANY($1)"}
+	args := []any{symbols}
+	if q.Start != nil {
+		args = append(args, *q.Start)
+		conditions = append(conditions, fmt.Sprintf("date >= $%d", len(args)))
+	}
+	if q.End != nil {
+		args = append(args, *q.End)
+		conditions = append(conditions, fmt.Sprintf("date <= $%d", len(args)))
+	}
+	args = append(args, q.Limit)
+
+	query := fmt.Sprintf(`
+        SELECT symbol, %s
+        FROM (
+            SELECT *, ROW_NUMBER() OVER (PARTITION BY symbol ORDER BY date DESC) AS recency
+            FROM stock_history
+            WHERE %s
+        ) ranked
+        WHERE recency <= $%d
+        ORDER BY symbol, date ASC
+    `, selectColumns(defaultHistoryFields, historyFieldColumns), strings.Join(conditions, " AND "), len(args))
+
+	rows, err := r.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock history: %w", err)
+	}
+	defer rows.Close()
+
+	histories := make(map[string][]models.StockHistoryEntry)
+	for rows.Next() {
+		var symbol string
+		var entry models.StockHistoryEntry
+		if err := rows.Scan(append([]any{&symbol}, historyScanTargets(&entry, defaultHistoryFields)...)...); err != nil {
+			return nil, fmt.Errorf("failed to scan stock history entry: %w", err)
+		}
+		histories[symbol] = append(histories[symbol], entry)
+	}
+
+	return histories, rows.Err()
+}
+
+// ListSymbols returns every stored symbol in alphabetical order
+func (r *PostgresStockRepository) ListSymbols() ([]string, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, "SELECT symbol FROM stocks ORDER BY symbol")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query symbols: %w", err)
+	}
+
+	symbols, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan symbols: %w", err)
+	}
+
+	return symbols, nil
 }
 
 func (r *PostgresStockRepository) SaveStock(stock *models.Stock) error {
@@ -167,7 +391,8 @@ func (r *PostgresStockRepository) SaveStockData(data []service.StockData) error
 		}
 	}
 
-	// Then save the historical data
+	// Then save the historical data; rows that are already stored as they
+	// are do not count as changed
 	historyQuery := `
         INSERT INTO stock_history (symbol, date, open, high, low, close, volume, adj_close) 
         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -179,8 +404,11 @@ func (r *PostgresStockRepository) SaveStockData(data []service.StockData) error
             close = EXCLUDED.close,
             volume = EXCLUDED.volume,
             adj_close = EXCLUDED.adj_close
+        WHERE (stock_history.open, stock_history.high, stock_history.low, stock_history.close, stock_history.volume, stock_history.adj_close)
+            IS DISTINCT FROM (EXCLUDED.open, EXCLUDED.high, EXCLUDED.low, EXCLUDED.close, EXCLUDED.volume, EXCLUDED.adj_close)
     `
 
+	changed := make(map[string]time.Time)
 	for _, entry := range data {
 		// Parse the date string to time.Time
 		date, err := time.Parse("2006-01-02", entry.Date)
@@ -188,7 +416,7 @@ func (r *PostgresStockRepository) SaveStockData(data []service.StockData) error
 			continue // Skip invalid dates
 		}
 
-		_, err = tx.Exec(ctx, historyQuery,
+		tag, err := tx.Exec(ctx, historyQuery,
 			entry.Symbol,
 			date,
 			entry.Open,
@@ -201,6 +429,22 @@ func (r *PostgresStockRepository) SaveStockData(data []service.StockData) error
 		if err != nil {
 			return fmt.Errorf("failed to save stock history entry: %w", err)
 		}
+		if first, ok := changed[entry.Symbol]; tag.RowsAffected() > 0 && (!ok || date.Before(first)) {
+			changed[entry.Symbol] = date
+		}
+	}
+
+	// New or corrected closes change the valuations of every portfolio that
+	// has traded the symbol from that day on
+	for symbol, from := range changed {
+		_, err = tx.Exec(ctx, `
+            UPDATE portfolios p
+            SET snapshots_stale_from = LEAST(COALESCE(p.snapshots_stale_from, $2::date), $2::date)
+            WHERE EXISTS (SELECT 1 FROM transactions t WHERE t.portfolio_id = p.id AND t.symbol = $1)
+        `, symbol, from)
+		if err != nil {
+			return fmt.Errorf("failed to mark snapshots stale: %w", err)
+		}
 	}
 
 	// Commit the transaction
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-flow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrTokenReused is returned when a refresh token that was already rotated or
+// revoked is presented again
+var ErrTokenReused = errors.New("refresh token reused")
+
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	Get(id string) (*models.RefreshToken, error)
+	Rotate(oldID string, next *models.RefreshToken) error
+	RevokeFamily(familyID string) error
+	RevokeAllForUser(userID int64) error
+	IsSessionActive(familyID string) (bool, error)
+}
+
+type PostgresRefreshTokenRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewRefreshTokenRepository(conn *pgxpool.Pool) RefreshTokenRepository {
+	return &PostgresRefreshTokenRepository{
+		conn: conn,
+	}
+}
+
+func (r *PostgresRefreshTokenRepository) Create(token *models.RefreshToken) error {
+	ctx := context.Background()
+
+	query := `
+        INSERT INTO refresh_tokens (id, user_id, family_id, expires_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING created_at
+    `
+
+	err := r.conn.QueryRow(ctx, query, token.ID, token.UserID, token.FamilyID, token.ExpiresAt).Scan(&token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRefreshTokenRepository) Get(id string) (*models.RefreshToken, error) {
+	ctx := context.Background()
+
+	query := `
+        SELECT id, user_id, family_id, expires_at, revoked_at, replaced_by, created_at
+        FROM refresh_tokens
+        WHERE id = $1
+    `
+
+	var token models.RefreshToken
+	err := r.conn.QueryRow(ctx, query, id).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+		&token.ReplacedBy,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// Rotate revokes the old token and stores its replacement in one transaction.
+// Only one caller can rotate a given token; everyone else gets ErrTokenReused.
+func (r *PostgresRefreshTokenRepository) Rotate(oldID string, next *models.RefreshToken) error {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+        UPDATE refresh_tokens
+        SET revoked_at = NOW(), replaced_by = $2
+        WHERE id = $1 AND revoked_at IS NULL
+    `, oldID, next.ID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTokenReused
+	}
+
+	err = tx.QueryRow(ctx, `
+        INSERT INTO refresh_tokens (id, user_id, family_id, expires_at)
+        VALUES ($1, $2, $3, $4)
+        RETURNING created_at
+    `, next.ID, next.UserID, next.FamilyID, next.ExpiresAt).Scan(&next.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeFamily ends a session by revoking every token issued for it
+func (r *PostgresRefreshTokenRepository) RevokeFamily(familyID string) error {
+	ctx := context.Background()
+
+	_, err := r.conn.Exec(ctx, `
+        UPDATE refresh_tokens
+        SET revoked_at = NOW()
+        WHERE family_id = $1 AND revoked_at IS NULL
+    `, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAllForUser ends every session of a user
+func (r *PostgresRefreshTokenRepository) RevokeAllForUser(userID int64) error {
+	ctx := context.Background()
+
+	_, err := r.conn.Exec(ctx, `
+        UPDATE refresh_tokens
+        SET revoked_at = NOW()
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+// IsSessionActive reports whether a session still has a usable refresh token
+func (r *PostgresRefreshTokenRepository) IsSessionActive(familyID string) (bool, error) {
+	ctx := context.Background()
+
+	var active bool
+	err := r.conn.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM refresh_tokens
+            WHERE family_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+        )
+    `, familyID).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+
+	return active, nil
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-flow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UserRepository interface {
+	Create(user *models.User) error
+	GetByID(id int64) (*models.User, error)
+	GetByLogin(login string) (*models.User, error)
+	List(limit, offset int) ([]models.User, int, error)
+	UpdateRole(id int64, role string) error
+	Delete(id int64) error
+}
+
+type PostgresUserRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewUserRepository(conn *pgxpool.Pool) UserRepository {
+	return &PostgresUserRepository{
+		conn: conn,
+	}
+}
+
+// Create inserts a user and fills in the generated ID and timestamps
+func (r *PostgresUserRepository) Create(user *models.User) error {
+	ctx := context.Background()
+
+	query := `
+        INSERT INTO users (email, username, password_hash)
+        VALUES ($1, $2, $3)
+        RETURNING id, role, created_at, updated_at
+    `
+
+	err := r.conn.QueryRow(ctx, query, user.Email, user.Username, user.PasswordHash).Scan(
+		&user.ID,
+		&user.Role,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresUserRepository) GetByID(id int64) (*models.User, error) {
+	query := `
+        SELECT id, email, username, password_hash, role, created_at, updated_at
+        FROM users
+        WHERE id = $1
+    `
+
+	return r.getOne(query, id)
+}
+
+// GetByLogin looks a user up by email (case-insensitive) or username
+func (r *PostgresUserRepository) GetByLogin(login string) (*models.User, error) {
+	query := `
+        SELECT id, email, username, password_hash, role, created_at, updated_at
+        FROM users
+        WHERE LOWER(email) = LOWER($1) OR username = $1
+        LIMIT 1
+    `
+
+	return r.getOne(query, login)
+}
+
+func (r *PostgresUserRepository) getOne(query string, arg any) (*models.User, error) {
+	ctx := context.Background()
+
+	user, err := scanUser(r.conn.QueryRow(ctx, query, arg))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// List returns a page of users ordered by ID and the total number of users
+func (r *PostgresUserRepository) List(limit, offset int) ([]models.User, int, error) {
+	ctx := context.Background()
+
+	var total int
+	if err := r.conn.QueryRow(ctx, "SELECT COUNT(*) FROM users").Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT id, email, username, password_hash, role, created_at, updated_at
+        FROM users
+        ORDER BY id
+        LIMIT $1 OFFSET $2
+    `, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, *user)
+	}
+
+	return users, total, rows.Err()
+}
+
+func (r *PostgresUserRepository) UpdateRole(id int64, role string) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, `
+        UPDATE users
+        SET role = $2, updated_at = NOW()
+        WHERE id = $1
+    `, id, role)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a user; sessions and API keys are removed by cascade
+func (r *PostgresUserRepository) Delete(id int64) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, "DELETE FROM users WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func scanUser(row pgx.Row) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.Username,
+		&user.PasswordHash,
+		&user.Role,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
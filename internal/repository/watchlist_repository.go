@@ -0,0 +1,514 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"go-flow/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WatchlistRepository interface {
+	List(userID int64) ([]models.Watchlist, error)
+	Get(id, userID int64) (*models.Watchlist, error)
+	Create(watchlist *models.Watchlist) error
+	Update(watchlist *models.Watchlist) error
+	Delete(id, userID int64) error
+	ListItems(watchlistID, userID int64) ([]models.StockWatchlist, error)
+	ListItemsFor(watchlistIDs []int64, userID int64) (map[int64][]models.StockWatchlist, error)
+	AddItem(item *models.StockWatchlist) error
+	UpdateItem(watchlistID, userID int64, symbol string, notes *string, position *int) (*models.StockWatchlist, error)
+	RemoveItem(watchlistID, userID int64, symbol string) error
+	ReorderItems(watchlistID, userID int64, symbols []string) error
+	GetView(watchlistID, userID int64, sparklineDays int) ([]models.WatchlistItemView, error)
+}
+
+type PostgresWatchlistRepository struct {
+	conn *pgxpool.Pool
+}
+
+func NewWatchlistRepository(conn *pgxpool.Pool) WatchlistRepository {
+	return &PostgresWatchlistRepository{
+		conn: conn,
+	}
+}
+
+const watchlistSelect = `
+        SELECT w.id, w.user_id, w.name, w.description, w.position,
+               (SELECT COUNT(*) FROM stock_watchlist sw WHERE sw.watchlist_id = w.id),
+               w.created_at, w.updated_at
+        FROM watchlists w
+    `
+
+func scanWatchlist(row pgx.Row) (*models.Watchlist, error) {
+	var w models.Watchlist
+	err := row.Scan(
+		&w.ID,
+		&w.UserID,
+		&w.Name,
+		&w.Description,
+		&w.Position,
+		&w.ItemCount,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// List returns the user's watchlists in display order
+func (r *PostgresWatchlistRepository) List(userID int64) ([]models.Watchlist, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, watchlistSelect+`
+        WHERE w.user_id = $1
+        ORDER BY w.position, w.id
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlists: %w", err)
+	}
+	defer rows.Close()
+
+	watchlists := []models.Watchlist{}
+	for rows.Next() {
+		w, err := scanWatchlist(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist: %w", err)
+		}
+		watchlists = append(watchlists, *w)
+	}
+
+	return watchlists, rows.Err()
+}
+
+func (r *PostgresWatchlistRepository) Get(id, userID int64) (*models.Watchlist, error) {
+	ctx := context.Background()
+
+	w, err := scanWatchlist(r.conn.QueryRow(ctx, watchlistSelect+`
+        WHERE w.id = $1 AND w.user_id = $2
+    `, id, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get watchlist: %w", err)
+	}
+
+	return w, nil
+}
+
+// Create inserts a watchlist; a negative position appends it after the
+// user's other watchlists
+func (r *PostgresWatchlistRepository) Create(w *models.Watchlist) error {
+	ctx := context.Background()
+
+	query := `
+        INSERT INTO watchlists (user_id, name, description, position)
+        VALUES ($1, $2, $3, CASE WHEN $4 >= 0 THEN $4
+            ELSE (SELECT COALESCE(MAX(position) + 1, 0) FROM watchlists WHERE user_id = $1) END)
+        RETURNING id, position, created_at, updated_at
+    `
+
+	err := r.conn.QueryRow(ctx, query, w.UserID, w.Name, w.Description, w.Position).Scan(
+		&w.ID,
+		&w.Position,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("failed to create watchlist: %w", err)
+	}
+
+	return nil
+}
+
+// Update changes a watchlist's name, description and position
+func (r *PostgresWatchlistRepository) Update(w *models.Watchlist) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, `
+        UPDATE watchlists
+        SET name = $3, description = $4, position = $5, updated_at = NOW()
+        WHERE id = $1 AND user_id = $2
+    `, w.ID, w.UserID, w.Name, w.Description, w.Position)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("failed to update watchlist: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Delete removes a watchlist and its entries
+func (r *PostgresWatchlistRepository) Delete(id, userID int64) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, "DELETE FROM watchlists WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete watchlist: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ListItems returns the entries of one of the user's watchlists in order
+func (r *PostgresWatchlistRepository) ListItems(watchlistID, userID int64) ([]models.StockWatchlist, error) {
+	ctx := context.Background()
+
+	if _, err := r.Get(watchlistID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT id, user_id, watchlist_id, symbol, position, notes, created_at
+        FROM stock_watchlist
+        WHERE watchlist_id = $1
+        ORDER BY position, symbol
+    `, watchlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.StockWatchlist{}
+	for rows.Next() {
+		item, err := scanWatchlistItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist item: %w", err)
+		}
+		items = append(items, *item)
+	}
+
+	return items, rows.Err()
+}
+
+// ListItemsFor returns the items of several of the user's watchlists in one
+// query, keyed by watchlist. Watchlists the user does not own are left out.
+func (r *PostgresWatchlistRepository) ListItemsFor(watchlistIDs []int64, userID int64) (map[int64][]models.StockWatchlist, error) {
+	ctx := context.Background()
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT id, user_id, watchlist_id, symbol, position, notes, created_at
+        FROM stock_watchlist
+        WHERE watchlist_id = ANY($1) AND user_id = $2
+        ORDER BY watchlist_id, position, symbol
+    `, watchlistIDs, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist items: %w", err)
+	}
+	defer rows.Close()
+
+	items := make(map[int64][]models.StockWatchlist)
+	for rows.Next() {
+		item, err := scanWatchlistItem(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist item: %w", err)
+		}
+		items[item.WatchlistID] = append(items[item.WatchlistID], *item)
+	}
+
+	return items, rows.Err()
+}
+
+// AddItem adds a symbol to one of the user's watchlists at item.Position,
+// moving the entries from there on down one place; a negative position
+// appends it to the end
+func (r *PostgresWatchlistRepository) AddItem(item *models.StockWatchlist) error {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockWatchlist(ctx, tx, item.WatchlistID, item.UserID); err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx, `
+        INSERT INTO stock_watchlist (user_id, watchlist_id, symbol, notes)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at
+    `, item.UserID, item.WatchlistID, item.Symbol, item.Notes).Scan(&item.ID, &item.CreatedAt)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return ErrDuplicate
+		case isForeignKeyViolation(err):
+			return ErrInvalidReference
+		}
+		return fmt.Errorf("failed to add watchlist item: %w", err)
+	}
+
+	target := item.Position
+	if target < 0 {
+		target = math.MaxInt32
+	}
+	if item.Position, err = placeItem(ctx, tx, item.WatchlistID, item.ID, target); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateItem changes the notes and/or position of a watchlist entry. Moving
+// an entry shifts the ones between its old and new place to close the gap.
+func (r *PostgresWatchlistRepository) UpdateItem(watchlistID, userID int64, symbol string, notes *string, position *int) (*models.StockWatchlist, error) {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockWatchlist(ctx, tx, watchlistID, userID); err != nil {
+		return nil, err
+	}
+
+	item, err := scanWatchlistItem(tx.QueryRow(ctx, `
+        UPDATE stock_watchlist
+        SET notes = COALESCE($3, notes)
+        WHERE watchlist_id = $1 AND symbol = $2
+        RETURNING id, user_id, watchlist_id, symbol, position, notes, created_at
+    `, watchlistID, symbol, notes))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to update watchlist item: %w", err)
+	}
+
+	if position != nil {
+		if item.Position, err = placeItem(ctx, tx, watchlistID, item.ID, *position); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return item, nil
+}
+
+// lockWatchlist locks one of the user's watchlists so changes to its order
+// apply one after another
+func lockWatchlist(ctx context.Context, tx pgx.Tx, watchlistID, userID int64) error {
+	var locked int64
+	err := tx.QueryRow(ctx, "SELECT id FROM watchlists WHERE id = $1 AND user_id = $2 FOR UPDATE", watchlistID, userID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to lock watchlist: %w", err)
+	}
+	return nil
+}
+
+// placeItem moves an entry to position, clamped to the end of the list, and
+// renumbers the other entries from zero in their current order around it.
+// It returns the position the entry ends up at.
+func placeItem(ctx context.Context, tx pgx.Tx, watchlistID, itemID int64, position int) (int, error) {
+	var others int
+	err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM stock_watchlist WHERE watchlist_id = $1 AND id <> $2", watchlistID, itemID).Scan(&others)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count watchlist items: %w", err)
+	}
+	position = min(max(position, 0), others)
+
+	_, err = tx.Exec(ctx, `
+        WITH ranked AS (
+            SELECT id, ROW_NUMBER() OVER (ORDER BY position, symbol) - 1 AS rank
+            FROM stock_watchlist
+            WHERE watchlist_id = $1 AND id <> $2
+        )
+        UPDATE stock_watchlist sw
+        SET position = CASE WHEN r.rank >= $3 THEN r.rank + 1 ELSE r.rank END
+        FROM ranked r
+        WHERE sw.id = r.id
+    `, watchlistID, itemID, position)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reorder watchlist items: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, "UPDATE stock_watchlist SET position = $2 WHERE id = $1", itemID, position); err != nil {
+		return 0, fmt.Errorf("failed to move watchlist item: %w", err)
+	}
+
+	return position, nil
+}
+
+// RemoveItem removes a symbol from one of the user's watchlists
+func (r *PostgresWatchlistRepository) RemoveItem(watchlistID, userID int64, symbol string) error {
+	ctx := context.Background()
+
+	tag, err := r.conn.Exec(ctx, `
+        DELETE FROM stock_watchlist sw
+        USING watchlists w
+        WHERE w.id = sw.watchlist_id AND w.id = $1 AND w.user_id = $2 AND sw.symbol = $3
+    `, watchlistID, userID, symbol)
+	if err != nil {
+		return fmt.Errorf("failed to remove watchlist item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ReorderItems puts the given symbols first, in order; entries that are not
+// listed keep their relative order after them
+func (r *PostgresWatchlistRepository) ReorderItems(watchlistID, userID int64, symbols []string) error {
+	ctx := context.Background()
+
+	tx, err := r.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM watchlists WHERE id = $1 AND user_id = $2)", watchlistID, userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check watchlist: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	_, err = tx.Exec(ctx, `
+        WITH ordered AS (
+            SELECT sw.id,
+                   ROW_NUMBER() OVER (ORDER BY o.ord NULLS LAST, sw.position, sw.symbol) - 1 AS new_position
+            FROM stock_watchlist sw
+            LEFT JOIN unnest($2::text[]) WITH ORDINALITY AS o(symbol, ord) ON o.symbol = sw.symbol
+            WHERE sw.watchlist_id = $1
+        )
+        UPDATE stock_watchlist sw
+        SET position = ordered.new_position
+        FROM ordered
+        WHERE sw.id = ordered.id
+    `, watchlistID, symbols)
+	if err != nil {
+		return fmt.Errorf("failed to reorder watchlist: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetView returns the watchlist entries joined with the latest price, the
+// change since the previous close and the last sparklineDays closes
+func (r *PostgresWatchlistRepository) GetView(watchlistID, userID int64, sparklineDays int) ([]models.WatchlistItemView, error) {
+	ctx := context.Background()
+
+	if _, err := r.Get(watchlistID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.conn.Query(ctx, `
+        SELECT sw.symbol, s.name, sw.notes, sw.position, sw.created_at,
+               COALESCE(s.last_price, 0), COALESCE(h.closes, '{}')
+        FROM stock_watchlist sw
+        JOIN stocks s ON s.symbol = sw.symbol
+        LEFT JOIN LATERAL (
+            SELECT array_agg(close ORDER BY date) AS closes
+            FROM (
+                SELECT date, close
+                FROM stock_history
+                WHERE symbol = sw.symbol
+                ORDER BY date DESC
+                LIMIT $2
+            ) recent
+        ) h ON TRUE
+        WHERE sw.watchlist_id = $1
+        ORDER BY sw.position, sw.symbol
+    `, watchlistID, max(sparklineDays, 2))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist view: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.WatchlistItemView{}
+	for rows.Next() {
+		var item models.WatchlistItemView
+		var closes []float64
+		err := rows.Scan(
+			&item.Symbol,
+			&item.Name,
+			&item.Notes,
+			&item.Position,
+			&item.AddedAt,
+			&item.LastPrice,
+			&closes,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist view: %w", err)
+		}
+
+		// The day change compares the latest close with the one before it
+		if n := len(closes); n > 0 {
+			if item.LastPrice == 0 {
+				item.LastPrice = closes[n-1]
+			}
+			if n > 1 && closes[n-2] != 0 {
+				item.PreviousClose = closes[n-2]
+				item.DayChange = closes[n-1] - closes[n-2]
+				item.DayChangePercent = item.DayChange / closes[n-2] * 100
+			}
+		}
+
+		item.Sparkline = closes
+		if len(closes) > sparklineDays {
+			item.Sparkline = closes[len(closes)-sparklineDays:]
+		}
+
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func scanWatchlistItem(row pgx.Row) (*models.StockWatchlist, error) {
+	var item models.StockWatchlist
+	err := row.Scan(
+		&item.ID,
+		&item.UserID,
+		&item.WatchlistID,
+		&item.Symbol,
+		&item.Position,
+		&item.Notes,
+		&item.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"go-flow/internal/models"
+)
+
+// DefaultBacktestHorizons are the forward return windows used when none are given
+var DefaultBacktestHorizons = []int{1, 5, 20}
+
+// RuleCondition expresses any alert as a condition, turning above/below
+// target prices into price conditions
+func RuleCondition(alert models.StockAlert) models.AlertCondition {
+	if alert.AlertType == models.AlertTypeCondition && alert.Condition != nil {
+		return *alert.Condition
+	}
+
+	target := 0.0
+	if alert.TargetPrice != nil {
+		target = *alert.TargetPrice
+	}
+	return models.AlertCondition{
+		Type:     models.ConditionPrice,
+		Operator: alert.AlertType,
+		Value:    &target,
+	}
+}
+
+// BacktestCondition replays a condition over history, ordered from oldest to
+// newest, for the bars from index start to end inclusive. Each bar is
+// evaluated on the same lookback window the alert engine loads, so results
+// match live evaluation. A live alert deactivates when it fires; here it
+// re-arms once the condition stops holding, so a firing marks each move
+// into the condition rather than every bar spent in it. Forward returns may
+// use bars after end.
+func BacktestCondition(cond models.AlertCondition, history []models.StockHistoryEntry, start, end int, horizons []int) ([]models.AlertFiring, []models.HorizonSummary) {
+	lookback := ConditionLookback(cond)
+
+	firings := []models.AlertFiring{}
+	holding := false
+	for i := start; i <= end && i < len(history); i++ {
+		window := history[max(0, i+1-lookback) : i+1]
+		matched := EvaluateCondition(cond, window)
+		if matched && !holding {
+			firings = append(firings, models.AlertFiring{
+				Date:           history[i].Date,
+				Price:          history[i].Close,
+				ForwardReturns: forwardReturns(history, i, horizons),
+			})
+		}
+		holding = matched
+	}
+
+	return firings, summarizeHorizons(firings, horizons)
+}
+
+func forwardReturns(history []models.StockHistoryEntry, i int, horizons []int) []models.ForwardReturn {
+	returns := make([]models.ForwardReturn, len(horizons))
+	for j, days := range horizons {
+		returns[j].Days = days
+		if i+days < len(history) && history[i].Close != 0 {
+			change := (history[i+days].Close - history[i].Close) / history[i].Close * 100
+			returns[j].Return = &change
+		}
+	}
+	return returns
+}
+
+func summarizeHorizons(firings []models.AlertFiring, horizons []int) []models.HorizonSummary {
+	summary := make([]models.HorizonSummary, len(horizons))
+	for j, days := range horizons {
+		summary[j].Days = days
+
+		var total float64
+		positive := 0
+		for _, firing := range firings {
+			ret := firing.ForwardReturns[j].Return
+			if ret == nil {
+				continue
+			}
+			summary[j].Count++
+			total += *ret
+			if *ret > 0 {
+				positive++
+			}
+		}
+
+		if summary[j].Count > 0 {
+			average := total / float64(summary[j].Count)
+			rate := float64(positive) / float64(summary[j].Count)
+			summary[j].AverageReturn = &average
+			summary[j].PositiveRate = &rate
+		}
+	}
+	return summary
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"strconv"
+
+	"go-flow/internal/models"
+)
+
+// Limits on the size of a condition tree
+const (
+	maxConditionDepth  = 3
+	maxConditionLeaves = 10
+	maxConditionDays   = 1000
+)
+
+// Default windows for conditions that do not specify days
+const (
+	defaultVolumeDays  = 20
+	defaultRSIDays     = 14
+	defaultHighLowDays = 252
+)
+
+// rsiWarmup is how many windows of history RSI is computed over, so Wilder's
+// smoothing has settled
+const rsiWarmup = 5
+
+// ConditionError reports an invalid field in an alert condition
+type ConditionError struct {
+	Field   string
+	Message string
+}
+
+func (e *ConditionError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// NormalizeCondition validates an alert condition and fills in default windows
+func NormalizeCondition(cond *models.AlertCondition) error {
+	leaves := 0
+	return normalizeCondition(cond, "condition", 1, &leaves)
+}
+
+func normalizeCondition(cond *models.AlertCondition, field string, depth int, leaves *int) error {
+	invalid := func(name, message string) error {
+		if name != "" {
+			name = field + "." + name
+		} else {
+			name = field
+		}
+		return &ConditionError{Field: name, Message: message}
+	}
+
+	switch cond.Type {
+	case models.ConditionAnd, models.ConditionOr:
+		if depth >= maxConditionDepth {
+			return invalid("", fmt.Sprintf("conditions may be nested at most %d deep", maxConditionDepth))
+		}
+		if len(cond.Conditions) < 2 {
+			return invalid("conditions", "needs at least 2 conditions")
+		}
+		for i := range cond.Conditions {
+			nested := fmt.Sprintf("%s.conditions[%d]", field, i)
+			if err := normalizeCondition(&cond.Conditions[i], nested, depth+1, leaves); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "":
+		return invalid("type", "is required")
+	}
+
+	*leaves++
+	if *leaves > maxConditionLeaves {
+		return invalid("", fmt.Sprintf("an alert may combine at most %d conditions", maxConditionLeaves))
+	}
+	if len(cond.Conditions) > 0 {
+		return invalid("conditions", "only and/or conditions may nest conditions")
+	}
+	if cond.Days < 0 || cond.Days > maxConditionDays {
+		return invalid("days", "must be between 1 and "+strconv.Itoa(maxConditionDays))
+	}
+
+	needsOperator := true
+	switch cond.Type {
+	case models.ConditionPrice:
+		if cond.Value == nil || *cond.Value <= 0 {
+			return invalid("value", "must be a positive price")
+		}
+		cond.Days = 0
+	case models.ConditionPercentChange:
+		if cond.Value == nil {
+			return invalid("value", "is required")
+		}
+		if cond.Days == 0 {
+			return invalid("days", "is required")
+		}
+	case models.ConditionVolumeSpike:
+		if cond.Value == nil || *cond.Value <= 0 {
+			return invalid("value", "must be a positive multiple of average volume")
+		}
+		cond.Days = cmp.Or(cond.Days, defaultVolumeDays)
+		needsOperator = false
+	case models.ConditionMACross:
+		if cond.Days < 2 {
+			return invalid("days", "must be at least 2")
+		}
+		if cond.Value != nil {
+			return invalid("value", "is not used by ma_cross")
+		}
+	case models.ConditionRSI:
+		if cond.Value == nil || *cond.Value < 0 || *cond.Value > 100 {
+			return invalid("value", "must be between 0 and 100")
+		}
+		cond.Days = cmp.Or(cond.Days, defaultRSIDays)
+	case models.ConditionNewHigh, models.ConditionNewLow:
+		if cond.Value != nil {
+			return invalid("value", "is not used by "+cond.Type)
+		}
+		cond.Days = cmp.Or(cond.Days, defaultHighLowDays)
+		if cond.Days < 2 {
+			return invalid("days", "must be at least 2")
+		}
+		needsOperator = false
+	default:
+		return invalid("type", "unknown condition type "+strconv.Quote(cond.Type))
+	}
+
+	if needsOperator {
+		if cond.Operator != "above" && cond.Operator != "below" {
+			return invalid("operator", "must be above or below")
+		}
+	} else if cond.Operator != "" {
+		return invalid("operator", "is not used by "+cond.Type)
+	}
+
+	return nil
+}
+
+// ConditionLookback returns how many daily bars, ending with the latest,
+// should be loaded to evaluate the condition
+func ConditionLookback(cond models.AlertCondition) int {
+	switch cond.Type {
+	case models.ConditionAnd, models.ConditionOr:
+		lookback := 1
+		for _, nested := range cond.Conditions {
+			lookback = max(lookback, ConditionLookback(nested))
+		}
+		return lookback
+	case models.ConditionRSI:
+		return cond.Days*rsiWarmup + 1
+	}
+	return minBars(cond)
+}
+
+// minBars returns the fewest bars a leaf condition can be evaluated on
+func minBars(cond models.AlertCondition) int {
+	switch cond.Type {
+	case models.ConditionPrice:
+		return 1
+	case models.ConditionNewHigh, models.ConditionNewLow:
+		return cond.Days
+	}
+	return cond.Days + 1
+}
+
+// EvaluateCondition reports whether the condition holds on the last bar of a
+// history ordered from oldest to newest. Conditions without enough history
+// do not hold.
+func EvaluateCondition(cond models.AlertCondition, history []models.StockHistoryEntry) bool {
+	n := len(history)
+	if n == 0 {
+		return false
+	}
+	last := history[n-1]
+
+	switch cond.Type {
+	case models.ConditionAnd:
+		for _, nested := range cond.Conditions {
+			if !EvaluateCondition(nested, history) {
+				return false
+			}
+		}
+		return len(cond.Conditions) > 0
+	case models.ConditionOr:
+		for _, nested := range cond.Conditions {
+			if EvaluateCondition(nested, history) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if n < minBars(cond) {
+		return false
+	}
+
+	switch cond.Type {
+	case models.ConditionPrice:
+		return reached(last.Close, cond.Operator, *cond.Value)
+
+	case models.ConditionPercentChange:
+		base := history[n-1-cond.Days].Close
+		if base == 0 {
+			return false
+		}
+		return reached((last.Close-base)/base*100, cond.Operator, *cond.Value)
+
+	case models.ConditionVolumeSpike:
+		var total float64
+		for _, entry := range history[n-1-cond.Days : n-1] {
+			total += float64(entry.Volume)
+		}
+		average := total / float64(cond.Days)
+		return average > 0 && float64(last.Volume) >= *cond.Value*average
+
+	case models.ConditionMACross:
+		closes := historyCloses(history)
+		previous, current := closes[n-2], closes[n-1]
+		previousMA, currentMA := SMA(closes[:n-1], cond.Days), SMA(closes, cond.Days)
+		if cond.Operator == "above" {
+			return previous <= previousMA && current > currentMA
+		}
+		return previous >= previousMA && current < currentMA
+
+	case models.ConditionRSI:
+		return reached(RSI(historyCloses(history), cond.Days), cond.Operator, *cond.Value)
+
+	case models.ConditionNewHigh, models.ConditionNewLow:
+		extreme := history[n-cond.Days].Close
+		for _, entry := range history[n-cond.Days : n-1] {
+			if cond.Type == models.ConditionNewHigh {
+				extreme = math.Max(extreme, entry.Close)
+			} else {
+				extreme = math.Min(extreme, entry.Close)
+			}
+		}
+		if cond.Type == models.ConditionNewHigh {
+			return last.Close > extreme
+		}
+		return last.Close < extreme
+	}
+
+	return false
+}
+
+// reached matches price alert semantics: a target is reached when the value
+// touches it
+func reached(value float64, operator string, target float64) bool {
+	if operator == "above" {
+		return value >= target
+	}
+	return value <= target
+}
+
+func historyCloses(history []models.StockHistoryEntry) []float64 {
+	closes := make([]float64, len(history))
+	for i, entry := range history {
+		closes[i] = entry.Close
+	}
+	return closes
+}
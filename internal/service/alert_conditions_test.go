@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"go-flow/internal/models"
+)
+
+// bars builds a daily history with the given closes and a volume of 100
+func bars(closes ...float64) []models.StockHistoryEntry {
+	history := make([]models.StockHistoryEntry, len(closes))
+	for i, c := range closes {
+		history[i] = models.StockHistoryEntry{Date: day(i + 1), Close: c, Volume: 100}
+	}
+	return history
+}
+
+// withVolumes sets the volumes of a history, oldest first
+func withVolumes(history []models.StockHistoryEntry, volumes ...int64) []models.StockHistoryEntry {
+	for i, v := range volumes {
+		history[i].Volume = v
+	}
+	return history
+}
+
+func leaf(typ, operator string, value *float64, days int) models.AlertCondition {
+	return models.AlertCondition{Type: typ, Operator: operator, Value: value, Days: days}
+}
+
+func TestNormalizeCondition(t *testing.T) {
+	tooMany := models.AlertCondition{Type: models.ConditionOr}
+	for range maxConditionLeaves + 1 {
+		tooMany.Conditions = append(tooMany.Conditions, leaf(models.ConditionPrice, "above", ptr(100), 0))
+	}
+
+	tests := []struct {
+		name      string
+		cond      models.AlertCondition
+		wantField string // "" when the condition is valid
+		wantDays  int
+	}{
+		{"price", leaf(models.ConditionPrice, "above", ptr(100), 0), "", 0},
+		{"price ignores days", leaf(models.ConditionPrice, "below", ptr(100), 5), "", 0},
+		{"price without value", leaf(models.ConditionPrice, "above", nil, 0), "condition.value", 0},
+		{"price not positive", leaf(models.ConditionPrice, "above", ptr(-1), 0), "condition.value", 0},
+		{"price without operator", leaf(models.ConditionPrice, "", ptr(100), 0), "condition.operator", 0},
+		{"price with unknown operator", leaf(models.ConditionPrice, "crosses", ptr(100), 0), "condition.operator", 0},
+		{"percent change", leaf(models.ConditionPercentChange, "below", ptr(-5), 10), "", 10},
+		{"percent change without days", leaf(models.ConditionPercentChange, "above", ptr(5), 0), "condition.days", 0},
+		{"percent change without value", leaf(models.ConditionPercentChange, "above", nil, 5), "condition.value", 0},
+		{"days out of range", leaf(models.ConditionPercentChange, "above", ptr(5), maxConditionDays+1), "condition.days", 0},
+		{"negative days", leaf(models.ConditionPercentChange, "above", ptr(5), -1), "condition.days", 0},
+		{"volume spike default days", leaf(models.ConditionVolumeSpike, "", ptr(2), 0), "", defaultVolumeDays},
+		{"volume spike with operator", leaf(models.ConditionVolumeSpike, "above", ptr(2), 0), "condition.operator", 0},
+		{"ma cross", leaf(models.ConditionMACross, "above", nil, 50), "", 50},
+		{"ma cross too short", leaf(models.ConditionMACross, "above", nil, 1), "condition.days", 0},
+		{"ma cross with value", leaf(models.ConditionMACross, "above", ptr(1), 50), "condition.value", 0},
+		{"rsi default days", leaf(models.ConditionRSI, "below", ptr(30), 0), "", defaultRSIDays},
+		{"rsi out of range", leaf(models.ConditionRSI, "above", ptr(120), 0), "condition.value", 0},
+		{"new high default days", leaf(models.ConditionNewHigh, "", nil, 0), "", defaultHighLowDays},
+		{"new low too short", leaf(models.ConditionNewLow, "", nil, 1), "condition.days", 0},
+		{"new high with operator", leaf(models.ConditionNewHigh, "above", nil, 20), "condition.operator", 0},
+		{"missing type", leaf("", "above", ptr(1), 0), "condition.type", 0},
+		{"unknown type", leaf("macd", "above", ptr(1), 0), "condition.type", 0},
+		{
+			"leaf with nested conditions",
+			models.AlertCondition{Type: models.ConditionPrice, Operator: "above", Value: ptr(1), Conditions: []models.AlertCondition{leaf(models.ConditionPrice, "above", ptr(1), 0)}},
+			"condition.conditions", 0,
+		},
+		{
+			"and with one condition",
+			models.AlertCondition{Type: models.ConditionAnd, Conditions: []models.AlertCondition{leaf(models.ConditionPrice, "above", ptr(1), 0)}},
+			"condition.conditions", 0,
+		},
+		{
+			"invalid nested condition",
+			models.AlertCondition{Type: models.ConditionAnd, Conditions: []models.AlertCondition{
+				leaf(models.ConditionPrice, "above", ptr(1), 0),
+				leaf(models.ConditionRSI, "above", nil, 0),
+			}},
+			"condition.conditions[1].value", 0,
+		},
+		{
+			"nested too deep",
+			models.AlertCondition{Type: models.ConditionAnd, Conditions: []models.AlertCondition{
+				{Type: models.ConditionOr, Conditions: []models.AlertCondition{
+					{Type: models.ConditionAnd, Conditions: []models.AlertCondition{
+						leaf(models.ConditionPrice, "above", ptr(1), 0),
+						leaf(models.ConditionPrice, "below", ptr(2), 0),
+					}},
+					leaf(models.ConditionPrice, "above", ptr(1), 0),
+				}},
+				leaf(models.ConditionPrice, "above", ptr(1), 0),
+			}},
+			"condition.conditions[0].conditions[0]", 0,
+		},
+		{"too many conditions", tooMany, "condition.conditions[10]", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond := tt.cond
+			err := NormalizeCondition(&cond)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("NormalizeCondition() = %v, want nil", err)
+				}
+				if cond.Days != tt.wantDays {
+					t.Errorf("days = %d, want %d", cond.Days, tt.wantDays)
+				}
+				return
+			}
+
+			var condErr *ConditionError
+			if !errors.As(err, &condErr) {
+				t.Fatalf("NormalizeCondition() = %v, want a ConditionError on %s", err, tt.wantField)
+			}
+			if condErr.Field != tt.wantField {
+				t.Errorf("field = %q (%s), want %q", condErr.Field, condErr.Message, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestNormalizeConditionFillsNestedDefaults(t *testing.T) {
+	cond := models.AlertCondition{Type: models.ConditionOr, Conditions: []models.AlertCondition{
+		leaf(models.ConditionRSI, "below", ptr(30), 0),
+		leaf(models.ConditionVolumeSpike, "", ptr(3), 0),
+	}}
+	if err := NormalizeCondition(&cond); err != nil {
+		t.Fatalf("NormalizeCondition() = %v", err)
+	}
+	if got := cond.Conditions[0].Days; got != defaultRSIDays {
+		t.Errorf("rsi days = %d, want %d", got, defaultRSIDays)
+	}
+	if got := cond.Conditions[1].Days; got != defaultVolumeDays {
+		t.Errorf("volume spike days = %d, want %d", got, defaultVolumeDays)
+	}
+}
+
+func TestEvaluateCondition(t *testing.T) {
+	priceAbove := leaf(models.ConditionPrice, "above", ptr(100), 0)
+	priceBelow := leaf(models.ConditionPrice, "below", ptr(90), 0)
+
+	tests := []struct {
+		name    string
+		cond    models.AlertCondition
+		history []models.StockHistoryEntry
+		want    bool
+	}{
+		{"no history", priceAbove, nil, false},
+		{"price touches target", priceAbove, bars(95, 100), true},
+		{"price short of target", priceAbove, bars(101, 99.5), false},
+		{"price below", priceBelow, bars(95, 89), true},
+
+		{"percent change up", leaf(models.ConditionPercentChange, "above", ptr(10), 2), bars(90, 100, 105, 110), true},
+		{"percent change too small", leaf(models.ConditionPercentChange, "above", ptr(10), 2), bars(100, 105, 109), false},
+		{"percent change down", leaf(models.ConditionPercentChange, "below", ptr(-5), 1), bars(100, 94), true},
+		{"percent change measured over days", leaf(models.ConditionPercentChange, "below", ptr(-5), 1), bars(100, 120, 114), true},
+		{"percent change without enough history", leaf(models.ConditionPercentChange, "above", ptr(10), 3), bars(100, 105, 200), false},
+		{"percent change from zero", leaf(models.ConditionPercentChange, "above", ptr(10), 1), bars(0, 5), false},
+
+		{"volume spike", leaf(models.ConditionVolumeSpike, "", ptr(2), 3), withVolumes(bars(1, 1, 1, 1), 100, 100, 100, 200), true},
+		{"volume spike too small", leaf(models.ConditionVolumeSpike, "", ptr(2), 3), withVolumes(bars(1, 1, 1, 1), 100, 100, 100, 199), false},
+		{"volume spike without average", leaf(models.ConditionVolumeSpike, "", ptr(2), 2), withVolumes(bars(1, 1, 1), 0, 0, 500), false},
+		{"volume spike without enough history", leaf(models.ConditionVolumeSpike, "", ptr(2), 3), withVolumes(bars(1, 1, 1), 100, 100, 900), false},
+
+		{"close crosses above sma", leaf(models.ConditionMACross, "above", nil, 3), bars(10, 10, 10, 9, 12), true},
+		{"close stays above sma", leaf(models.ConditionMACross, "above", nil, 3), bars(10, 10, 10, 11, 12), false},
+		{"close crosses below sma", leaf(models.ConditionMACross, "below", nil, 3), bars(10, 10, 10, 11, 8), true},
+		{"close stays below sma", leaf(models.ConditionMACross, "below", nil, 3), bars(10, 10, 10, 9, 8), false},
+		{"ma cross without enough history", leaf(models.ConditionMACross, "above", nil, 3), bars(10, 9, 12), false},
+
+		{"rsi oversold", leaf(models.ConditionRSI, "below", ptr(30), 3), bars(10, 9, 8, 7, 6), true},
+		{"rsi not overbought", leaf(models.ConditionRSI, "above", ptr(70), 3), bars(10, 9, 8, 7, 6), false},
+		{"rsi overbought", leaf(models.ConditionRSI, "above", ptr(70), 3), bars(6, 7, 8, 9, 10), true},
+		{"rsi without enough history", leaf(models.ConditionRSI, "below", ptr(30), 3), bars(10, 9, 8), false},
+
+		{"new high", leaf(models.ConditionNewHigh, "", nil, 3), bars(5, 12, 7, 8, 10), true},
+		{"new high below an older close in the window", leaf(models.ConditionNewHigh, "", nil, 4), bars(5, 12, 7, 8, 10), false},
+		{"new high ties are not new", leaf(models.ConditionNewHigh, "", nil, 3), bars(5, 10, 8, 10), false},
+		{"new low", leaf(models.ConditionNewLow, "", nil, 3), bars(1, 7, 6, 5), true},
+		{"new high without enough history", leaf(models.ConditionNewHigh, "", nil, 3), bars(5, 10), false},
+
+		{"and holds", models.AlertCondition{Type: models.ConditionAnd, Conditions: []models.AlertCondition{priceAbove, leaf(models.ConditionNewHigh, "", nil, 2)}}, bars(99, 101), true},
+		{"and fails on one", models.AlertCondition{Type: models.ConditionAnd, Conditions: []models.AlertCondition{priceAbove, priceBelow}}, bars(99, 101), false},
+		{"and without conditions", models.AlertCondition{Type: models.ConditionAnd}, bars(99, 101), false},
+		{"or holds on one", models.AlertCondition{Type: models.ConditionOr, Conditions: []models.AlertCondition{priceBelow, priceAbove}}, bars(99, 101), true},
+		{"or fails on all", models.AlertCondition{Type: models.ConditionOr, Conditions: []models.AlertCondition{priceBelow, priceAbove}}, bars(99, 95), false},
+		{
+			"or with a short leaf",
+			models.AlertCondition{Type: models.ConditionOr, Conditions: []models.AlertCondition{leaf(models.ConditionPercentChange, "above", ptr(1), 30), priceAbove}},
+			bars(99, 101), true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EvaluateCondition(tt.cond, tt.history); got != tt.want {
+				t.Errorf("EvaluateCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionLookback(t *testing.T) {
+	tests := []struct {
+		name string
+		cond models.AlertCondition
+		want int
+	}{
+		{"price", leaf(models.ConditionPrice, "above", ptr(1), 0), 1},
+		{"percent change", leaf(models.ConditionPercentChange, "above", ptr(1), 5), 6},
+		{"new high", leaf(models.ConditionNewHigh, "", nil, 20), 20},
+		{"rsi warms up", leaf(models.ConditionRSI, "below", ptr(30), 14), 14*rsiWarmup + 1},
+		{
+			"longest nested",
+			models.AlertCondition{Type: models.ConditionAnd, Conditions: []models.AlertCondition{
+				leaf(models.ConditionMACross, "above", nil, 50),
+				leaf(models.ConditionNewLow, "", nil, 10),
+			}},
+			51,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ConditionLookback(tt.cond); got != tt.want {
+				t.Errorf("ConditionLookback() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
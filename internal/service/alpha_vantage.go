@@ -1,19 +1,35 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"go-flow/internal/models"
+
 	"github.com/joho/godotenv"
 )
 
+// alphaVantageURL is the endpoint every Alpha Vantage function is called on
+const alphaVantageURL = "https://www.alphavantage.co/query"
+
+// Free tier limits for the Alpha Vantage API
+const (
+	defaultRequestsPerMinute = 5
+	defaultRequestsPerDay    = 25
+)
+
 type AlphaVantageService struct {
 	apiKey     string
 	httpClient *http.Client
+	quota      *Quota
 }
 
 func NewAlphaVantageService(apiKey string) *AlphaVantageService {
@@ -24,7 +40,25 @@ func NewAlphaVantageService(apiKey string) *AlphaVantageService {
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		quota: NewQuota(
+			envInt("ALPHA_VANTAGE_REQUESTS_PER_MINUTE", defaultRequestsPerMinute),
+			envInt("ALPHA_VANTAGE_REQUESTS_PER_DAY", defaultRequestsPerDay),
+		),
+	}
+}
+
+// QuotaStatus reports how much of the provider quota has been used
+func (s *AlphaVantageService) QuotaStatus() QuotaStatus {
+	return s.quota.Status()
+}
+
+// envInt reads an integer environment variable, falling back to def
+func envInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
 	}
+	return value
 }
 
 // Struct to parse Alpha Vantage response
@@ -40,7 +74,9 @@ type StockData struct {
 }
 
 type DailyResponse struct {
-	MetaData struct {
+	Note        string `json:"Note"`
+	Information string `json:"Information"`
+	MetaData    struct {
 		Symbol        string `json:"2. Symbol"`
 		LastRefreshed string `json:"3. Last Refreshed"`
 	} `json:"Meta Data"`
@@ -53,22 +89,30 @@ type DailyResponse struct {
 	} `json:"Time Series (Daily)"`
 }
 
-func (s *AlphaVantageService) GetDailyStockData(symbol string) ([]StockData, error) {
-	url := fmt.Sprintf("https://www.alphavantage.co/query?function=TIME_SERIES_DAILY&symbol=%s&apikey=%s", symbol, s.apiKey)
+type GlobalQuoteResponse struct {
+	Note        string `json:"Note"`
+	Information string `json:"Information"`
+	GlobalQuote struct {
+		Symbol        string `json:"01. symbol"`
+		Price         string `json:"05. price"`
+		Volume        string `json:"06. volume"`
+		Change        string `json:"09. change"`
+		ChangePercent string `json:"10. change percent"`
+	} `json:"Global Quote"`
+}
 
-	resp, err := s.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch data from Alpha Vantage: %w", err)
+func (s *AlphaVantageService) GetDailyStockData(ctx context.Context, symbol string) ([]StockData, error) {
+	var dailyResponse DailyResponse
+	if err := s.get(ctx, "TIME_SERIES_DAILY", symbol, &dailyResponse); err != nil {
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received non-200 response from Alpha Vantage: %d", resp.StatusCode)
+	// Rate limited or unknown symbols come back as 200 with a message
+	if msg := firstNonEmpty(dailyResponse.Note, dailyResponse.Information); msg != "" {
+		return nil, fmt.Errorf("request rejected by Alpha Vantage: %s", msg)
 	}
-
-	var dailyResponse DailyResponse
-	if err := json.NewDecoder(resp.Body).Decode(&dailyResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode Alpha Vantage response: %w", err)
+	if len(dailyResponse.TimeSeries) == 0 {
+		return nil, fmt.Errorf("no daily data returned for symbol %s", symbol)
 	}
 
 	var stockData []StockData
@@ -92,5 +136,85 @@ func (s *AlphaVantageService) GetDailyStockData(symbol string) ([]StockData, err
 		})
 	}
 
+	// Newest first, so the first entry carries the latest close
+	sort.Slice(stockData, func(i, j int) bool {
+		return stockData[i].Date > stockData[j].Date
+	})
+
 	return stockData, nil
 }
+
+// GetQuote returns the latest quote for a symbol
+func (s *AlphaVantageService) GetQuote(ctx context.Context, symbol string) (*models.StockQuote, error) {
+	var quoteResponse GlobalQuoteResponse
+	if err := s.get(ctx, "GLOBAL_QUOTE", symbol, &quoteResponse); err != nil {
+		return nil, err
+	}
+
+	if msg := firstNonEmpty(quoteResponse.Note, quoteResponse.Information); msg != "" {
+		return nil, fmt.Errorf("request rejected by Alpha Vantage: %s", msg)
+	}
+
+	data := quoteResponse.GlobalQuote
+	if data.Symbol == "" {
+		return nil, fmt.Errorf("no quote returned for symbol %s", symbol)
+	}
+
+	// Convert string values to appropriate types
+	price, _ := strconv.ParseFloat(data.Price, 64)
+	change, _ := strconv.ParseFloat(data.Change, 64)
+	changePercent, _ := strconv.ParseFloat(strings.TrimSuffix(data.ChangePercent, "%"), 64)
+	volume, _ := strconv.ParseInt(data.Volume, 10, 64)
+
+	return &models.StockQuote{
+		Symbol:        data.Symbol,
+		Price:         price,
+		Change:        change,
+		ChangePercent: changePercent,
+		Volume:        volume,
+		Timestamp:     time.Now(),
+	}, nil
+}
+
+// get waits for quota, calls an Alpha Vantage function for a symbol and
+// decodes the JSON response. Waiting and the request both stop when ctx is
+// cancelled.
+func (s *AlphaVantageService) get(ctx context.Context, function, symbol string, out any) error {
+	if err := s.quota.Wait(ctx); err != nil {
+		return fmt.Errorf("provider quota: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("function", function)
+	query.Set("symbol", symbol)
+	query.Set("apikey", s.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, alphaVantageURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to build Alpha Vantage request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch data from Alpha Vantage: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received non-200 response from Alpha Vantage: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Alpha Vantage response: %w", err)
+	}
+
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
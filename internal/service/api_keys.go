@@ -0,0 +1,35 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// APIKeyPrefix marks API keys so they can be told apart from JWTs
+const APIKeyPrefix = "gf_"
+
+// apiKeyDisplayLength is how much of the key is stored in clear for display
+const apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+// GenerateAPIKey returns a new random key, its display prefix and its hash
+func GenerateAPIKey() (key, prefix, hash string) {
+	b := make([]byte, 32)
+	rand.Read(b)
+
+	key = APIKeyPrefix + hex.EncodeToString(b)
+	return key, key[:apiKeyDisplayLength], HashAPIKey(key)
+}
+
+// HashAPIKey returns the SHA-256 hex digest under which a key is stored. The
+// keys carry 256 bits of randomness, so a fast unsalted hash is sufficient.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey reports whether a credential looks like an API key
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, APIKeyPrefix)
+}
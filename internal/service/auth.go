@@ -0,0 +1,152 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+	tokenIssuer      = "go-flow"
+)
+
+// ErrInvalidToken is returned for malformed, expired or wrongly signed tokens
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenClaims are the claims carried by both access and refresh tokens
+type TokenClaims struct {
+	Type      string `json:"typ"`
+	SessionID string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// UserID returns the numeric user ID from the subject claim
+func (c *TokenClaims) UserID() int64 {
+	id, _ := strconv.ParseInt(c.Subject, 10, 64)
+	return id
+}
+
+// IssuedToken is a signed token together with its ID and expiry
+type IssuedToken struct {
+	Token     string
+	ID        string
+	ExpiresAt time.Time
+}
+
+// TokenService signs and verifies HS256 JWTs
+type TokenService struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenService(secret string, accessTTL, refreshTTL time.Duration) *TokenService {
+	return &TokenService{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// IssueAccessToken creates a short-lived token for API requests
+func (s *TokenService) IssueAccessToken(userID int64, sessionID string) (*IssuedToken, error) {
+	return s.issue(accessTokenType, userID, sessionID, s.accessTTL)
+}
+
+// IssueRefreshToken creates a long-lived token that can be exchanged once
+func (s *TokenService) IssueRefreshToken(userID int64, sessionID string) (*IssuedToken, error) {
+	return s.issue(refreshTokenType, userID, sessionID, s.refreshTTL)
+}
+
+// ParseAccessToken verifies an access token and returns its claims
+func (s *TokenService) ParseAccessToken(token string) (*TokenClaims, error) {
+	return s.parse(token, accessTokenType)
+}
+
+// ParseRefreshToken verifies a refresh token and returns its claims
+func (s *TokenService) ParseRefreshToken(token string) (*TokenClaims, error) {
+	return s.parse(token, refreshTokenType)
+}
+
+func (s *TokenService) issue(tokenType string, userID int64, sessionID string, ttl time.Duration) (*IssuedToken, error) {
+	now := time.Now()
+	claims := TokenClaims{
+		Type:      tokenType,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        NewTokenID(),
+			Issuer:    tokenIssuer,
+			Subject:   strconv.FormatInt(userID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign %s token: %w", tokenType, err)
+	}
+
+	return &IssuedToken{
+		Token:     signed,
+		ID:        claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+func (s *TokenService) parse(token string, tokenType string) (*TokenClaims, error) {
+	var claims TokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		return s.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Type != tokenType || claims.UserID() == 0 {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// NewTokenID returns a random identifier for tokens and sessions
+func NewTokenID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// HashPassword hashes a password with bcrypt
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// dummyHash is compared against when a login does not match any user so that
+// unknown accounts take as long to reject as wrong passwords
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("go-flow-dummy-password"), bcrypt.DefaultCost)
+
+// CheckPassword reports whether password matches the bcrypt hash. An empty
+// hash still performs a comparison to keep timing uniform.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// jobRetention is how long finished jobs stay available for polling
+const jobRetention = time.Hour
+
+// BatchRunner fans work out across symbols with a bounded number of workers
+// and keeps track of asynchronous jobs in memory
+type BatchRunner struct {
+	workers int
+	mu      sync.Mutex
+	jobs    map[string]*models.BatchJob
+}
+
+func NewBatchRunner(workers int) *BatchRunner {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	return &BatchRunner{
+		workers: workers,
+		jobs:    make(map[string]*models.BatchJob),
+	}
+}
+
+// BatchFunc processes one symbol of a batch, giving up when ctx is cancelled
+type BatchFunc func(ctx context.Context, symbol string) (any, error)
+
+// Run processes every symbol and returns the results in input order. Once
+// ctx is cancelled, symbols not yet finished fail with its error.
+func (r *BatchRunner) Run(ctx context.Context, symbols []string, fn BatchFunc) []models.BatchResult {
+	return r.run(ctx, symbols, fn, nil)
+}
+
+// Submit starts processing in the background and returns the new job. The
+// job outlives the request that submitted it.
+func (r *BatchRunner) Submit(jobType string, symbols []string, fn BatchFunc) models.BatchJob {
+	job := &models.BatchJob{
+		ID:        newJobID(),
+		Type:      jobType,
+		Status:    "running",
+		Total:     len(symbols),
+		CreatedAt: time.Now(),
+	}
+
+	r.mu.Lock()
+	r.pruneJobs()
+	r.jobs[job.ID] = job
+	snapshot := *job
+	r.mu.Unlock()
+
+	go func() {
+		results := r.run(context.Background(), symbols, fn, func() {
+			r.mu.Lock()
+			job.Completed++
+			r.mu.Unlock()
+		})
+
+		r.mu.Lock()
+		finished := time.Now()
+		job.Results = results
+		job.Status = "completed"
+		job.FinishedAt = &finished
+		r.mu.Unlock()
+	}()
+
+	return snapshot
+}
+
+// Job returns a snapshot of an asynchronous job
+func (r *BatchRunner) Job(id string) (models.BatchJob, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, ok := r.jobs[id]
+	if !ok {
+		return models.BatchJob{}, false
+	}
+	return *job, true
+}
+
+func (r *BatchRunner) run(ctx context.Context, symbols []string, fn BatchFunc, progress func()) []models.BatchResult {
+	results := make([]models.BatchResult, len(symbols))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(r.workers, len(symbols)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				symbol := symbols[i]
+				data, err := fn(ctx, symbol)
+				if err != nil {
+					results[i] = models.BatchResult{Symbol: symbol, Error: err.Error()}
+				} else {
+					results[i] = models.BatchResult{Symbol: symbol, Success: true, Data: data}
+				}
+				if progress != nil {
+					progress()
+				}
+			}
+		}()
+	}
+
+	for i := range symbols {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// pruneJobs drops finished jobs past their retention; callers must hold the lock
+func (r *BatchRunner) pruneJobs() {
+	cutoff := time.Now().Add(-jobRetention)
+	for id, job := range r.jobs {
+		if job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+			delete(r.jobs, id)
+		}
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// DefaultCompareBenchmark is used when a comparison names no benchmarks
+const DefaultCompareBenchmark = "SPY"
+
+// IndexPoint is the level of a series on one date; returns are measured as
+// changes in level
+type IndexPoint struct {
+	Date  time.Time
+	Level float64
+}
+
+// SymbolIndex uses a symbol's closes, ordered oldest first, as its level
+func SymbolIndex(history []models.StockHistoryEntry) []IndexPoint {
+	index := make([]IndexPoint, 0, len(history))
+	for _, entry := range history {
+		if entry.Close > 0 {
+			index = append(index, IndexPoint{Date: tradingDay(entry.Date), Level: entry.Close})
+		}
+	}
+	return index
+}
+
+// PortfolioIndex uses a portfolio's time-weighted growth as its level, so
+// deposits and withdrawals do not show up as returns
+func PortfolioIndex(snapshots []models.PortfolioSnapshot) []IndexPoint {
+	index := make([]IndexPoint, 0, len(snapshots))
+	level := 1.0
+	for i, s := range snapshots {
+		if i > 0 {
+			level *= dailyGrowth(snapshots, i)
+		}
+		index = append(index, IndexPoint{Date: s.Date, Level: level})
+	}
+	return index
+}
+
+// Compare normalizes the subject and each benchmark to cumulative returns
+// from the first date they all share within start and end (either may be nil)
+// and measures the subject against each benchmark from their daily returns
+// on the shared dates. riskFree is an annual percentage.
+func Compare(subject string, subjectIndex []IndexPoint, benchmarks []string, benchmarkIndexes map[string][]IndexPoint, start, end *time.Time, riskFree float64) models.ComparisonResponse {
+	resp := models.ComparisonResponse{
+		Subject:    subject,
+		Series:     []models.ComparisonSeries{},
+		Statistics: []models.ComparisonStats{},
+	}
+
+	names := append([]string{subject}, benchmarks...)
+	levels := make([]map[time.Time]float64, len(names))
+	for i, name := range names {
+		index := subjectIndex
+		if i > 0 {
+			index = benchmarkIndexes[name]
+		}
+		levels[i] = make(map[time.Time]float64, len(index))
+		for _, p := range index {
+			levels[i][p.Date] = p.Level
+		}
+	}
+
+	var dates []time.Time
+	for _, p := range subjectIndex {
+		if (start != nil && p.Date.Before(tradingDay(*start))) || (end != nil && p.Date.After(tradingDay(*end))) {
+			continue
+		}
+		shared := true
+		for _, l := range levels[1:] {
+			if _, ok := l[p.Date]; !ok {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			dates = append(dates, p.Date)
+		}
+	}
+
+	returns := make([][]float64, len(names))
+	for i, name := range names {
+		series := models.ComparisonSeries{Name: name, Points: []models.ComparisonPoint{}}
+		for j, date := range dates {
+			level := levels[i][date]
+			series.TotalReturn = (level/levels[i][dates[0]] - 1) * 100
+			series.Points = append(series.Points, models.ComparisonPoint{Date: date, CumulativeReturn: series.TotalReturn})
+			if j > 0 {
+				returns[i] = append(returns[i], level/levels[i][dates[j-1]]-1)
+			}
+		}
+		resp.Series = append(resp.Series, series)
+	}
+	if len(dates) > 0 {
+		resp.Start, resp.End = &dates[0], &dates[len(dates)-1]
+	}
+
+	dailyRiskFree := math.Pow(1+riskFree/100, 1.0/TradingDaysPerYear) - 1
+	annualize := math.Sqrt(TradingDaysPerYear)
+	for i, benchmark := range benchmarks {
+		s, b := returns[0], returns[i+1]
+		stats := models.ComparisonStats{Benchmark: benchmark, Observations: len(s)}
+		if len(dates) > 0 {
+			excess := resp.Series[0].TotalReturn - resp.Series[i+1].TotalReturn
+			stats.ExcessReturn = &excess
+		}
+		if len(s) >= 2 {
+			if variance := covariance(b, b); variance > 0 {
+				beta := covariance(s, b) / variance
+				alpha := (Mean(s) - dailyRiskFree - beta*(Mean(b)-dailyRiskFree)) * TradingDaysPerYear * 100
+				stats.Beta, stats.Alpha = &beta, &alpha
+			}
+
+			active := make([]float64, len(s))
+			for j := range s {
+				active[j] = s[j] - b[j]
+			}
+			if sd := Stdev(active); sd > 0 {
+				trackingError := sd * annualize * 100
+				information := Mean(active) / sd * annualize
+				stats.TrackingError, stats.InformationRatio = &trackingError, &information
+			}
+		}
+		resp.Statistics = append(resp.Statistics, stats)
+	}
+
+	return resp
+}
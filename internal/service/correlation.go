@@ -0,0 +1,110 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// DefaultCorrelationLookback is how many daily returns are correlated when the
+// request does not say
+const DefaultCorrelationLookback = 252
+
+// Correlation computes the Pearson, Spearman and covariance matrices of the
+// symbols' daily returns over the last lookback days of the trading calendar,
+// the union of the dates any of them traded. A symbol without a bar on a
+// calendar day carries its previous close forward, and the window starts once
+// every symbol has a close. histories are ordered oldest first.
+func Correlation(symbols []string, histories map[string][]models.StockHistoryEntry, lookback int) models.CorrelationResponse {
+	resp := models.CorrelationResponse{
+		Symbols:    symbols,
+		Lookback:   lookback,
+		FilledDays: make(map[string]int, len(symbols)),
+	}
+
+	days := make(map[time.Time]bool)
+	var firstCommon time.Time
+	for _, symbol := range symbols {
+		history := histories[symbol]
+		for _, entry := range history {
+			days[tradingDay(entry.Date)] = true
+		}
+		if len(history) > 0 && tradingDay(history[0].Date).After(firstCommon) {
+			firstCommon = tradingDay(history[0].Date)
+		}
+	}
+	var calendar []time.Time
+	for day := range days {
+		if !day.Before(firstCommon) {
+			calendar = append(calendar, day)
+		}
+	}
+	sort.Slice(calendar, func(i, j int) bool { return calendar[i].Before(calendar[j]) })
+	if len(calendar) > lookback+1 {
+		calendar = calendar[len(calendar)-lookback-1:]
+	}
+
+	// Align every symbol's closes to the calendar
+	closes := make([][]float64, len(symbols))
+	for i, symbol := range symbols {
+		history := histories[symbol]
+		closes[i] = make([]float64, len(calendar))
+		next, last := 0, 0.0
+		for j, day := range calendar {
+			filled := true
+			for ; next < len(history) && !tradingDay(history[next].Date).After(day); next++ {
+				last = history[next].Close
+				filled = !tradingDay(history[next].Date).Equal(day)
+			}
+			if filled && j > 0 {
+				resp.FilledDays[symbol]++
+			}
+			closes[i][j] = last
+		}
+	}
+
+	returns := make([][]float64, len(symbols))
+	for i := range symbols {
+		for j := 1; j < len(calendar); j++ {
+			r := 0.0
+			if closes[i][j-1] > 0 {
+				r = closes[i][j]/closes[i][j-1] - 1
+			}
+			returns[i] = append(returns[i], r)
+		}
+	}
+	if len(calendar) > 1 {
+		resp.Start, resp.End = &calendar[1], &calendar[len(calendar)-1]
+		resp.Observations = len(calendar) - 1
+	}
+
+	ranked := make([][]float64, len(symbols))
+	for i := range returns {
+		ranked[i] = ranks(returns[i])
+	}
+
+	n := len(symbols)
+	resp.Pearson = make([][]*float64, n)
+	resp.Spearman = make([][]*float64, n)
+	resp.Covariance = make([][]float64, n)
+	for i := range n {
+		resp.Pearson[i] = make([]*float64, n)
+		resp.Spearman[i] = make([]*float64, n)
+		resp.Covariance[i] = make([]float64, n)
+	}
+	for i := range n {
+		for j := i; j < n; j++ {
+			cov := covariance(returns[i], returns[j])
+			resp.Covariance[i][j], resp.Covariance[j][i] = cov, cov
+			if r, ok := pearson(returns[i], returns[j]); ok {
+				resp.Pearson[i][j], resp.Pearson[j][i] = &r, &r
+			}
+			if r, ok := pearson(ranked[i], ranked[j]); ok {
+				resp.Spearman[i][j], resp.Spearman[j][i] = &r, &r
+			}
+		}
+	}
+
+	return resp
+}
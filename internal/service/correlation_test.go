@@ -0,0 +1,142 @@
+package service
+
+import (
+	"maps"
+	"testing"
+
+	"go-flow/internal/models"
+)
+
+// closesOn builds a history with a close on each of the given days of 2024-01
+func closesOn(days []int, closes ...float64) []models.StockHistoryEntry {
+	history := make([]models.StockHistoryEntry, len(days))
+	for i, d := range days {
+		history[i] = models.StockHistoryEntry{Date: day(d), Close: closes[i]}
+	}
+	return history
+}
+
+// fromReturns builds closes starting at 100 that move by the given returns
+func fromReturns(returns ...float64) []float64 {
+	closes := []float64{100}
+	for _, r := range returns {
+		closes = append(closes, closes[len(closes)-1]*(1+r))
+	}
+	return closes
+}
+
+func TestCorrelation(t *testing.T) {
+	tests := []struct {
+		name         string
+		histories    map[string][]models.StockHistoryEntry
+		lookback     int
+		observations int
+		start, end   int
+		filled       map[string]int // symbols that needed a close carried forward
+		pearson      *float64       // between the first two symbols
+		spearman     *float64
+		covariance   float64
+	}{
+		{
+			name: "proportional moves",
+			histories: map[string][]models.StockHistoryEntry{
+				"A": closesOn([]int{1, 2, 3, 4}, 100, 110, 99, 108.9),
+				"B": closesOn([]int{1, 2, 3, 4}, 50, 55, 49.5, 54.45),
+			},
+			lookback:     252,
+			observations: 3, start: 2, end: 4,
+			filled:     map[string]int{},
+			pearson:    ptr(1),
+			spearman:   ptr(1),
+			covariance: 0.04 / 3,
+		},
+		{
+			name: "missing day carries the close forward",
+			histories: map[string][]models.StockHistoryEntry{
+				"A": closesOn([]int{1, 2, 3, 4, 5}, 10, 11, 12, 13, 14),
+				"B": closesOn([]int{1, 2, 4, 5}, 20, 22, 26, 28),
+			},
+			lookback:     252,
+			observations: 4, start: 2, end: 5,
+			filled:     map[string]int{"B": 1},
+			pearson:    ptr(-0.19065199587040135),
+			spearman:   ptr(0),
+			covariance: -0.00014190452127514953,
+		},
+		{
+			name: "window starts once every symbol has a close",
+			histories: map[string][]models.StockHistoryEntry{
+				"A": closesOn([]int{1, 2, 3, 4, 5, 6}, 10, 11, 12, 13, 14, 15),
+				"B": closesOn([]int{3, 4, 5, 6}, 5, 6, 5, 6),
+			},
+			lookback:     252,
+			observations: 3, start: 4, end: 6,
+			filled:     map[string]int{},
+			pearson:    ptr(0.04436782547080571),
+			spearman:   ptr(0),
+			covariance: 5.596255596255578e-05,
+		},
+		{
+			name: "lookback trims the calendar",
+			histories: map[string][]models.StockHistoryEntry{
+				"A": closesOn([]int{1, 2, 3, 4, 5}, 10, 11, 12, 13, 14),
+				"B": closesOn([]int{1, 2, 4, 5}, 20, 22, 26, 28),
+			},
+			lookback:     2,
+			observations: 2, start: 4, end: 5,
+			// Day 3 starts the window, so its carried close is not counted
+			filled:     map[string]int{},
+			pearson:    ptr(1),
+			spearman:   ptr(1),
+			covariance: 0.00033620225927918153,
+		},
+		{
+			name: "constant symbol has no correlation",
+			histories: map[string][]models.StockHistoryEntry{
+				"A": closesOn([]int{1, 2, 3}, 10, 11, 12),
+				"B": closesOn([]int{1, 2, 3}, 5, 5, 5),
+			},
+			lookback:     252,
+			observations: 2, start: 2, end: 3,
+			filled: map[string]int{},
+		},
+		{
+			name: "monotonic but not linear",
+			histories: map[string][]models.StockHistoryEntry{
+				"A": closesOn([]int{1, 2, 3, 4, 5}, fromReturns(0.01, 0.02, 0.03, 0.04)...),
+				"B": closesOn([]int{1, 2, 3, 4, 5}, fromReturns(0.01, 0.04, 0.09, 0.16)...),
+			},
+			lookback:     252,
+			observations: 4, start: 2, end: 5,
+			filled:     map[string]int{},
+			pearson:    ptr(0.9843740386976972),
+			spearman:   ptr(1),
+			covariance: 0.0025 / 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Correlation([]string{"A", "B"}, tt.histories, tt.lookback)
+
+			if got.Observations != tt.observations {
+				t.Errorf("observations = %d, want %d", got.Observations, tt.observations)
+			}
+			if got.Start == nil || !got.Start.Equal(day(tt.start)) || got.End == nil || !got.End.Equal(day(tt.end)) {
+				t.Errorf("window = %v to %v, want %v to %v", got.Start, got.End, day(tt.start), day(tt.end))
+			}
+			if !maps.Equal(got.FilledDays, tt.filled) {
+				t.Errorf("filled days = %v, want %v", got.FilledDays, tt.filled)
+			}
+			if !equalPtr(got.Pearson[0][1], tt.pearson) || !equalPtr(got.Pearson[1][0], tt.pearson) {
+				t.Errorf("pearson = %v, want %v", deref(got.Pearson[0][1]), deref(tt.pearson))
+			}
+			if !equalPtr(got.Spearman[0][1], tt.spearman) {
+				t.Errorf("spearman = %v, want %v", deref(got.Spearman[0][1]), deref(tt.spearman))
+			}
+			if !approx(got.Covariance[0][1], tt.covariance) || got.Covariance[0][1] != got.Covariance[1][0] {
+				t.Errorf("covariance = %v / %v, want %v", got.Covariance[0][1], got.Covariance[1][0], tt.covariance)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import "math"
+
+// IndicatorValues holds the indicators as of one bar. A nil value needs more
+// history than the bar has.
+type IndicatorValues struct {
+	SMA20, SMA50, SMA200       *float64
+	EMA12, EMA26               *float64
+	RSI14                      *float64
+	MACD, Signal, Histogram    *float64
+	BBUpper, BBMiddle, BBLower *float64
+}
+
+// IndicatorSeries computes the daily indicators bar by bar. After each close
+// is added the values equal what CalculateIndicators returns for the history
+// up to that bar, without keeping more than the last 200 closes.
+type IndicatorSeries struct {
+	window []float64
+	count  int
+
+	ema12, ema26 float64
+	prev         float64
+	gain, loss   float64
+
+	macdCount int
+	macdSum   float64
+	signal    float64
+}
+
+func NewIndicatorSeries() *IndicatorSeries {
+	return &IndicatorSeries{}
+}
+
+// Add appends the next close and returns the indicators as of it
+func (s *IndicatorSeries) Add(close float64) IndicatorValues {
+	s.window = append(s.window, close)
+	if len(s.window) > 200 {
+		s.window = s.window[1:]
+	}
+	s.count++
+
+	var v IndicatorValues
+	v.SMA20 = s.sma(20)
+	v.SMA50 = s.sma(50)
+	v.SMA200 = s.sma(200)
+
+	s.ema12 = s.nextEMA(s.ema12, close, 12)
+	s.ema26 = s.nextEMA(s.ema26, close, 26)
+	if s.count >= 12 {
+		v.EMA12 = ptr(s.ema12)
+	}
+	if s.count >= 26 {
+		v.EMA26 = ptr(s.ema26)
+	}
+
+	v.RSI14 = s.nextRSI(close, 14)
+	s.prev = close
+
+	if s.count >= 26 {
+		macd := s.ema12 - s.ema26
+		s.macdCount++
+		switch {
+		case s.macdCount < 9:
+			s.macdSum += macd
+		case s.macdCount == 9:
+			s.signal = (s.macdSum + macd) / 9
+		default:
+			k := 2.0 / 10
+			s.signal = macd*k + s.signal*(1-k)
+		}
+		if s.macdCount >= 9 {
+			v.MACD, v.Signal, v.Histogram = ptr(macd), ptr(s.signal), ptr(macd-s.signal)
+		}
+	}
+
+	if v.SMA20 != nil {
+		middle := *v.SMA20
+		variance := 0.0
+		for _, c := range s.window[len(s.window)-20:] {
+			variance += (c - middle) * (c - middle)
+		}
+		stdDev := math.Sqrt(variance / 20)
+		v.BBUpper, v.BBMiddle, v.BBLower = ptr(middle+2*stdDev), ptr(middle), ptr(middle-2*stdDev)
+	}
+
+	return v
+}
+
+func (s *IndicatorSeries) sma(period int) *float64 {
+	if s.count < period {
+		return nil
+	}
+	return ptr(SMA(s.window, period))
+}
+
+// nextEMA seeds the average with the SMA of the first period closes and
+// smooths it from then on
+func (s *IndicatorSeries) nextEMA(ema, close float64, period int) float64 {
+	switch {
+	case s.count < period:
+		return 0
+	case s.count == period:
+		return SMA(s.window, period)
+	default:
+		k := 2.0 / float64(period+1)
+		return close*k + ema*(1-k)
+	}
+}
+
+// nextRSI sums the first period changes and applies Wilder's smoothing to
+// later ones
+func (s *IndicatorSeries) nextRSI(close float64, period int) *float64 {
+	if s.count == 1 {
+		return nil
+	}
+	g, l := 0.0, 0.0
+	if change := close - s.prev; change > 0 {
+		g = change
+	} else {
+		l = -change
+	}
+
+	n := float64(period)
+	switch {
+	case s.count <= period:
+		s.gain += g
+		s.loss += l
+		return nil
+	case s.count == period+1:
+		s.gain = (s.gain + g) / n
+		s.loss = (s.loss + l) / n
+	default:
+		s.gain = (s.gain*(n-1) + g) / n
+		s.loss = (s.loss*(n-1) + l) / n
+	}
+
+	if s.loss == 0 {
+		return ptr(100.0)
+	}
+	return ptr(100 - 100/(1+s.gain/s.loss))
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// CalculateIndicators computes technical indicators from a price history
+// ordered from oldest to newest. Indicators that need more data than is
+// available are left at zero.
+func CalculateIndicators(symbol string, history []models.StockHistoryEntry) *models.TechnicalIndicators {
+	closes := make([]float64, len(history))
+	for i, entry := range history {
+		closes[i] = entry.Close
+	}
+
+	indicators := &models.TechnicalIndicators{
+		Symbol:       symbol,
+		Timeframe:    "daily",
+		SMA20:        SMA(closes, 20),
+		SMA50:        SMA(closes, 50),
+		SMA200:       SMA(closes, 200),
+		EMA12:        EMA(closes, 12),
+		EMA26:        EMA(closes, 26),
+		RSI:          RSI(closes, 14),
+		MACD:         MACD(closes),
+		CalculatedAt: time.Now(),
+		Bars:         len(closes),
+	}
+
+	if len(closes) >= 20 {
+		indicators.BollingerBands = BollingerBands(closes, 20, 2)
+	}
+
+	return indicators
+}
+
+// SMA returns the simple moving average of the last period values
+func SMA(values []float64, period int) float64 {
+	if period <= 0 || len(values) < period {
+		return 0
+	}
+
+	sum := 0.0
+	for _, v := range values[len(values)-period:] {
+		sum += v
+	}
+	return sum / float64(period)
+}
+
+// EMA returns the exponential moving average seeded with the SMA of the
+// first period values
+func EMA(values []float64, period int) float64 {
+	series := emaSeries(values, period)
+	if len(series) == 0 {
+		return 0
+	}
+	return series[len(series)-1]
+}
+
+// emaSeries returns the EMA for every value from index period-1 onwards
+func emaSeries(values []float64, period int) []float64 {
+	if period <= 0 || len(values) < period {
+		return nil
+	}
+
+	k := 2.0 / float64(period+1)
+	ema := SMA(values[:period], period)
+	series := []float64{ema}
+	for _, v := range values[period:] {
+		ema = v*k + ema*(1-k)
+		series = append(series, ema)
+	}
+	return series
+}
+
+// RSI returns the relative strength index using Wilder's smoothing
+func RSI(values []float64, period int) float64 {
+	if period <= 0 || len(values) <= period {
+		return 0
+	}
+
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		if change > 0 {
+			gain += change
+		} else {
+			loss -= change
+		}
+	}
+	avgGain := gain / float64(period)
+	avgLoss := loss / float64(period)
+
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		g, l := 0.0, 0.0
+		if change > 0 {
+			g = change
+		} else {
+			l = -change
+		}
+		avgGain = (avgGain*float64(period-1) + g) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + l) / float64(period)
+	}
+
+	if avgLoss == 0 {
+		return 100
+	}
+	rs := avgGain / avgLoss
+	return 100 - 100/(1+rs)
+}
+
+// MACD returns the 12/26/9 MACD values, or nil if there is not enough data
+func MACD(values []float64) *models.MACDData {
+	fast := emaSeries(values, 12)
+	slow := emaSeries(values, 26)
+	if len(slow) == 0 {
+		return nil
+	}
+
+	// Align the fast series with the slow one before taking the difference
+	offset := len(fast) - len(slow)
+	macdLine := make([]float64, len(slow))
+	for i := range slow {
+		macdLine[i] = fast[i+offset] - slow[i]
+	}
+
+	signal := emaSeries(macdLine, 9)
+	if len(signal) == 0 {
+		return nil
+	}
+
+	last := macdLine[len(macdLine)-1]
+	lastSignal := signal[len(signal)-1]
+	return &models.MACDData{
+		MACD:      last,
+		Signal:    lastSignal,
+		Histogram: last - lastSignal,
+	}
+}
+
+// BollingerBands returns the bands around the SMA of the last period values
+func BollingerBands(values []float64, period int, multiplier float64) *models.BollingerBandsData {
+	if period <= 0 || len(values) < period {
+		return nil
+	}
+
+	middle := SMA(values, period)
+	variance := 0.0
+	for _, v := range values[len(values)-period:] {
+		variance += (v - middle) * (v - middle)
+	}
+	stdDev := math.Sqrt(variance / float64(period))
+
+	return &models.BollingerBandsData{
+		Upper:  middle + multiplier*stdDev,
+		Middle: middle,
+		Lower:  middle - multiplier*stdDev,
+	}
+}
+
+// indicatorBars is how many closes each indicator needs to be computed
+var indicatorBars = map[string]int{
+	"close": 1, "price": 1,
+	"rsi":    15,
+	"sma_20": 20, "sma_50": 50, "sma_200": 200,
+	"ema_12": 12, "ema_26": 26,
+	"macd": 34,
+}
+
+// IndicatorValue looks up a named indicator; the boolean is false when the
+// name is unknown or the indicator could not be computed. Computability is
+// judged by the history length, as zero is a valid value for RSI and MACD.
+func IndicatorValue(indicators *models.TechnicalIndicators, close float64, name string) (float64, bool) {
+	needed, known := indicatorBars[name]
+	if !known || indicators.Bars < needed {
+		return 0, false
+	}
+
+	switch name {
+	case "close", "price":
+		return close, true
+	case "rsi":
+		return indicators.RSI, true
+	case "sma_20":
+		return indicators.SMA20, true
+	case "sma_50":
+		return indicators.SMA50, true
+	case "sma_200":
+		return indicators.SMA200, true
+	case "ema_12":
+		return indicators.EMA12, true
+	case "ema_26":
+		return indicators.EMA26, true
+	case "macd":
+		if indicators.MACD == nil {
+			return 0, false
+		}
+		return indicators.MACD.MACD, true
+	}
+	return 0, false
+}
+
+// IsValidIndicator reports whether name can be used in an indicator filter
+func IsValidIndicator(name string) bool {
+	_, ok := indicatorBars[name]
+	return ok
+}
+
+// MatchesIndicatorFilters reports whether the latest close and indicators
+// satisfy every filter. Filters on indicators that cannot be computed from
+// the available history never match.
+func MatchesIndicatorFilters(indicators *models.TechnicalIndicators, close float64, filters []models.IndicatorFilter) bool {
+	for _, f := range filters {
+		left, ok := IndicatorValue(indicators, close, f.Indicator)
+		if !ok {
+			return false
+		}
+
+		var right float64
+		if f.CompareTo != "" {
+			right, ok = IndicatorValue(indicators, close, f.CompareTo)
+			if !ok {
+				return false
+			}
+		} else if f.Value != nil {
+			right = *f.Value
+		} else {
+			return false
+		}
+
+		if !compare(left, f.Operator, right) {
+			return false
+		}
+	}
+	return true
+}
+
+func compare(left float64, operator string, right float64) bool {
+	switch operator {
+	case "<", "below":
+		return left < right
+	case "<=":
+		return left <= right
+	case ">", "above":
+		return left > right
+	case ">=":
+		return left >= right
+	}
+	return false
+}
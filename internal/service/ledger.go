@@ -0,0 +1,365 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// Ledger replay errors
+var (
+	ErrInsufficientQuantity = errors.New("insufficient quantity")
+	ErrUnknownLot           = errors.New("lot is not open for this symbol")
+	ErrLotMismatch          = errors.New("selected lot quantities do not add up to the quantity sold")
+)
+
+// quantityEpsilon absorbs rounding left over from splits and partial sales
+const quantityEpsilon = 1e-9
+
+// LedgerError reports the transaction a ledger replay failed on
+type LedgerError struct {
+	TransactionID int64
+	Err           error
+}
+
+func (e *LedgerError) Error() string {
+	return fmt.Sprintf("transaction %d: %v", e.TransactionID, e.Err)
+}
+
+func (e *LedgerError) Unwrap() error {
+	return e.Err
+}
+
+// TransactionError reports an invalid field in a ledger transaction
+type TransactionError struct {
+	Field   string
+	Message string
+}
+
+func (e *TransactionError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// PrepareTransaction validates a transaction against the rules for its type
+// and sets its total amount. Trades take their total from quantity, price and
+// fees; cash entries must already carry their amount in TotalAmount.
+func PrepareTransaction(t *models.Transaction, method string) error {
+	invalid := func(field, message string) error {
+		return &TransactionError{Field: field, Message: message}
+	}
+
+	switch t.Type {
+	case models.TransactionBuy, models.TransactionSell, models.TransactionTransferIn, models.TransactionTransferOut:
+		if t.Symbol == "" {
+			return invalid("symbol", "is required for a "+t.Type)
+		}
+		if t.Quantity <= 0 {
+			return invalid("quantity", "must be greater than 0")
+		}
+	case models.TransactionDividend:
+		if t.Symbol == "" {
+			return invalid("symbol", "is required for a dividend")
+		}
+		if t.TotalAmount <= 0 {
+			return invalid("amount", "must be greater than 0")
+		}
+	case models.TransactionSplit:
+		if t.Symbol == "" {
+			return invalid("symbol", "is required for a split")
+		}
+		if t.SplitRatio == nil || *t.SplitRatio <= 0 {
+			return invalid("split_ratio", "is required for a split and must be greater than 0")
+		}
+	case models.TransactionFee:
+		if t.TotalAmount <= 0 {
+			return invalid("amount", "must be greater than 0")
+		}
+	case models.TransactionDeposit, models.TransactionWithdrawal:
+		if t.Symbol != "" {
+			return invalid("symbol", "does not apply to a "+t.Type)
+		}
+		if t.TotalAmount <= 0 {
+			return invalid("amount", "must be greater than 0")
+		}
+	default:
+		return invalid("type", "is not a known transaction type")
+	}
+
+	if t.Type != models.TransactionSplit {
+		t.SplitRatio = nil
+	}
+
+	sale := t.Type == models.TransactionSell || t.Type == models.TransactionTransferOut
+	if len(t.LotSelections) > 0 {
+		if !sale {
+			return invalid("lots", "only applies to a sell or transfer_out")
+		}
+		if method == models.CostBasisAverage {
+			return invalid("lots", "cannot be chosen under average cost")
+		}
+		total := 0.0
+		for _, sel := range t.LotSelections {
+			total += sel.Quantity
+		}
+		if math.Abs(total-t.Quantity) > quantityEpsilon {
+			return invalid("lots", ErrLotMismatch.Error())
+		}
+	} else if sale && method == models.CostBasisSpecific {
+		return invalid("lots", "is required when the portfolio uses specific lot matching")
+	}
+
+	switch t.Type {
+	case models.TransactionBuy:
+		t.TotalAmount = t.Quantity*t.Price + t.Fees
+	case models.TransactionSell:
+		t.TotalAmount = t.Quantity*t.Price - t.Fees
+	case models.TransactionTransferIn, models.TransactionTransferOut, models.TransactionSplit:
+		t.TotalAmount = 0
+	}
+
+	return nil
+}
+
+// Ledger is the state derived from replaying a portfolio's transactions
+type Ledger struct {
+	Method   string
+	Cash     float64
+	Lots     []models.TaxLot // every lot in acquisition order, closed ones included
+	Realized []models.RealizedGain
+}
+
+// NewLedger returns an empty ledger that matches sales by method
+func NewLedger(method string) *Ledger {
+	return &Ledger{
+		Method:   method,
+		Lots:     []models.TaxLot{},
+		Realized: []models.RealizedGain{},
+	}
+}
+
+// ReplayLedger applies transactions in the order they were executed and
+// returns the resulting cash, lots and realized gains. Sales are matched to
+// lots by the cost basis method unless they pick their own lots.
+func ReplayLedger(transactions []models.Transaction, method string) (*Ledger, error) {
+	l := NewLedger(method)
+	for _, t := range OrderTransactions(transactions) {
+		if err := l.Apply(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// OrderTransactions returns a copy of transactions in the order they are
+// replayed: by execution time, then ID
+func OrderTransactions(transactions []models.Transaction) []models.Transaction {
+	ordered := slices.Clone(transactions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if !ordered[i].ExecutedAt.Equal(ordered[j].ExecutedAt) {
+			return ordered[i].ExecutedAt.Before(ordered[j].ExecutedAt)
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+	return ordered
+}
+
+// Apply adds one transaction to the ledger; transactions must be applied in
+// the order OrderTransactions returns
+func (l *Ledger) Apply(t models.Transaction) error {
+	if err := l.apply(t); err != nil {
+		return &LedgerError{TransactionID: t.ID, Err: err}
+	}
+	return nil
+}
+
+func (l *Ledger) apply(t models.Transaction) error {
+	switch t.Type {
+	case models.TransactionBuy:
+		l.Cash -= t.TotalAmount
+		l.open(t, t.TotalAmount/t.Quantity)
+	case models.TransactionTransferIn:
+		l.open(t, t.Price)
+	case models.TransactionSell:
+		l.Cash += t.TotalAmount
+		return l.close(t, true)
+	case models.TransactionTransferOut:
+		return l.close(t, false)
+	case models.TransactionDividend, models.TransactionDeposit:
+		l.Cash += t.TotalAmount
+	case models.TransactionFee, models.TransactionWithdrawal:
+		l.Cash -= t.TotalAmount
+	case models.TransactionSplit:
+		ratio := *t.SplitRatio
+		for i := range l.Lots {
+			lot := &l.Lots[i]
+			if lot.Symbol == t.Symbol && lot.Remaining > 0 {
+				lot.Quantity *= ratio
+				lot.Remaining *= ratio
+				lot.CostPerShare /= ratio
+				lot.CostBasis = lot.Remaining * lot.CostPerShare
+			}
+		}
+	default:
+		return fmt.Errorf("unknown transaction type %q", t.Type)
+	}
+
+	return nil
+}
+
+func (l *Ledger) open(t models.Transaction, costPerShare float64) {
+	l.Lots = append(l.Lots, models.TaxLot{
+		ID:           t.ID,
+		Symbol:       t.Symbol,
+		AcquiredAt:   t.ExecutedAt,
+		Quantity:     t.Quantity,
+		Remaining:    t.Quantity,
+		CostPerShare: costPerShare,
+		CostBasis:    t.Quantity * costPerShare,
+	})
+}
+
+// close removes a sale's shares from the open lots, recording the realized
+// gain on each lot it draws from when realize is set
+func (l *Ledger) close(t models.Transaction, realize bool) error {
+	var open []int
+	held, cost := 0.0, 0.0
+	for i, lot := range l.Lots {
+		if lot.Symbol == t.Symbol && lot.Remaining > 0 {
+			open = append(open, i)
+			held += lot.Remaining
+			cost += lot.CostBasis
+		}
+	}
+	if t.Quantity > held+quantityEpsilon {
+		return ErrInsufficientQuantity
+	}
+
+	if l.Method == models.CostBasisAverage {
+		average := cost / held
+		for _, i := range open {
+			l.Lots[i].CostPerShare = average
+			l.Lots[i].CostBasis = l.Lots[i].Remaining * average
+		}
+	}
+
+	take := func(i int, quantity float64) {
+		lot := &l.Lots[i]
+		lot.Remaining -= quantity
+		if lot.Remaining < quantityEpsilon {
+			lot.Remaining = 0
+		}
+		lot.CostBasis = lot.Remaining * lot.CostPerShare
+		if !realize {
+			return
+		}
+
+		proceeds := t.TotalAmount * quantity / t.Quantity
+		basis := quantity * lot.CostPerShare
+		term := "short"
+		if t.ExecutedAt.After(lot.AcquiredAt.AddDate(1, 0, 0)) {
+			term = "long"
+		}
+		l.Realized = append(l.Realized, models.RealizedGain{
+			LotID:         lot.ID,
+			TransactionID: t.ID,
+			Symbol:        t.Symbol,
+			Quantity:      quantity,
+			Proceeds:      proceeds,
+			CostBasis:     basis,
+			Gain:          proceeds - basis,
+			Term:          term,
+			AcquiredAt:    lot.AcquiredAt,
+			SoldAt:        t.ExecutedAt,
+		})
+	}
+
+	if len(t.LotSelections) > 0 {
+		for _, sel := range t.LotSelections {
+			i := slices.IndexFunc(open, func(i int) bool { return l.Lots[i].ID == sel.LotID })
+			if i < 0 {
+				return ErrUnknownLot
+			}
+			if sel.Quantity > l.Lots[open[i]].Remaining+quantityEpsilon {
+				return ErrInsufficientQuantity
+			}
+			take(open[i], math.Min(sel.Quantity, l.Lots[open[i]].Remaining))
+		}
+		return nil
+	}
+
+	if l.Method == models.CostBasisLIFO {
+		slices.Reverse(open)
+	}
+	need := t.Quantity
+	for _, i := range open {
+		if need <= quantityEpsilon {
+			break
+		}
+		quantity := math.Min(need, l.Lots[i].Remaining)
+		take(i, quantity)
+		need -= quantity
+	}
+
+	return nil
+}
+
+// OpenLots returns the lots that still hold shares
+func (l *Ledger) OpenLots() []models.TaxLot {
+	lots := []models.TaxLot{}
+	for _, lot := range l.Lots {
+		if lot.Remaining > 0 {
+			lots = append(lots, lot)
+		}
+	}
+	return lots
+}
+
+// Positions sums the open lots into one position per symbol, ordered by symbol
+func (l *Ledger) Positions() []models.PortfolioPosition {
+	bySymbol := make(map[string]*models.PortfolioPosition)
+	var symbols []string
+	for _, lot := range l.OpenLots() {
+		pos, ok := bySymbol[lot.Symbol]
+		if !ok {
+			pos = &models.PortfolioPosition{Symbol: lot.Symbol}
+			bySymbol[lot.Symbol] = pos
+			symbols = append(symbols, lot.Symbol)
+		}
+		pos.Quantity += lot.Remaining
+		pos.CostBasis += lot.CostBasis
+	}
+
+	sort.Strings(symbols)
+	positions := make([]models.PortfolioPosition, 0, len(symbols))
+	for _, symbol := range symbols {
+		pos := bySymbol[symbol]
+		pos.AverageCost = pos.CostBasis / pos.Quantity
+		positions = append(positions, *pos)
+	}
+	return positions
+}
+
+// RealizedGains returns the gains realized between from and to (either may be
+// zero for an open range) with short and long term totals
+func (l *Ledger) RealizedGains(from, to time.Time) models.RealizedGainsResponse {
+	resp := models.RealizedGainsResponse{Gains: []models.RealizedGain{}}
+	for _, g := range l.Realized {
+		if (!from.IsZero() && g.SoldAt.Before(from)) || (!to.IsZero() && !g.SoldAt.Before(to)) {
+			continue
+		}
+		resp.Gains = append(resp.Gains, g)
+		if g.Term == "long" {
+			resp.LongTerm += g.Gain
+		} else {
+			resp.ShortTerm += g.Gain
+		}
+	}
+	resp.Total = resp.ShortTerm + resp.LongTerm
+	return resp
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// trade builds a prepared transaction executed on the given day of 2024
+func trade(t *testing.T, id int64, day int, typ, symbol string, quantity, price float64) models.Transaction {
+	t.Helper()
+	tx := models.Transaction{
+		ID:         id,
+		Type:       typ,
+		Symbol:     symbol,
+		Quantity:   quantity,
+		Price:      price,
+		ExecutedAt: time.Date(2024, time.January, day, 0, 0, 0, 0, time.UTC),
+	}
+	if err := PrepareTransaction(&tx, models.CostBasisFIFO); err != nil {
+		t.Fatalf("prepare %s: %v", typ, err)
+	}
+	return tx
+}
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func realizedBasis(l *Ledger) []float64 {
+	basis := make([]float64, len(l.Realized))
+	for i, g := range l.Realized {
+		basis[i] = g.CostBasis
+	}
+	return basis
+}
+
+func TestReplayLedgerMethods(t *testing.T) {
+	tests := []struct {
+		method       string
+		firstBasis   float64 // basis realized by selling the first 10 shares
+		averageAfter float64 // average cost of the 10 shares left
+	}{
+		{models.CostBasisFIFO, 100, 20},
+		{models.CostBasisLIFO, 200, 10},
+		{models.CostBasisAverage, 150, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			transactions := []models.Transaction{
+				trade(t, 1, 1, models.TransactionBuy, "AAPL", 10, 10),
+				trade(t, 2, 2, models.TransactionBuy, "AAPL", 10, 20),
+				trade(t, 3, 3, models.TransactionSell, "AAPL", 10, 30),
+			}
+
+			l, err := ReplayLedger(transactions, tt.method)
+			if err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			if got := realizedBasis(l); len(got) == 0 || !approx(sum(got), tt.firstBasis) {
+				t.Errorf("first sale basis = %v, want %v", got, tt.firstBasis)
+			}
+			positions := l.Positions()
+			if len(positions) != 1 || !approx(positions[0].Quantity, 10) || !approx(positions[0].AverageCost, tt.averageAfter) {
+				t.Fatalf("positions = %+v, want 10 shares at %v", positions, tt.averageAfter)
+			}
+
+			// Selling the rest realizes exactly what was paid in total
+			transactions = append(transactions, trade(t, 4, 4, models.TransactionSell, "AAPL", 10, 30))
+			if l, err = ReplayLedger(transactions, tt.method); err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			if got := sum(realizedBasis(l)); !approx(got, 300) {
+				t.Errorf("total realized basis = %v, want 300", got)
+			}
+			if len(l.Positions()) != 0 {
+				t.Errorf("positions = %+v, want none", l.Positions())
+			}
+			if !approx(l.Cash, -300+600) {
+				t.Errorf("cash = %v, want 300", l.Cash)
+			}
+		})
+	}
+}
+
+func TestReplayLedgerSplit(t *testing.T) {
+	ratio := 2.0
+	split := models.Transaction{
+		ID:         2,
+		Type:       models.TransactionSplit,
+		Symbol:     "AAPL",
+		SplitRatio: &ratio,
+		ExecutedAt: time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		sell     float64
+		basis    float64
+		quantity float64
+		average  float64
+	}{
+		{"no sale", models.CostBasisFIFO, 0, 0, 20, 50},
+		{"fifo partial sale", models.CostBasisFIFO, 5, 250, 15, 50},
+		{"average partial sale", models.CostBasisAverage, 5, 250, 15, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions := []models.Transaction{trade(t, 1, 1, models.TransactionBuy, "AAPL", 10, 100), split}
+			if tt.sell > 0 {
+				transactions = append(transactions, trade(t, 3, 3, models.TransactionSell, "AAPL", tt.sell, 60))
+			}
+
+			l, err := ReplayLedger(transactions, tt.method)
+			if err != nil {
+				t.Fatalf("replay: %v", err)
+			}
+			if got := sum(realizedBasis(l)); !approx(got, tt.basis) {
+				t.Errorf("realized basis = %v, want %v", got, tt.basis)
+			}
+			positions := l.Positions()
+			if len(positions) != 1 || !approx(positions[0].Quantity, tt.quantity) || !approx(positions[0].AverageCost, tt.average) {
+				t.Errorf("positions = %+v, want %v shares at %v", positions, tt.quantity, tt.average)
+			}
+		})
+	}
+}
+
+func TestReplayLedgerSpecificLots(t *testing.T) {
+	buys := func(t *testing.T) []models.Transaction {
+		return []models.Transaction{
+			trade(t, 1, 1, models.TransactionBuy, "AAPL", 10, 10),
+			trade(t, 2, 2, models.TransactionBuy, "AAPL", 10, 20),
+			trade(t, 3, 3, models.TransactionBuy, "MSFT", 5, 50),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		selections []models.LotSelection
+		basis      float64
+		err        error
+	}{
+		{"later lot", []models.LotSelection{{LotID: 2, Quantity: 4}}, 80, nil},
+		{"both lots", []models.LotSelection{{LotID: 1, Quantity: 2}, {LotID: 2, Quantity: 2}}, 60, nil},
+		{"other symbol's lot", []models.LotSelection{{LotID: 3, Quantity: 4}}, 0, ErrUnknownLot},
+		{"more than the lot holds", []models.LotSelection{{LotID: 1, Quantity: 11}}, 0, ErrInsufficientQuantity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quantity := 0.0
+			for _, sel := range tt.selections {
+				quantity += sel.Quantity
+			}
+			sell := trade(t, 4, 4, models.TransactionSell, "AAPL", quantity, 30)
+			sell.LotSelections = tt.selections
+
+			l, err := ReplayLedger(append(buys(t), sell), models.CostBasisSpecific)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("replay error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				var ledgerErr *LedgerError
+				if !errors.As(err, &ledgerErr) || ledgerErr.TransactionID != 4 {
+					t.Errorf("error %v does not name transaction 4", err)
+				}
+				return
+			}
+			if got := sum(realizedBasis(l)); !approx(got, tt.basis) {
+				t.Errorf("realized basis = %v, want %v", got, tt.basis)
+			}
+		})
+	}
+}
+
+func TestReplayLedgerOrdersByExecution(t *testing.T) {
+	// Recorded out of order: the sale was entered before the buy it sells
+	transactions := []models.Transaction{
+		trade(t, 1, 2, models.TransactionSell, "AAPL", 5, 30),
+		trade(t, 2, 1, models.TransactionBuy, "AAPL", 5, 10),
+	}
+	if _, err := ReplayLedger(transactions, models.CostBasisFIFO); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+
+	transactions[1].ExecutedAt = transactions[0].ExecutedAt.AddDate(0, 0, 1)
+	if _, err := ReplayLedger(transactions, models.CostBasisFIFO); !errors.Is(err, ErrInsufficientQuantity) {
+		t.Fatalf("selling before buying: error = %v, want %v", err, ErrInsufficientQuantity)
+	}
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
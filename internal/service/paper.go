@@ -0,0 +1,138 @@
+package service
+
+import (
+	"math"
+	"time"
+	_ "time/tzdata" // the market calendar must not depend on the host's zoneinfo
+
+	"go-flow/internal/models"
+)
+
+// marketCloseHour is when day orders expire, in the exchange's time zone
+const marketCloseHour = 16
+
+var exchangeLocation = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// OrderError reports an invalid field in a paper order
+type OrderError struct {
+	Field   string
+	Message string
+}
+
+func (e *OrderError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// PrepareOrder checks that an order carries the prices its type needs,
+// drops the ones it does not use and sets when a day order expires
+func PrepareOrder(o *models.PaperOrder, now time.Time) error {
+	needsLimit := o.Type == models.OrderLimit || o.Type == models.OrderStopLimit
+	needsStop := o.Type == models.OrderStop || o.Type == models.OrderStopLimit
+
+	if needsLimit && o.LimitPrice == nil {
+		return &OrderError{Field: "limit_price", Message: "is required for a " + o.Type + " order"}
+	}
+	if needsStop && o.StopPrice == nil {
+		return &OrderError{Field: "stop_price", Message: "is required for a " + o.Type + " order"}
+	}
+	if !needsLimit {
+		o.LimitPrice = nil
+	}
+	if !needsStop {
+		o.StopPrice = nil
+	}
+
+	if o.TimeInForce == "" {
+		o.TimeInForce = models.TimeInForceDay
+	}
+	if o.TimeInForce == models.TimeInForceDay {
+		expires := DayOrderExpiry(now)
+		o.ExpiresAt = &expires
+	} else {
+		o.ExpiresAt = nil
+	}
+
+	return nil
+}
+
+// DayOrderExpiry returns the next market close at or after t: 4pm New York
+// time on a weekday. Exchange holidays are not taken into account.
+func DayOrderExpiry(t time.Time) time.Time {
+	local := t.In(exchangeLocation)
+	expiry := time.Date(local.Year(), local.Month(), local.Day(), marketCloseHour, 0, 0, 0, exchangeLocation)
+	if !local.Before(expiry) {
+		expiry = expiry.AddDate(0, 0, 1)
+	}
+	for expiry.Weekday() == time.Saturday || expiry.Weekday() == time.Sunday {
+		expiry = expiry.AddDate(0, 0, 1)
+	}
+	return expiry
+}
+
+// OrderMatch is the outcome of matching an open order against a price update
+type OrderMatch struct {
+	Triggered bool // the stop of a stop or stop-limit order was reached
+	Filled    bool
+	Price     float64
+}
+
+// MatchOrder decides whether an open order fills on a price update and at
+// what price. A bar's high and low are only used for orders that were open
+// before the bar's day began; anything placed later only sees the latest
+// price, so an order is never filled on a move that happened before it
+// existed. Limit orders fill at their limit or better and stops become
+// market orders once the price trades through them.
+func MatchOrder(o models.PaperOrder, update PriceUpdate) OrderMatch {
+	var m OrderMatch
+	if update.Price <= 0 {
+		return m
+	}
+
+	high, low := update.Price, update.Price
+	if !update.BarDate.IsZero() && o.CreatedAt.Before(update.BarDate) && update.High > 0 && update.Low > 0 {
+		high, low = math.Max(update.High, update.Price), math.Min(update.Low, update.Price)
+	}
+	buy := o.Side == models.OrderBuy
+
+	if (o.Type == models.OrderStop || o.Type == models.OrderStopLimit) && !o.StopTriggered {
+		stop := *o.StopPrice
+		if (buy && high < stop) || (!buy && low > stop) {
+			return m
+		}
+		m.Triggered = true
+
+		// A stop reached inside the bar fills at the stop; one the price
+		// gapped through fills at the nearest price that traded
+		if o.Type == models.OrderStop {
+			m.Filled = true
+			if buy {
+				m.Price = math.Max(stop, low)
+			} else {
+				m.Price = math.Min(stop, high)
+			}
+			return m
+		}
+	}
+
+	switch o.Type {
+	case models.OrderMarket, models.OrderStop:
+		m.Filled, m.Price = true, update.Price
+	case models.OrderLimit, models.OrderStopLimit:
+		limit := *o.LimitPrice
+		if buy && low <= limit {
+			m.Filled, m.Price = true, math.Min(limit, high)
+		} else if !buy && high >= limit {
+			m.Filled, m.Price = true, math.Max(limit, low)
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+func TestMatchOrder(t *testing.T) {
+	placed := day(1).Add(12 * time.Hour)
+	order := func(side, typ string, limit, stop *float64) models.PaperOrder {
+		return models.PaperOrder{Side: side, Type: typ, LimitPrice: limit, StopPrice: stop, Quantity: 1, CreatedAt: placed}
+	}
+	bar := func(price, high, low float64) PriceUpdate {
+		return PriceUpdate{Symbol: "AAPL", Price: price, High: high, Low: low, BarDate: day(2)}
+	}
+
+	triggered := order(models.OrderSell, models.OrderStopLimit, ptr(88), ptr(90))
+	triggered.StopTriggered = true
+	late := order(models.OrderBuy, models.OrderLimit, ptr(100), nil)
+	late.CreatedAt = day(2).Add(15 * time.Hour)
+
+	tests := []struct {
+		name   string
+		order  models.PaperOrder
+		update PriceUpdate
+		want   OrderMatch
+	}{
+		{"market", order(models.OrderBuy, models.OrderMarket, nil, nil), bar(102, 105, 95), OrderMatch{Filled: true, Price: 102}},
+		{"no price", order(models.OrderBuy, models.OrderMarket, nil, nil), bar(0, 0, 0), OrderMatch{}},
+
+		{"buy limit reached in the bar", order(models.OrderBuy, models.OrderLimit, ptr(100), nil), bar(102, 105, 95), OrderMatch{Filled: true, Price: 100}},
+		{"buy limit gapped through", order(models.OrderBuy, models.OrderLimit, ptr(100), nil), bar(92, 95, 90), OrderMatch{Filled: true, Price: 95}},
+		{"buy limit not reached", order(models.OrderBuy, models.OrderLimit, ptr(100), nil), bar(103, 105, 101), OrderMatch{}},
+		{"sell limit gapped through", order(models.OrderSell, models.OrderLimit, ptr(100), nil), bar(108, 110, 105), OrderMatch{Filled: true, Price: 105}},
+		{"limit placed during the bar", late, bar(102, 105, 95), OrderMatch{}},
+
+		{"sell stop reached in the bar", order(models.OrderSell, models.OrderStop, nil, ptr(90)), bar(92, 95, 85), OrderMatch{Triggered: true, Filled: true, Price: 90}},
+		{"sell stop gapped through", order(models.OrderSell, models.OrderStop, nil, ptr(90)), bar(82, 85, 80), OrderMatch{Triggered: true, Filled: true, Price: 85}},
+		{"buy stop gapped through", order(models.OrderBuy, models.OrderStop, nil, ptr(110)), bar(118, 120, 115), OrderMatch{Triggered: true, Filled: true, Price: 115}},
+		{"sell stop not reached", order(models.OrderSell, models.OrderStop, nil, ptr(90)), bar(93, 95, 91), OrderMatch{}},
+
+		{"stop limit gapped past the limit", order(models.OrderSell, models.OrderStopLimit, ptr(88), ptr(90)), bar(82, 85, 80), OrderMatch{Triggered: true}},
+		{"stop limit filled at the limit", order(models.OrderSell, models.OrderStopLimit, ptr(88), ptr(90)), bar(92, 95, 85), OrderMatch{Triggered: true, Filled: true, Price: 88}},
+		{"triggered stop limit waits for the limit", triggered, bar(86, 87, 85), OrderMatch{}},
+		{"triggered stop limit fills", triggered, bar(89, 91, 87), OrderMatch{Filled: true, Price: 88}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchOrder(tt.order, tt.update); got.Triggered != tt.want.Triggered || got.Filled != tt.want.Filled || !approx(got.Price, tt.want.Price) {
+				t.Errorf("match = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// Standard performance periods, each ending at the latest snapshot
+const (
+	PeriodMTD       = "mtd"
+	PeriodQTD       = "qtd"
+	PeriodYTD       = "ytd"
+	Period1Y        = "1y"
+	PeriodInception = "inception"
+)
+
+// tradingDay truncates a timestamp to its UTC calendar date, matching the
+// dates stored in stock_history
+func tradingDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// BuildSnapshots values a portfolio at the close of each day from its first
+// transaction through until by replaying the ledger one day at a time.
+// closes holds each symbol's history ordered oldest first; the days valued
+// are the trading days in that history plus the days with transactions.
+// Holdings are priced at the latest close on or before each day, or at cost
+// before the first close is available.
+func BuildSnapshots(transactions []models.Transaction, method string, closes map[string][]models.StockHistoryEntry, until time.Time) ([]models.PortfolioSnapshot, error) {
+	snapshots := []models.PortfolioSnapshot{}
+	ordered := OrderTransactions(transactions)
+	if len(ordered) == 0 {
+		return snapshots, nil
+	}
+
+	start, end := tradingDay(ordered[0].ExecutedAt), tradingDay(until)
+	days := make(map[time.Time]bool)
+	for _, t := range ordered {
+		if day := tradingDay(t.ExecutedAt); !day.After(end) {
+			days[day] = true
+		}
+	}
+	for _, entries := range closes {
+		for _, entry := range entries {
+			if day := tradingDay(entry.Date); !day.Before(start) && !day.After(end) {
+				days[day] = true
+			}
+		}
+	}
+	calendar := make([]time.Time, 0, len(days))
+	for day := range days {
+		calendar = append(calendar, day)
+	}
+	sort.Slice(calendar, func(i, j int) bool { return calendar[i].Before(calendar[j]) })
+
+	ledger := NewLedger(method)
+	prices := make(map[string]float64)
+	next := make(map[string]int)
+	applied := 0
+	for _, day := range calendar {
+		for symbol, entries := range closes {
+			i := next[symbol]
+			for ; i < len(entries) && !tradingDay(entries[i].Date).After(day); i++ {
+				prices[symbol] = entries[i].Close
+			}
+			next[symbol] = i
+		}
+
+		snapshot := models.PortfolioSnapshot{Date: day}
+		for ; applied < len(ordered) && !tradingDay(ordered[applied].ExecutedAt).After(day); applied++ {
+			t := ordered[applied]
+			if err := ledger.Apply(t); err != nil {
+				return nil, err
+			}
+			snapshot.NetFlow += externalFlow(t, prices)
+		}
+
+		snapshot.Cash = ledger.Cash
+		for _, pos := range ledger.Positions() {
+			price, ok := prices[pos.Symbol]
+			if !ok {
+				price = pos.AverageCost
+			}
+			snapshot.MarketValue += pos.Quantity * price
+		}
+		snapshot.TotalValue = snapshot.Cash + snapshot.MarketValue
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// externalFlow returns the money a transaction moves into or out of the
+// portfolio; trades, dividends and fees are part of its performance
+func externalFlow(t models.Transaction, prices map[string]float64) float64 {
+	price, ok := prices[t.Symbol]
+	if !ok {
+		price = t.Price
+	}
+
+	switch t.Type {
+	case models.TransactionDeposit:
+		return t.TotalAmount
+	case models.TransactionWithdrawal:
+		return -t.TotalAmount
+	case models.TransactionTransferIn:
+		return t.Quantity * price
+	case models.TransactionTransferOut:
+		return -t.Quantity * price
+	}
+	return 0
+}
+
+// Performance computes the time-weighted series and returns between start and
+// end (either may be nil for the full history) and the standard periods
+// ending at the latest snapshot. Snapshots must be ordered oldest first.
+func Performance(snapshots []models.PortfolioSnapshot, start, end *time.Time) models.PerformanceResponse {
+	resp := models.PerformanceResponse{
+		Periods: []models.PeriodReturn{},
+		Series:  []models.PerformancePoint{},
+	}
+	if len(snapshots) == 0 {
+		return resp
+	}
+
+	first, last := snapshots[0].Date, snapshots[len(snapshots)-1].Date
+	from, to := first, last
+	if start != nil {
+		from = tradingDay(*start)
+	}
+	if end != nil {
+		to = tradingDay(*end)
+	}
+	resp.Range = periodReturn("range", snapshots, from, to)
+
+	growth := 1.0
+	base := baseIndex(snapshots, from)
+	for i := base + 1; i < len(snapshots) && !snapshots[i].Date.After(to); i++ {
+		growth *= dailyGrowth(snapshots, i)
+		resp.Series = append(resp.Series, models.PerformancePoint{
+			Date:             snapshots[i].Date,
+			TotalValue:       snapshots[i].TotalValue,
+			NetFlow:          snapshots[i].NetFlow,
+			CumulativeReturn: (growth - 1) * 100,
+		})
+	}
+
+	periods := []struct {
+		name string
+		from time.Time
+	}{
+		{PeriodMTD, time.Date(last.Year(), last.Month(), 1, 0, 0, 0, 0, time.UTC)},
+		{PeriodQTD, time.Date(last.Year(), (last.Month()-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)},
+		{PeriodYTD, time.Date(last.Year(), 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Period1Y, last.AddDate(-1, 0, 1)},
+		{PeriodInception, first},
+	}
+	for _, p := range periods {
+		resp.Periods = append(resp.Periods, periodReturn(p.name, snapshots, p.from, last))
+	}
+
+	return resp
+}
+
+// baseIndex returns the last snapshot before from, whose value a period
+// starting at from grows from, or -1 when the period starts at inception
+func baseIndex(snapshots []models.PortfolioSnapshot, from time.Time) int {
+	return sort.Search(len(snapshots), func(i int) bool { return !snapshots[i].Date.Before(from) }) - 1
+}
+
+// dailyGrowth returns one plus the return of day i, treating the day's flows
+// as arriving at the start of the day
+func dailyGrowth(snapshots []models.PortfolioSnapshot, i int) float64 {
+	previous := 0.0
+	if i > 0 {
+		previous = snapshots[i-1].TotalValue
+	}
+	invested := previous + snapshots[i].NetFlow
+	if invested <= 0 {
+		return 1
+	}
+	return snapshots[i].TotalValue / invested
+}
+
+// periodReturn computes the TWR and XIRR of the snapshots from from through
+// to; either is nil when the period holds no snapshots or the IRR does not
+// converge
+func periodReturn(name string, snapshots []models.PortfolioSnapshot, from, to time.Time) models.PeriodReturn {
+	period := models.PeriodReturn{Period: name, Start: from, End: to}
+	if from.Before(snapshots[0].Date) {
+		period.Start = snapshots[0].Date
+	}
+
+	base := baseIndex(snapshots, from)
+	growth := 1.0
+	var amounts []float64
+	var dates []time.Time
+	if base >= 0 {
+		amounts = append(amounts, -snapshots[base].TotalValue)
+		dates = append(dates, snapshots[base].Date)
+	}
+
+	lastIndex := -1
+	for i := base + 1; i < len(snapshots) && !snapshots[i].Date.After(to); i++ {
+		growth *= dailyGrowth(snapshots, i)
+		if snapshots[i].NetFlow != 0 {
+			amounts = append(amounts, -snapshots[i].NetFlow)
+			dates = append(dates, snapshots[i].Date)
+		}
+		lastIndex = i
+	}
+	if lastIndex < 0 {
+		return period
+	}
+	period.End = snapshots[lastIndex].Date
+
+	twr := (growth - 1) * 100
+	period.TWR = &twr
+
+	amounts = append(amounts, snapshots[lastIndex].TotalValue)
+	dates = append(dates, snapshots[lastIndex].Date)
+	if irr, ok := XIRR(amounts, dates); ok {
+		irr *= 100
+		period.IRR = &irr
+	}
+
+	return period
+}
+
+// XIRR returns the annualized internal rate of return of cash flows on the
+// given dates, as a fraction. It reports false when the flows do not change
+// sign, span no time, or the rate cannot be found.
+func XIRR(amounts []float64, dates []time.Time) (float64, bool) {
+	if len(amounts) < 2 || len(amounts) != len(dates) {
+		return 0, false
+	}
+
+	years := make([]float64, len(dates))
+	positive, negative := false, false
+	for i := range amounts {
+		years[i] = dates[i].Sub(dates[0]).Hours() / 24 / 365
+		positive = positive || amounts[i] > 0
+		negative = negative || amounts[i] < 0
+	}
+	if !positive || !negative || years[len(years)-1] <= 0 {
+		return 0, false
+	}
+
+	npv := func(rate float64) float64 {
+		total := 0.0
+		for i, amount := range amounts {
+			total += amount / math.Pow(1+rate, years[i])
+		}
+		return total
+	}
+
+	// Newton's method converges quickly from a sensible guess
+	rate := 0.1
+	for range 50 {
+		value := npv(rate)
+		if math.Abs(value) < 1e-7 {
+			return rate, true
+		}
+		derivative := 0.0
+		for i, amount := range amounts {
+			derivative -= years[i] * amount / math.Pow(1+rate, years[i]+1)
+		}
+		if derivative == 0 {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		if math.Abs(next-rate) < 1e-10 {
+			return next, true
+		}
+		rate = next
+	}
+
+	// Otherwise bisect over a bracket where the net present value changes sign
+	low, high := -0.9999, 1.0
+	for npv(low)*npv(high) > 0 {
+		high *= 2
+		if high > 1e6 {
+			return 0, false
+		}
+	}
+	for range 200 {
+		mid := (low + high) / 2
+		if npv(low)*npv(mid) <= 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+	return (low + high) / 2, true
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestXIRR(t *testing.T) {
+	year := day(1).AddDate(1, 0, 0)
+
+	tests := []struct {
+		name    string
+		amounts []float64
+		dates   []time.Time
+		want    float64
+		ok      bool
+	}{
+		{"gain over a year", []float64{-1000, 1100}, []time.Time{day(1), day(1).AddDate(0, 0, 365)}, 0.1, true},
+		{"loss over a year", []float64{-1000, 500}, []time.Time{day(1), day(1).AddDate(0, 0, 365)}, -0.5, true},
+		{"flat", []float64{-1000, 1000}, []time.Time{day(1), year}, 0, true},
+		{"no time passes", []float64{-1000, 1100}, []time.Time{day(1), day(1)}, 0, false},
+		{"flows do not change sign", []float64{1000, 1100}, []time.Time{day(1), year}, 0, false},
+		{"single flow", []float64{-1000}, []time.Time{day(1)}, 0, false},
+		{"mismatched dates", []float64{-1000, 1100}, []time.Time{day(1)}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := XIRR(tt.amounts, tt.dates)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("rate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXIRRSolvesNetPresentValue(t *testing.T) {
+	// Several flows of both signs; the rate found must discount them to zero
+	amounts := []float64{-1000, -500, 200, -300, 1900}
+	dates := []time.Time{day(1), day(1).AddDate(0, 2, 0), day(1).AddDate(0, 5, 0), day(1).AddDate(0, 9, 0), day(1).AddDate(1, 3, 0)}
+
+	rate, ok := XIRR(amounts, dates)
+	if !ok {
+		t.Fatal("rate not found")
+	}
+	npv := 0.0
+	for i, amount := range amounts {
+		years := dates[i].Sub(dates[0]).Hours() / 24 / 365
+		npv += amount / math.Pow(1+rate, years)
+	}
+	if math.Abs(npv) > 1e-4 {
+		t.Errorf("net present value at %v = %v, want 0", rate, npv)
+	}
+}
+
+func TestPerformance(t *testing.T) {
+	// 10% on the second day, a deposit on the third that earns nothing, and
+	// 10% again on the fourth: flows must not move the time-weighted return
+	snapshots := []models.PortfolioSnapshot{
+		{Date: day(1), TotalValue: 1000, NetFlow: 1000},
+		{Date: day(2), TotalValue: 1100},
+		{Date: day(3), TotalValue: 2100, NetFlow: 1000},
+		{Date: day(4), TotalValue: 2310},
+	}
+	tests := []struct {
+		name   string
+		start  *time.Time
+		end    *time.Time
+		twr    *float64
+		series int
+	}{
+		{"full history", nil, nil, ptr(21.0), 4},
+		{"from the first gain", dayRef(2), nil, ptr(21.0), 3},
+		{"from the deposit", dayRef(3), nil, ptr(10.0), 2},
+		{"through the deposit", nil, dayRef(3), ptr(10.0), 3},
+		{"after the last snapshot", dayRef(9), nil, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := Performance(snapshots, tt.start, tt.end)
+			if !equalPtr(resp.Range.TWR, tt.twr) {
+				t.Errorf("range TWR = %v, want %v", deref(resp.Range.TWR), deref(tt.twr))
+			}
+			if len(resp.Series) != tt.series {
+				t.Errorf("series has %d points, want %d", len(resp.Series), tt.series)
+			}
+			if len(resp.Periods) != 5 {
+				t.Fatalf("got %d periods, want 5", len(resp.Periods))
+			}
+			for _, p := range resp.Periods {
+				if p.TWR == nil || !approx(*p.TWR, 21) {
+					t.Errorf("%s TWR = %v, want 21", p.Period, deref(p.TWR))
+				}
+				if p.IRR == nil || *p.IRR <= 0 {
+					t.Errorf("%s IRR = %v, want a positive rate", p.Period, deref(p.IRR))
+				}
+			}
+		})
+	}
+}
+
+func TestPerformanceCumulativeSeries(t *testing.T) {
+	snapshots := []models.PortfolioSnapshot{
+		{Date: day(1), TotalValue: 1000, NetFlow: 1000},
+		{Date: day(2), TotalValue: 900},
+		{Date: day(3), TotalValue: 1400, NetFlow: 500},
+		{Date: day(4), TotalValue: 1050, NetFlow: -350},
+	}
+
+	want := []float64{0, -10, -10, -10}
+	resp := Performance(snapshots, nil, nil)
+	if len(resp.Series) != len(want) {
+		t.Fatalf("series = %+v, want %d points", resp.Series, len(want))
+	}
+	for i, point := range resp.Series {
+		if !approx(point.CumulativeReturn, want[i]) {
+			t.Errorf("%s cumulative return = %v, want %v", point.Date.Format(time.DateOnly), point.CumulativeReturn, want[i])
+		}
+	}
+}
+
+func TestPerformanceEmpty(t *testing.T) {
+	resp := Performance(nil, nil, nil)
+	if len(resp.Periods) != 0 || len(resp.Series) != 0 || resp.Range.TWR != nil {
+		t.Errorf("performance of no snapshots = %+v, want empty", resp)
+	}
+}
+
+func dayRef(d int) *time.Time {
+	t := day(d)
+	return &t
+}
+
+func deref(p *float64) any {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+func equalPtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return approx(*a, *b)
+}
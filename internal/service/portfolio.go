@@ -0,0 +1,38 @@
+package service
+
+import "go-flow/internal/models"
+
+// ValuePortfolio fills in each position's market value and gains from its
+// current price and previous close, then the portfolio totals
+func ValuePortfolio(p *models.Portfolio) {
+	p.TotalValue = p.CashBalance
+	p.CostBasis = 0
+	p.DayChange = 0
+
+	for i := range p.Positions {
+		pos := &p.Positions[i]
+		pos.MarketValue = pos.Quantity * pos.CurrentPrice
+		pos.CostBasis = pos.Quantity * pos.AverageCost
+		pos.UnrealizedGain = pos.MarketValue - pos.CostBasis
+		pos.UnrealizedGainPercent = percentOf(pos.UnrealizedGain, pos.CostBasis)
+		if pos.PreviousClose > 0 {
+			pos.DayChange = pos.Quantity * (pos.CurrentPrice - pos.PreviousClose)
+		}
+
+		p.TotalValue += pos.MarketValue
+		p.CostBasis += pos.CostBasis
+		p.DayChange += pos.DayChange
+	}
+
+	p.TotalReturn = p.TotalValue - p.CashBalance - p.CostBasis
+	p.TotalReturnPercent = percentOf(p.TotalReturn, p.CostBasis)
+	p.DayChangePercent = percentOf(p.DayChange, p.TotalValue-p.DayChange)
+}
+
+// percentOf returns part as a percentage of whole, or zero when whole is zero
+func percentOf(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole * 100
+}
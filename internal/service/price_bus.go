@@ -0,0 +1,72 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// PriceUpdate is published whenever a new price for a symbol has been stored.
+// Updates that come from a bar also carry its range and the day it covers;
+// they are zero for a plain quote.
+type PriceUpdate struct {
+	Symbol  string
+	Price   float64
+	Time    time.Time
+	High    float64
+	Low     float64
+	BarDate time.Time
+}
+
+// PriceBus fans stored prices out to in-process subscribers. Publishing never
+// blocks: a subscriber that falls behind misses updates, so consumers that
+// must not miss a price should also reconcile against the database.
+type PriceBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]chan PriceUpdate
+}
+
+func NewPriceBus() *PriceBus {
+	return &PriceBus{
+		subs: make(map[int]chan PriceUpdate),
+	}
+}
+
+// Subscribe returns a channel of updates and a function that unsubscribes
+// and closes it
+func (b *PriceBus) Subscribe(buffer int) (<-chan PriceUpdate, func()) {
+	ch := make(chan PriceUpdate, buffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers the update to every subscriber with room in its buffer
+func (b *PriceBus) Publish(update PriceUpdate) {
+	if b == nil {
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ch := range b.subs {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDailyQuotaExhausted is returned once the provider's daily request budget is spent
+var ErrDailyQuotaExhausted = errors.New("daily provider quota exhausted")
+
+// Quota throttles outgoing provider requests to a per-minute and per-day budget
+type Quota struct {
+	mu        sync.Mutex
+	perMinute int
+	perDay    int
+	recent    []time.Time
+	day       string
+	dayCount  int
+}
+
+// QuotaStatus is a snapshot of the provider quota usage
+type QuotaStatus struct {
+	PerMinute    int `json:"per_minute"`
+	PerDay       int `json:"per_day"`
+	UsedLastMin  int `json:"used_last_minute"`
+	UsedToday    int `json:"used_today"`
+	RemainingDay int `json:"remaining_today"`
+}
+
+// NewQuota creates a quota; a non-positive limit disables that check
+func NewQuota(perMinute, perDay int) *Quota {
+	return &Quota{
+		perMinute: perMinute,
+		perDay:    perDay,
+	}
+}
+
+// Wait blocks until a request may be sent and records it
+func (q *Quota) Wait(ctx context.Context) error {
+	for {
+		q.mu.Lock()
+		now := time.Now()
+		q.prune(now)
+
+		if q.perDay > 0 && q.dayCount >= q.perDay {
+			q.mu.Unlock()
+			return ErrDailyQuotaExhausted
+		}
+
+		if q.perMinute <= 0 || len(q.recent) < q.perMinute {
+			q.recent = append(q.recent, now)
+			q.dayCount++
+			q.mu.Unlock()
+			return nil
+		}
+
+		// Sleep until the oldest request leaves the one minute window
+		wait := q.recent[0].Add(time.Minute).Sub(now)
+		q.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Status returns the current quota usage
+func (q *Quota) Status() QuotaStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.prune(time.Now())
+
+	remaining := -1
+	if q.perDay > 0 {
+		remaining = max(q.perDay-q.dayCount, 0)
+	}
+
+	return QuotaStatus{
+		PerMinute:    q.perMinute,
+		PerDay:       q.perDay,
+		UsedLastMin:  len(q.recent),
+		UsedToday:    q.dayCount,
+		RemainingDay: remaining,
+	}
+}
+
+// prune drops requests older than a minute and resets the daily counter at
+// midnight UTC; callers must hold the lock
+func (q *Quota) prune(now time.Time) {
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(q.recent) && !q.recent[i].After(cutoff) {
+		i++
+	}
+	q.recent = q.recent[i:]
+
+	today := now.UTC().Format("2006-01-02")
+	if q.day != today {
+		q.day = today
+		q.dayCount = 0
+	}
+}
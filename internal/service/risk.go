@@ -0,0 +1,223 @@
+package service
+
+import (
+	"cmp"
+	"math"
+	"time"
+
+	"go-flow/internal/models"
+)
+
+// Risk metric defaults
+const (
+	DefaultRiskLookback  = 252
+	DefaultRiskBenchmark = "SPY"
+	DefaultVaRConfidence = 95
+)
+
+// dailyReturn is one day's return, as a fraction
+type dailyReturn struct {
+	Date   time.Time
+	Return float64
+}
+
+// RiskLookbacks fills in each metric's lookback from the shared default
+func RiskLookbacks(req *models.RiskRequest) {
+	req.Lookback = cmp.Or(req.Lookback, DefaultRiskLookback)
+	req.VolatilityLookback = cmp.Or(req.VolatilityLookback, req.Lookback)
+	req.BetaLookback = cmp.Or(req.BetaLookback, req.Lookback)
+	req.SharpeLookback = cmp.Or(req.SharpeLookback, req.Lookback)
+	req.DrawdownLookback = cmp.Or(req.DrawdownLookback, req.Lookback)
+	req.VaRLookback = cmp.Or(req.VaRLookback, req.Lookback)
+	req.Confidence = cmp.Or(req.Confidence, DefaultVaRConfidence)
+}
+
+// portfolioReturns returns the time-weighted return of each day after the
+// first snapshot
+func portfolioReturns(snapshots []models.PortfolioSnapshot) []dailyReturn {
+	var returns []dailyReturn
+	for i := 1; i < len(snapshots); i++ {
+		returns = append(returns, dailyReturn{Date: snapshots[i].Date, Return: dailyGrowth(snapshots, i) - 1})
+	}
+	return returns
+}
+
+// closeReturns returns the close-to-close return of each bar after the first;
+// history is ordered oldest first
+func closeReturns(history []models.StockHistoryEntry) []dailyReturn {
+	var returns []dailyReturn
+	for i := 1; i < len(history); i++ {
+		if history[i-1].Close > 0 {
+			returns = append(returns, dailyReturn{
+				Date:   tradingDay(history[i].Date),
+				Return: history[i].Close/history[i-1].Close - 1,
+			})
+		}
+	}
+	return returns
+}
+
+// lastReturns returns the most recent n returns
+func lastReturns(returns []dailyReturn, n int) []float64 {
+	if len(returns) > n {
+		returns = returns[len(returns)-n:]
+	}
+	values := make([]float64, len(returns))
+	for i, r := range returns {
+		values[i] = r.Return
+	}
+	return values
+}
+
+// PortfolioRisk computes risk metrics from a portfolio's snapshots, ordered
+// oldest first, and the benchmark's history; req must have its lookbacks
+// filled in by RiskLookbacks
+func PortfolioRisk(snapshots []models.PortfolioSnapshot, benchmark []models.StockHistoryEntry, req models.RiskRequest) models.RiskResponse {
+	var resp models.RiskResponse
+	if n := len(snapshots); n > 0 {
+		resp.AsOf = &snapshots[n-1].Date
+		resp.TotalValue = snapshots[n-1].TotalValue
+	}
+
+	returns := portfolioReturns(snapshots)
+	annualize := math.Sqrt(TradingDaysPerYear)
+
+	// Volatility
+	values := lastReturns(returns, req.VolatilityLookback)
+	resp.Volatility = models.VolatilityMetric{Lookback: req.VolatilityLookback, Observations: len(values)}
+	if len(values) >= 2 {
+		volatility := Stdev(values) * annualize * 100
+		resp.Volatility.Annualized = &volatility
+	}
+
+	// Beta over the days both series have a return
+	resp.Beta = models.BetaMetric{Lookback: req.BetaLookback, Benchmark: req.Benchmark}
+	benchmarkByDate := make(map[time.Time]float64)
+	for _, r := range closeReturns(benchmark) {
+		benchmarkByDate[r.Date] = r.Return
+	}
+	var ps, bs []float64
+	recent := returns
+	if len(recent) > req.BetaLookback {
+		recent = recent[len(recent)-req.BetaLookback:]
+	}
+	for _, r := range recent {
+		if b, ok := benchmarkByDate[r.Date]; ok {
+			ps = append(ps, r.Return)
+			bs = append(bs, b)
+		}
+	}
+	resp.Beta.Observations = len(ps)
+	if variance := covariance(bs, bs); len(ps) >= 2 && variance > 0 {
+		beta := covariance(ps, bs) / variance
+		resp.Beta.Beta = &beta
+		if correlation, ok := pearson(ps, bs); ok {
+			resp.Beta.Correlation = &correlation
+		}
+	}
+
+	// Sharpe and Sortino against a daily risk-free rate
+	riskFree := 0.0
+	if req.RiskFreeRate != nil {
+		riskFree = *req.RiskFreeRate
+	}
+	values = lastReturns(returns, req.SharpeLookback)
+	resp.Ratios = models.RatioMetrics{Lookback: req.SharpeLookback, Observations: len(values), RiskFreeRate: riskFree}
+	if len(values) >= 2 {
+		dailyRiskFree := math.Pow(1+riskFree/100, 1.0/TradingDaysPerYear) - 1
+		excess := Mean(values) - dailyRiskFree
+		if sd := Stdev(values); sd > 0 {
+			sharpe := excess / sd * annualize
+			resp.Ratios.Sharpe = &sharpe
+		}
+		downside := 0.0
+		for _, v := range values {
+			if d := v - dailyRiskFree; d < 0 {
+				downside += d * d
+			}
+		}
+		if downside > 0 {
+			sortino := excess / math.Sqrt(downside/float64(len(values))) * annualize
+			resp.Ratios.Sortino = &sortino
+		}
+	}
+
+	resp.Drawdown = maxDrawdown(returns, req.DrawdownLookback)
+
+	// Value at risk
+	values = lastReturns(returns, req.VaRLookback)
+	resp.VaR = models.VaRMetric{Lookback: req.VaRLookback, Observations: len(values), Confidence: req.Confidence}
+	if len(values) >= 2 {
+		tail := 1 - req.Confidence/100
+
+		cutoff := quantile(values, tail)
+		var losses []float64
+		for _, v := range values {
+			if v <= cutoff {
+				losses = append(losses, v)
+			}
+		}
+		historicalVaR, historicalCVaR := -cutoff*100, -Mean(losses)*100
+		resp.VaR.HistoricalVaR = &historicalVaR
+		resp.VaR.HistoricalCVaR = &historicalCVaR
+
+		mu, sigma, z := Mean(values), Stdev(values), normalQuantile(tail)
+		parametricVaR := -(mu + z*sigma) * 100
+		parametricCVaR := -(mu - sigma*normalPDF(z)/tail) * 100
+		resp.VaR.ParametricVaR = &parametricVaR
+		resp.VaR.ParametricCVaR = &parametricCVaR
+	}
+
+	return resp
+}
+
+// maxDrawdown finds the largest fall from a peak in the growth of the last
+// lookback returns, and the first day the value regained that peak
+func maxDrawdown(returns []dailyReturn, lookback int) models.DrawdownMetric {
+	metric := models.DrawdownMetric{Lookback: lookback}
+	if len(returns) > lookback {
+		returns = returns[len(returns)-lookback:]
+	}
+	if len(returns) == 0 {
+		return metric
+	}
+
+	// The series starts at 1 on the day before the first return
+	value, peak := 1.0, 1.0
+	peakDate := returns[0].Date.AddDate(0, 0, -1)
+	worst := 0.0
+	var worstPeak, worstTrough time.Time
+	var worstIndex int
+	for i, r := range returns {
+		value *= 1 + r.Return
+		if value > peak {
+			peak, peakDate = value, r.Date
+		}
+		if drawdown := value/peak - 1; drawdown < worst {
+			worst, worstPeak, worstTrough, worstIndex = drawdown, peakDate, r.Date, i
+		}
+	}
+	if worst == 0 {
+		zero := 0.0
+		metric.MaxDrawdown = &zero
+		return metric
+	}
+
+	drawdown := -worst * 100
+	metric.MaxDrawdown = &drawdown
+	metric.PeakDate = &worstPeak
+	metric.TroughDate = &worstTrough
+
+	// Recovered once growth since the trough makes up the fall
+	recovery := 1 + worst
+	for _, r := range returns[worstIndex+1:] {
+		recovery *= 1 + r.Return
+		if recovery >= 1-1e-12 {
+			date := r.Date
+			metric.RecoveryDate = &date
+			break
+		}
+	}
+
+	return metric
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func returnsFrom(values ...float64) []dailyReturn {
+	returns := make([]dailyReturn, len(values))
+	for i, v := range values {
+		returns[i] = dailyReturn{Date: day(i + 1), Return: v}
+	}
+	return returns
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		returns  []dailyReturn
+		lookback int
+		drawdown *float64
+		peak     *time.Time
+		trough   *time.Time
+		recovery *time.Time
+	}{
+		{"no returns", nil, 20, nil, nil, nil, nil},
+		{"only gains", returnsFrom(0.01, 0.02), 20, ptr(0), nil, nil, nil},
+		{"recovered", returnsFrom(0.1, -0.5, 1), 20, ptr(50), dayRef(1), dayRef(2), dayRef(3)},
+		{"not recovered", returnsFrom(-0.2, 0.1), 20, ptr(20), dayRef(0), dayRef(1), nil},
+		{"deeper second fall", returnsFrom(-0.1, 0.2, -0.3), 20, ptr(30), dayRef(2), dayRef(3), nil},
+		{"fall outside the lookback", returnsFrom(-0.5, 0.1, 0.1), 2, ptr(0), nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := maxDrawdown(tt.returns, tt.lookback)
+			if m.Lookback != tt.lookback {
+				t.Errorf("lookback = %d, want %d", m.Lookback, tt.lookback)
+			}
+			if !equalPtr(m.MaxDrawdown, tt.drawdown) {
+				t.Errorf("max drawdown = %v, want %v", deref(m.MaxDrawdown), deref(tt.drawdown))
+			}
+			for _, d := range []struct {
+				name      string
+				got, want *time.Time
+			}{
+				{"peak", m.PeakDate, tt.peak},
+				{"trough", m.TroughDate, tt.trough},
+				{"recovery", m.RecoveryDate, tt.recovery},
+			} {
+				if (d.got == nil) != (d.want == nil) || d.got != nil && !d.got.Equal(*d.want) {
+					t.Errorf("%s date = %v, want %v", d.name, d.got, d.want)
+				}
+			}
+		})
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	values := []float64{5, 1, 4, 2, 3}
+
+	tests := []struct {
+		name string
+		xs   []float64
+		p    float64
+		want float64
+	}{
+		{"minimum", values, 0, 1},
+		{"maximum", values, 1, 5},
+		{"median", values, 0.5, 3},
+		{"on a value", values, 0.25, 2},
+		{"between values", values, 0.1, 1.4},
+		{"tail", values, 0.95, 4.8},
+		{"single value", []float64{7}, 0.05, 7},
+		{"no values", nil, 0.5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quantile(tt.xs, tt.p); !approx(got, tt.want) {
+				t.Errorf("quantile(%v, %v) = %v, want %v", tt.xs, tt.p, got, tt.want)
+			}
+		})
+	}
+	if values[0] != 5 {
+		t.Errorf("quantile sorted its input in place: %v", values)
+	}
+}
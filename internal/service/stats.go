@@ -0,0 +1,98 @@
+package service
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// TradingDaysPerYear annualizes daily statistics
+const TradingDaysPerYear = 252
+
+// Mean returns the arithmetic mean, or 0 for no values
+func Mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, x := range xs {
+		total += x
+	}
+	return total / float64(len(xs))
+}
+
+// covariance returns the sample covariance of two equally long series
+func covariance(xs, ys []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	mx, my := Mean(xs), Mean(ys)
+	total := 0.0
+	for i := range xs {
+		total += (xs[i] - mx) * (ys[i] - my)
+	}
+	return total / float64(len(xs)-1)
+}
+
+// Stdev returns the sample standard deviation, or 0 for fewer than two
+// values
+func Stdev(xs []float64) float64 {
+	return math.Sqrt(covariance(xs, xs))
+}
+
+// pearson returns the correlation of two equally long series, or false when
+// either is constant
+func pearson(xs, ys []float64) (float64, bool) {
+	sx, sy := Stdev(xs), Stdev(ys)
+	if sx == 0 || sy == 0 {
+		return 0, false
+	}
+	return covariance(xs, ys) / (sx * sy), true
+}
+
+// ranks returns the rank of each value, averaging the ranks of ties
+func ranks(xs []float64) []float64 {
+	order := make([]int, len(xs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return xs[order[a]] < xs[order[b]] })
+
+	ranked := make([]float64, len(xs))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && xs[order[j+1]] == xs[order[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranked[order[k]] = rank
+		}
+		i = j + 1
+	}
+	return ranked
+}
+
+// quantile returns the p-quantile of xs by linear interpolation
+func quantile(xs []float64, p float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	sorted := slices.Sorted(slices.Values(xs))
+	pos := p * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (pos-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// normalPDF is the standard normal density
+func normalPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+// normalQuantile is the inverse of the standard normal distribution function
+func normalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRanks(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   []float64
+	}{
+		{"empty", nil, []float64{}},
+		{"single", []float64{5}, []float64{1}},
+		{"ascending", []float64{1, 2, 3}, []float64{1, 2, 3}},
+		{"unordered", []float64{0.3, -0.1, 0.2, 0}, []float64{4, 1, 3, 2}},
+		{"pair tie", []float64{10, 20, 10, 30}, []float64{1.5, 3, 1.5, 4}},
+		{"triple tie", []float64{2, 1, 2, 2, 3}, []float64{3, 1, 3, 3, 5}},
+		{"two ties", []float64{1, 1, 2, 2}, []float64{1.5, 1.5, 3.5, 3.5}},
+		{"all equal", []float64{7, 7, 7}, []float64{2, 2, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ranks(tt.values); !slices.Equal(got, tt.want) {
+				t.Errorf("ranks(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPearson(t *testing.T) {
+	tests := []struct {
+		name   string
+		xs, ys []float64
+		want   float64
+		wantOK bool
+	}{
+		{"identical", []float64{1, 2, 3}, []float64{1, 2, 3}, 1, true},
+		{"scaled", []float64{1, 2, 3}, []float64{10, 20, 30}, 1, true},
+		{"inverse", []float64{1, 2, 3}, []float64{3, 2, 1}, -1, true},
+		{"uncorrelated", []float64{1, 2, 3, 4}, []float64{1, -1, -1, 1}, 0, true},
+		{"constant", []float64{1, 2, 3}, []float64{4, 4, 4}, 0, false},
+		{"too short", []float64{1}, []float64{2}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pearson(tt.xs, tt.ys)
+			if ok != tt.wantOK || !approx(got, tt.want) {
+				t.Errorf("pearson() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
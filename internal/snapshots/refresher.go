@@ -0,0 +1,54 @@
+// Package snapshots keeps the stored daily valuations of portfolios up to
+// date with their ledgers and the stored closes
+package snapshots
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go-flow/internal/repository"
+)
+
+// Refresher periodically rebuilds the snapshots of portfolios whose ledger
+// or prices changed since they were last stored. Each portfolio is rebuilt
+// under its row lock from the earliest changed day on, so any number of
+// refreshers can run against the same database.
+type Refresher struct {
+	portfolios repository.PortfolioRepository
+	interval   time.Duration
+}
+
+func NewRefresher(portfolios repository.PortfolioRepository, interval time.Duration) *Refresher {
+	return &Refresher{
+		portfolios: portfolios,
+		interval:   interval,
+	}
+}
+
+// Run refreshes stale snapshots until ctx is cancelled; a non-positive
+// interval disables the refresher
+func (r *Refresher) Run(ctx context.Context) {
+	if r.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.Refresh()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Refresh()
+		}
+	}
+}
+
+// Refresh rebuilds every stale portfolio's snapshots once
+func (r *Refresher) Refresh() {
+	if _, err := r.portfolios.RefreshStaleSnapshots(); err != nil {
+		log.Printf("snapshots: refresh failed: %v", err)
+	}
+}